@@ -0,0 +1,52 @@
+package jwt
+
+import (
+	"fmt"
+	"slices"
+)
+
+// registeredHeaders JWS, JWE, JWA所定義的header，這些不可以出現在crit之中
+// https://datatracker.ietf.org/doc/html/rfc7515#section-4.1
+// https://datatracker.ietf.org/doc/html/rfc7516#section-4.1
+// https://datatracker.ietf.org/doc/html/rfc7518#section-4.1
+var registeredHeaders = []string{
+	"alg", "jku", "jwk", "kid", "x5u", "x5c", "x5t", "x5t#S256", "typ", "cty", "crit",
+	"enc", "zip",
+	"epk", "apu", "apv", "iv", "tag", "p2s", "p2c",
+}
+
+// CheckCritical https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.11
+//
+// crit所列出的擴充header，接收方若不理解(不在understood之中)就必須拒絕此token，此外
+//   - crit不可以是空的陣列
+//   - 不可以列出已註冊的header(例如: alg, kid)
+//   - 所列出的header必須存在
+//
+// header只應該包含受保護(被加簽)的部分，crit本身必須受到保護
+// 不符合時回傳 ErrTokenInvalidCritical
+func CheckCritical(header map[string]any, understood []string) error {
+	v, exists := header["crit"]
+	if !exists {
+		return nil
+	}
+	crit, ok := v.([]any)
+	if !ok || len(crit) == 0 {
+		return fmt.Errorf("crit must be a non-empty array %w", ErrTokenInvalidCritical)
+	}
+	for _, c := range crit {
+		name, ok := c.(string)
+		if !ok || name == "" {
+			return fmt.Errorf("crit: %v must be a non-empty string %w", c, ErrTokenInvalidCritical)
+		}
+		if slices.Contains(registeredHeaders, name) {
+			return fmt.Errorf("crit: %q is a registered header %w", name, ErrTokenInvalidCritical)
+		}
+		if !slices.Contains(understood, name) {
+			return fmt.Errorf("crit: %q is not understood %w", name, ErrTokenInvalidCritical)
+		}
+		if _, exists = header[name]; !exists {
+			return fmt.Errorf("crit: %q is not present in the header %w", name, ErrTokenInvalidCritical)
+		}
+	}
+	return nil
+}
@@ -3,7 +3,9 @@ package jwt
 import (
 	"crypto"
 	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/asn1"
 	"errors"
 	"fmt"
 	"math/big"
@@ -18,6 +20,19 @@ type SigningMethodECDSA struct {
 	Name       string
 	Hash       crypto.Hash
 	KeyBitSize int
+
+	// Deterministic 為true時，nonce依據RFC 6979由私鑰與雜湊值決定，相同的內容會得到相同的簽名(方便golden file的測試)
+	// 預設為false，也就是使用隨機的nonce
+	// https://datatracker.ietf.org/doc/html/rfc6979
+	Deterministic bool
+
+	// LowS 嚴格模式: 加簽時一律輸出 s <= n/2 的簽名，驗證時拒絕 s > n/2 的簽名
+	// 由於(r, s)與(r, n-s)都是合法的簽名，不限制的話同一個token可以被改寫成另一個也能通過驗證的token
+	// 若要把token(或其簽名)當作唯一的識別碼(例如: 防重放的快取)，請開啟此選項
+	LowS bool
+
+	// KeyPolicy 鑰匙強度的要求，nil表示使用 StrictKeyPolicy，請透過 WithKeyPolicy 設定
+	KeyPolicy *KeyPolicy
 }
 
 var (
@@ -28,11 +43,11 @@ var (
 
 func init() {
 	// "ES256": https://datatracker.ietf.org/doc/html/rfc7519#section-8
-	SigningMethodECDSA256 = &SigningMethodECDSA{"ES256", crypto.SHA256, 256} // 橢圓曲線ES256其實就是用了256bit, 其中它的keySize用位元組表示 256/8 = 32
-	SigningMethodECDSA384 = &SigningMethodECDSA{"ES384", crypto.SHA384, 384}
+	SigningMethodECDSA256 = &SigningMethodECDSA{Name: "ES256", Hash: crypto.SHA256, KeyBitSize: 256} // 橢圓曲線ES256其實就是用了256bit, 其中它的keySize用位元組表示 256/8 = 32
+	SigningMethodECDSA384 = &SigningMethodECDSA{Name: "ES384", Hash: crypto.SHA384, KeyBitSize: 384}
 
 	// 用的是 elliptic.P521() 它是一個type Curve interface, 共需要 521/8 = 65.125 byte => 因此實際上需要用66byte才能包含
-	SigningMethodECDSA512 = &SigningMethodECDSA{"ES512", crypto.SHA512, 521}
+	SigningMethodECDSA512 = &SigningMethodECDSA{Name: "ES512", Hash: crypto.SHA512, KeyBitSize: 521}
 }
 
 // AlgName implements the ISigningMethod interface
@@ -40,11 +55,58 @@ func (m *SigningMethodECDSA) AlgName() string {
 	return m.Name
 }
 
+// WithKeyPolicy 回傳使用policy的副本，原本的SigningMethod不會被異動
+func (m *SigningMethodECDSA) WithKeyPolicy(policy *KeyPolicy) *SigningMethodECDSA {
+	clone := *m
+	clone.KeyPolicy = policy
+	return &clone
+}
+
+// nistCurves KeyBitSize與JWS所規定的曲線 https://datatracker.ietf.org/doc/html/rfc7518#section-3.4
+var nistCurves = map[int]elliptic.Curve{
+	256: elliptic.P256(),
+	384: elliptic.P384(),
+	521: elliptic.P521(),
+}
+
+// matchesCurve 曲線必須是演算法所規定的那一條，例如ES256只能用P-256
+// 只比較BitSize的話，其他同樣是256-bit的曲線也會被ES256接受
+// KeyBitSize不是NIST曲線的自定義方法，才只比較BitSize
+func (m *SigningMethodECDSA) matchesCurve(curve elliptic.Curve) bool {
+	if curve == nil {
+		return false
+	}
+	if expected, ok := nistCurves[m.KeyBitSize]; ok {
+		return curve == expected
+	}
+	return curve.Params().BitSize == m.KeyBitSize
+}
+
+// curveName 演算法所規定的曲線名稱，例如: P-256
+func (m *SigningMethodECDSA) curveName() string {
+	if expected, ok := nistCurves[m.KeyBitSize]; ok {
+		return curveName(expected)
+	}
+	return fmt.Sprintf("%d-bit curve", m.KeyBitSize)
+}
+
 // Sign implements the ISigningMethod interface
-func (m *SigningMethodECDSA) Sign(signingBytes []byte, ecdsaPrivateKey any) ([]byte, error) {
-	privateKey, ok := ecdsaPrivateKey.(*ecdsa.PrivateKey)
+// key可以是 *ecdsa.PrivateKey 或者任何公鑰為 *ecdsa.PublicKey 的 crypto.Signer (例如HSM, KMS)
+func (m *SigningMethodECDSA) Sign(signingBytes []byte, key any) ([]byte, error) {
+	signer, ok := key.(crypto.Signer)
 	if !ok {
-		return nil, fmt.Errorf("ECDSA sign expects *ecdsa.PrivateKey. %w", ErrInvalidKeyType)
+		return nil, fmt.Errorf("ECDSA sign expects crypto.Signer with *ecdsa.PublicKey. %w", ErrInvalidKeyType)
+	}
+	publicKey, ok := signer.Public().(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("ECDSA sign expects crypto.Signer with *ecdsa.PublicKey. %w", ErrInvalidKeyType)
+	}
+	// 曲線必須與演算法相符，例如ES256只能用P-256的key
+	if !m.matchesCurve(publicKey.Curve) {
+		return nil, fmt.Errorf("%s sign expects a %s key, got %s. %w", m.Name, m.curveName(), curveName(publicKey.Curve), ErrInvalidKey)
+	}
+	if err := m.KeyPolicy.checkCurve(m.Name, curveName(publicKey.Curve)); err != nil {
+		return nil, err
 	}
 
 	if !m.Hash.Available() {
@@ -54,42 +116,98 @@ func (m *SigningMethodECDSA) Sign(signingBytes []byte, ecdsaPrivateKey any) ([]b
 	hasher := m.Hash.New()
 	hasher.Write(signingBytes)
 
-	var r, s *big.Int
-	var err error
-	r, s, err = ecdsa.Sign(rand.Reader, privateKey, hasher.Sum(nil))
+	nCurveBit := publicKey.Curve.Params().BitSize
+
+	random := rand.Reader
+	if m.Deterministic {
+		// RFC 6979需要由私鑰推導nonce，HSM等其他的crypto.Signer無法保證其行為
+		if _, ok = key.(*ecdsa.PrivateKey); !ok {
+			return nil, fmt.Errorf("deterministic ECDSA expects *ecdsa.PrivateKey. %w", ErrInvalidKeyType)
+		}
+		random = nil // *ecdsa.PrivateKey.Sign 在random為nil時會依據RFC 6979產生nonce
+	}
+
+	// crypto.Signer加簽出來的是ASN.1 DER的格式 SEQUENCE { r INTEGER, s INTEGER }
+	der, err := signer.Sign(random, hasher.Sum(nil), m.Hash)
 	if err != nil {
 		return nil, err
 	}
-	// 由於ecdsa驗證的時後，需要r, s的資訊，所以我們必須將這些資訊寫入
-	// var signature []byte
-	// 以下這樣還會需要寫入長度，取的時候才知道怎麼取
-	// signature = append(signature, r.Bytes()...)
-	// signature = append(signature, s.Bytes()...)
+	signature, err := ECDSASignatureFromASN1(der, (nCurveBit+7)>>3)
+	if err != nil {
+		return nil, err
+	}
+	if m.LowS {
+		toLowS(signature, publicKey.Curve.Params().N)
+	}
+	return signature, nil
+}
 
-	// 為了能簡化寫入長度
-	// 我們計算一個適當的長度，並且平均分配給r, s
+// toLowS 若s > n/2，將簽名(r || s)之中的s替換成n-s
+func toLowS(signature []byte, n *big.Int) {
+	keyBytes := len(signature) / 2
+	s := new(big.Int).SetBytes(signature[keyBytes:])
+	if isHighS(s, n) {
+		s.Sub(n, s).FillBytes(signature[keyBytes:])
+	}
+}
 
-	nCurveBit := privateKey.Curve.Params().BitSize
+func isHighS(s, n *big.Int) bool {
+	return s.Cmp(new(big.Int).Rsh(n, 1)) > 0
+}
 
-	if m.KeyBitSize != nCurveBit {
-		return nil, ErrInvalidKey
+// ECDSASignatureFromASN1 將ASN.1 DER格式的簽名轉換成JWS所用的 r || s，keyBytes為曲線的位元組數，例如P-256為32
+// crypto.Signer 以及大多數的KMS回傳的都是DER的格式
+// https://datatracker.ietf.org/doc/html/rfc7518#section-3.4
+//
+// 由於ecdsa驗證的時後，需要r, s的資訊，所以我們必須將這些資訊寫入
+// 若直接 append(r.Bytes(), s.Bytes()...) 還會需要寫入長度，取的時候才知道怎麼取
+// 為了能簡化寫入長度，我們計算一個適當的長度(keyBytes)，並且平均分配給r, s
+func ECDSASignatureFromASN1(der []byte, keyBytes int) ([]byte, error) {
+	var sig struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ASN.1 ECDSA signature: %w", err)
+	}
+	if len(rest) != 0 || sig.R.Sign() <= 0 || sig.S.Sign() <= 0 ||
+		sig.R.BitLen() > keyBytes*8 || sig.S.BitLen() > keyBytes*8 {
+		return nil, errors.New("invalid ASN.1 ECDSA signature")
 	}
-
-	keyBytes := (nCurveBit + 7) >> 3 // 即若bit/8(換成byte), 如果除不盡就會加1，也就是要多一個byte
 
 	signature := make([]byte, keyBytes<<1) // *2 // 前面KeyBytes放r，剩下的全部給s
-	r.FillBytes(signature[0:keyBytes])     // FillBytes用BigEndian的方式把r這個很大的[]uint填充到指定的[]byte之間，它會填滿，如果不夠填會panic，如果buf的尺寸比較大是可以的(但規定多餘的部分必須是0)
-	s.FillBytes(signature[keyBytes:])
-
+	sig.R.FillBytes(signature[0:keyBytes]) // FillBytes用BigEndian的方式把r這個很大的[]uint填充到指定的[]byte之間，它會填滿，如果不夠填會panic，如果buf的尺寸比較大是可以的(但規定多餘的部分必須是0)
+	sig.S.FillBytes(signature[keyBytes:])
 	return signature, nil
 }
 
+// ECDSASignatureToASN1 ECDSASignatureFromASN1 的反向，將JWS的 r || s 轉換成ASN.1 DER的格式
+func ECDSASignatureToASN1(signature []byte) ([]byte, error) {
+	keyBytes := len(signature) / 2
+	if keyBytes == 0 || len(signature) != keyBytes<<1 {
+		return nil, ErrECDSAVerification
+	}
+	return asn1.Marshal(struct {
+		R, S *big.Int
+	}{
+		new(big.Int).SetBytes(signature[:keyBytes]),
+		new(big.Int).SetBytes(signature[keyBytes:]),
+	})
+}
+
 // Verify implements the ISigningMethod interface
 func (m *SigningMethodECDSA) Verify(signingBytes []byte, signature []byte, key any) error {
 	publicKey, ok := key.(*ecdsa.PublicKey)
 	if !ok {
 		return fmt.Errorf("ECDSA verify expects *ecdsa.PublicKey. %w", ErrInvalidKeyType)
 	}
+	// 曲線必須與演算法相符，例如ES256只能用P-256的公鑰
+	if !m.matchesCurve(publicKey.Curve) {
+		return fmt.Errorf("%s verify expects a %s key, got %s. %w", m.Name, m.curveName(), curveName(publicKey.Curve), ErrInvalidKey)
+	}
+	if err := m.KeyPolicy.checkCurve(m.Name, curveName(publicKey.Curve)); err != nil {
+		return err
+	}
 
 	// 因為我們在Sign的時候，把簽名出來的r, s放到make([]byte, 2*keyBytes)之中
 	// 所以檢驗長度必須要符合
@@ -100,6 +218,9 @@ func (m *SigningMethodECDSA) Verify(signingBytes []byte, signature []byte, key a
 
 	r := big.NewInt(0).SetBytes(signature[:keyBytes])
 	s := big.NewInt(0).SetBytes(signature[keyBytes:])
+	if m.LowS && isHighS(s, publicKey.Curve.Params().N) {
+		return ErrECDSAVerification
+	}
 
 	if !m.Hash.Available() {
 		return ErrHashUnavailable
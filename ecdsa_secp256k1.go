@@ -0,0 +1,86 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt/secp256k1"
+)
+
+// SigningMethodSecp256k1 使用secp256k1曲線的ECDSA https://datatracker.ietf.org/doc/html/rfc8812#section-3.2
+// 簽名的格式與 SigningMethodECDSA 相同，都是 r || s
+type SigningMethodSecp256k1 struct {
+	Name string
+	Hash crypto.Hash
+
+	// KeyPolicy 鑰匙強度的要求，nil表示使用 StrictKeyPolicy，請透過 WithKeyPolicy 設定
+	KeyPolicy *KeyPolicy
+}
+
+var SigningMethodES256K *SigningMethodSecp256k1
+
+func init() {
+	SigningMethodES256K = &SigningMethodSecp256k1{Name: "ES256K", Hash: crypto.SHA256}
+}
+
+// AlgName implements the ISigningMethod interface
+func (m *SigningMethodSecp256k1) AlgName() string {
+	return m.Name
+}
+
+// WithKeyPolicy 回傳使用policy的副本，原本的SigningMethod不會被異動
+func (m *SigningMethodSecp256k1) WithKeyPolicy(policy *KeyPolicy) *SigningMethodSecp256k1 {
+	clone := *m
+	clone.KeyPolicy = policy
+	return &clone
+}
+
+// Sign implements the ISigningMethod interface
+// key可以是 *secp256k1.PrivateKey 或者任何公鑰為 *secp256k1.PublicKey 的 crypto.Signer (ASN.1 DER的簽名)
+func (m *SigningMethodSecp256k1) Sign(signingBytes []byte, key any) ([]byte, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("ES256K sign expects crypto.Signer with *secp256k1.PublicKey. %w", ErrInvalidKeyType)
+	}
+	if _, ok = signer.Public().(*secp256k1.PublicKey); !ok {
+		return nil, fmt.Errorf("ES256K sign expects crypto.Signer with *secp256k1.PublicKey. %w", ErrInvalidKeyType)
+	}
+	if err := m.KeyPolicy.checkCurve(m.Name, "secp256k1"); err != nil {
+		return nil, err
+	}
+	if !m.Hash.Available() {
+		return nil, ErrHashUnavailable
+	}
+	hasher := m.Hash.New()
+	hasher.Write(signingBytes)
+
+	// 本地的key不需要經過ASN.1的轉換
+	if privateKey, ok := key.(*secp256k1.PrivateKey); ok {
+		return secp256k1.Sign(rand.Reader, privateKey, hasher.Sum(nil))
+	}
+	der, err := signer.Sign(rand.Reader, hasher.Sum(nil), m.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return ECDSASignatureFromASN1(der, 32)
+}
+
+// Verify implements the ISigningMethod interface
+func (m *SigningMethodSecp256k1) Verify(signingBytes []byte, signature []byte, key any) error {
+	publicKey, ok := key.(*secp256k1.PublicKey)
+	if !ok {
+		return fmt.Errorf("ES256K verify expects *secp256k1.PublicKey. %w", ErrInvalidKeyType)
+	}
+	if err := m.KeyPolicy.checkCurve(m.Name, "secp256k1"); err != nil {
+		return err
+	}
+	if !m.Hash.Available() {
+		return ErrHashUnavailable
+	}
+	hasher := m.Hash.New()
+	hasher.Write(signingBytes)
+	if !secp256k1.Verify(publicKey, hasher.Sum(nil), signature) {
+		return ErrECDSAVerification
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/secp256k1"
+	"testing"
+)
+
+func TestSigningMethodSecp256k1_Verify(t *testing.T) {
+	msg := []byte("hello")
+	key, err := secp256k1.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature, err := jwt.SigningMethodES256K.Sign(msg, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signature) != 64 {
+		t.Fatal(len(signature))
+	}
+	if err = jwt.SigningMethodES256K.Verify(msg, signature, key.PublicKey()); err != nil {
+		t.Fatal(err)
+	}
+	if err = jwt.SigningMethodES256K.Verify([]byte("hello!"), signature, key.PublicKey()); !errors.Is(err, jwt.ErrSignatureInvalid) {
+		t.Fatal(err)
+	}
+
+	// NIST的曲線不能混用
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err = jwt.SigningMethodES256K.Sign(msg, p256Key); !errors.Is(err, jwt.ErrInvalidKeyType) {
+		t.Fatal(err)
+	}
+	if err = jwt.SigningMethodES256K.Verify(msg, signature, &p256Key.PublicKey); !errors.Is(err, jwt.ErrInvalidKeyType) {
+		t.Fatal(err)
+	}
+}
@@ -1,10 +1,16 @@
 package jwt_test
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"github.com/CarsonSlovoka/jwt"
+	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -28,3 +34,152 @@ func TestSigningMethodECDSA_Verify(t *testing.T) {
 		}
 	}
 }
+
+// https://datatracker.ietf.org/doc/html/rfc6979#appendix-A.2.5
+// 只取與JWS相同的組合: P-256 + SHA-256, P-384 + SHA-384, P-521 + SHA-512
+func TestSigningMethodECDSA_deterministic(t *testing.T) {
+	for _, tt := range []struct {
+		method *jwt.SigningMethodECDSA
+		curve  elliptic.Curve
+		d      string
+		msg    string
+		r, s   string
+	}{
+		{jwt.SigningMethodECDSA256, elliptic.P256(),
+			"C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721", "sample",
+			"EFD48B2AACB6A8FD1140DD9CD45E81D69D2C877B56AAF991C34D0EA84EAF3716",
+			"F7CB1C942D657C41D436C7A1B6E29F65F3E900DBB9AFF4064DC4AB2F843ACDA8"},
+		{jwt.SigningMethodECDSA256, elliptic.P256(),
+			"C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721", "test",
+			"F1ABB023518351CD71D881567B1EA663ED3EFCF6C5132B354F28D3B0B7D38367",
+			"019F4113742A2B14BD25926B49C649155F267E60D3814B4C0CC84250E46F0083"},
+		{jwt.SigningMethodECDSA384, elliptic.P384(),
+			"6B9D3DAD2E1B8C1C05B19875B6659F4DE23C3B667BF297BA9AA47740787137D896D5724E4C70A825F872C9EA60D2EDF5", "sample",
+			"94EDBB92A5ECB8AAD4736E56C691916B3F88140666CE9FA73D64C4EA95AD133C81A648152E44ACF96E36DD1E80FABE46",
+			"99EF4AEB15F178CEA1FE40DB2603138F130E740A19624526203B6351D0A3A94FA329C145786E679E7B82C71A38628AC8"},
+		{jwt.SigningMethodECDSA512, elliptic.P521(),
+			"00FAD06DAA62BA3B25D2FB40133DA757205DE67F5BB0018FEE8C86E1B68C7E75CAA896EB32F1F47C70855836A6D16FCC1466F6D8FBEC67DB89EC0C08B0E996B83538", "sample",
+			"00C328FAFCBD79DD77850370C46325D987CB525569FB63C5D3BC53950E6D4C5F174E25A1EE9017B5D450606ADD152B534931D7D4E8455CC91F9B15BF05EC36E377FA",
+			"00617CCE7CF5064806C467F678D3B4080D6F1CC50AF26CA209417308281B68AF282623EAA63E5B5C0723D8B8C37FF0777B1A20F8CCB1DCCC43997F1EE0E44DA4A67A"},
+	} {
+		d, _ := hex.DecodeString(tt.d)
+		// ecdsa.ParseRawPrivateKey 需要Go 1.25，這裡自行由d算出公鑰
+		privateKey := &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: tt.curve}, D: new(big.Int).SetBytes(d)}
+		privateKey.X, privateKey.Y = tt.curve.ScalarBaseMult(d)
+		m := *tt.method
+		m.Deterministic = true
+		signature, err := m.Sign([]byte(tt.msg), privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.ToUpper(hex.EncodeToString(signature)); got != tt.r+tt.s {
+			t.Fatalf("%s %s\n got: %s\nwant: %s", m.Name, tt.msg, got, tt.r+tt.s)
+		}
+		if err = m.Verify([]byte(tt.msg), signature, &privateKey.PublicKey); err != nil {
+			t.Fatal(err)
+		}
+
+		// 預設仍然是隨機的nonce
+		s1, _ := tt.method.Sign([]byte(tt.msg), privateKey)
+		s2, _ := tt.method.Sign([]byte(tt.msg), privateKey)
+		if bytes.Equal(s1, s2) {
+			t.Fatal("randomized signatures should differ")
+		}
+	}
+
+	// RFC 6979需要私鑰本身
+	m := *jwt.SigningMethodECDSA256
+	m.Deterministic = true
+	privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := m.Sign([]byte("hello"), struct{ *ecdsa.PrivateKey }{privateKey}); !errors.Is(err, jwt.ErrInvalidKeyType) {
+		t.Fatal(err)
+	}
+}
+
+func TestSigningMethodECDSA_lowS(t *testing.T) {
+	msg := []byte("hello")
+	for _, tt := range []struct {
+		method *jwt.SigningMethodECDSA
+		curve  elliptic.Curve
+	}{
+		{jwt.SigningMethodECDSA256, elliptic.P256()},
+		{jwt.SigningMethodECDSA384, elliptic.P384()},
+		{jwt.SigningMethodECDSA512, elliptic.P521()},
+	} {
+		privateKey, _ := ecdsa.GenerateKey(tt.curve, rand.Reader)
+		n := tt.curve.Params().N
+		halfN := new(big.Int).Rsh(n, 1)
+		strict := *tt.method
+		strict.LowS = true
+
+		for range 16 { // 隨機的nonce約有一半的機率會產生high-S
+			signature, err := strict.Sign(msg, privateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			keyBytes := len(signature) / 2
+			s := new(big.Int).SetBytes(signature[keyBytes:])
+			if s.Cmp(halfN) > 0 {
+				t.Fatal("signature should be low-S")
+			}
+			if err = strict.Verify(msg, signature, &privateKey.PublicKey); err != nil {
+				t.Fatal(err)
+			}
+
+			// (r, n-s) 也是合法的簽名，只有嚴格模式會拒絕
+			highS := bytes.Clone(signature)
+			new(big.Int).Sub(n, s).FillBytes(highS[keyBytes:])
+			if err = tt.method.Verify(msg, highS, &privateKey.PublicKey); err != nil {
+				t.Fatal(err)
+			}
+			if err = strict.Verify(msg, highS, &privateKey.PublicKey); !errors.Is(err, jwt.ErrECDSAVerification) {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// 公鑰的曲線必須與KeyBitSize相符
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err := jwt.SigningMethodECDSA256.Verify(msg, make([]byte, 64), &p384Key.PublicKey); !errors.Is(err, jwt.ErrInvalidKey) {
+		t.Fatal(err)
+	}
+	if err := jwt.SigningMethodECDSA256.Verify(msg, make([]byte, 64), &ecdsa.PublicKey{}); !errors.Is(err, jwt.ErrInvalidKey) {
+		t.Fatal(err)
+	}
+}
+
+// customCurveSigner 私鑰本身沒有問題，但公鑰的曲線被換成了別的曲線
+type customCurveSigner struct {
+	*ecdsa.PrivateKey
+	pub *ecdsa.PublicKey
+}
+
+// Public implements the crypto.Signer interface
+func (s customCurveSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// 曲線必須是演算法所規定的那一條，BitSize相同(甚至名稱相同)的其他曲線也不行
+func TestSigningMethodECDSA_curve(t *testing.T) {
+	msg := []byte("hello")
+	privateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signature, _ := jwt.SigningMethodECDSA256.Sign(msg, privateKey)
+	if err := jwt.SigningMethodECDSA256.Verify(msg, signature, &privateKey.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// elliptic.P256().Params() 的BitSize為256，Name也是P-256，但並不是 elliptic.P256()
+	custom := &ecdsa.PublicKey{Curve: elliptic.P256().Params(), X: privateKey.X, Y: privateKey.Y}
+	if err := jwt.SigningMethodECDSA256.Verify(msg, signature, custom); !errors.Is(err, jwt.ErrInvalidKey) {
+		t.Fatal(err)
+	}
+	if _, err := jwt.SigningMethodECDSA256.Sign(msg, customCurveSigner{privateKey, custom}); !errors.Is(err, jwt.ErrInvalidKey) {
+		t.Fatal(err)
+	}
+	if _, err := jwt.BindKey(jwt.SigningMethodECDSA256, custom); !errors.Is(err, jwt.ErrKeyAlgorithmMismatch) {
+		t.Fatal(err)
+	}
+	if _, err := jwt.BindKey(jwt.SigningMethodECDSA256, &privateKey.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+}
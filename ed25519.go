@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"github.com/CarsonSlovoka/jwt/ed448"
 )
 
 // ErrEd25519Verification 使其可以被兩種錯誤類型判別
@@ -14,8 +15,19 @@ var ErrEd25519Verification = fmt.Errorf("%w %w",
 	ErrSignatureInvalid,
 )
 
+// ErrEd448Verification 使其可以被兩種錯誤類型判別
+var ErrEd448Verification = fmt.Errorf("%w %w",
+	errors.New("ed448: verification error"),
+	ErrSignatureInvalid,
+)
+
+// SigningMethodED25519 alg為EdDSA，實際使用的曲線由key決定(Ed25519或Ed448)
+// https://datatracker.ietf.org/doc/html/rfc8037#section-3.1
 type SigningMethodED25519 struct{}
 
+// SigningMethodEdDSA 同 SigningMethodED25519，名稱更能表達它同時支援Ed25519與Ed448
+type SigningMethodEdDSA = SigningMethodED25519
+
 // AlgName implements the ISigningMethod interface
 func (m *SigningMethodED25519) AlgName() string {
 	return "EdDSA"
@@ -34,8 +46,10 @@ func (m *SigningMethodED25519) Sign(signingBytes []byte, key any) ([]byte, error
 		return nil, fmt.Errorf("ed25519 sign expects crypto.Signer. %w", ErrInvalidKeyType)
 	}
 
-	// 用此來確保所提供的Signer，符合ed25519
-	if _, ok = privateKey.Public().(ed25519.PublicKey); !ok {
+	// 用此來確保所提供的Signer，符合ed25519或ed448
+	switch privateKey.Public().(type) {
+	case ed25519.PublicKey, ed448.PublicKey:
+	default:
 		return nil, ErrInvalidKey
 	}
 
@@ -48,21 +62,27 @@ func (m *SigningMethodED25519) Sign(signingBytes []byte, key any) ([]byte, error
 }
 
 // Verify implements the ISigningMethod interface
+// 依據公鑰的類型決定要使用Ed25519或Ed448驗證
 func (m *SigningMethodED25519) Verify(signingBytes []byte, signature []byte, key any) error {
-	publicKey, ok := key.(ed25519.PublicKey)
-	if !ok {
-		return fmt.Errorf("ed25519 verify error. expected type: ed25519.PublicKey, got: %T. %w",
-			key, ErrInvalidKeyType,
-		)
+	switch publicKey := key.(type) {
+	case ed25519.PublicKey:
+		if len(publicKey) != ed25519.PublicKeySize {
+			return ErrInvalidKey
+		}
+		if !ed25519.Verify(publicKey, signingBytes, signature) {
+			return ErrEd25519Verification
+		}
+		return nil
+	case ed448.PublicKey:
+		if len(publicKey) != ed448.PublicKeySize {
+			return ErrInvalidKey
+		}
+		if !ed448.Verify(publicKey, signingBytes, signature) {
+			return ErrEd448Verification
+		}
+		return nil
 	}
-
-	if len(publicKey) != ed25519.PublicKeySize {
-		return ErrInvalidKey
-	}
-
-	if !ed25519.Verify(publicKey, signingBytes, signature) {
-		return ErrEd25519Verification
-	}
-
-	return nil
+	return fmt.Errorf("eddsa verify error. expected type: ed25519.PublicKey or ed448.PublicKey, got: %T. %w",
+		key, ErrInvalidKeyType,
+	)
 }
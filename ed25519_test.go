@@ -5,6 +5,8 @@ import (
 	"crypto/rand"
 	"errors"
 	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/ed448"
+	"github.com/CarsonSlovoka/jwt/parser"
 	"testing"
 )
 
@@ -28,3 +30,39 @@ func TestSigningMethodED25519_Verify(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+// 同一個EdDSA的方法，可以依據key的曲線使用Ed25519或Ed448
+func TestSigningMethodEdDSA_ed448(t *testing.T) {
+	m := &jwt.SigningMethodEdDSA{}
+	publicKey, privateKey, _ := ed448.GenerateKey(rand.Reader)
+	token := jwt.New(m)
+	bsToken, err := token.SignedBytes(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vdFunc, err := parser.New().Parse(string(bsToken), func(method string) (jwt.ISigningMethod, error) {
+		return m, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+		return publicKey, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 簽名是由Ed448所產生，不可以用Ed25519的公鑰驗證
+	ed25519PublicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	msg := []byte("hello")
+	signature, _ := m.Sign(msg, privateKey)
+	if err = m.Verify(msg, signature, ed25519PublicKey); !errors.Is(err, jwt.ErrSignatureInvalid) {
+		t.Fatal(err)
+	}
+	if err = m.Verify([]byte("another msg"), signature, publicKey); !errors.Is(err, jwt.ErrEd448Verification) {
+		t.Fatal(err)
+	}
+	if err = m.Verify(msg, signature, "key"); !errors.Is(err, jwt.ErrInvalidKeyType) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,210 @@
+package ed448
+
+import (
+	"github.com/CarsonSlovoka/jwt/internal/montgomery"
+	"math/big"
+)
+
+// edwards448: x^2 + y^2 = 1 + d*x^2*y^2, d = -39081
+// https://datatracker.ietf.org/doc/html/rfc8032#section-5.2
+var (
+	fp *modulus // p = 2^448 - 2^224 - 1
+	fl *modulus // L = 2^446 - 13818066809895115352007386748515426880336692474882178609894547503885
+
+	curveD    element
+	sqrtExp   element // (p-3)/4，用於開根號
+	generator point
+)
+
+func init() {
+	p := new(big.Int).Lsh(big.NewInt(1), 448)
+	p.Sub(p, new(big.Int).Lsh(big.NewInt(1), 224))
+	p.Sub(p, big.NewInt(1))
+	fp = newModulus(p)
+
+	l := new(big.Int).Lsh(big.NewInt(1), 446)
+	c, _ := new(big.Int).SetString("13818066809895115352007386748515426880336692474882178609894547503885", 10)
+	fl = newModulus(l.Sub(l, c))
+
+	d := montgomery.FromBig(new(big.Int).Sub(p, big.NewInt(39081)))
+	fp.ToMont(&curveD, &d)
+	sqrtExp = montgomery.FromBig(new(big.Int).Rsh(new(big.Int).Sub(p, big.NewInt(3)), 2))
+
+	gx, _ := new(big.Int).SetString("224580040295924300187604334099896036246789641632564134246125461686950415467406032909029192869357953282578032075146446173674602635247710", 10)
+	gy, _ := new(big.Int).SetString("298819210078481492676017930443930673437544040154080242095928241372331506189835876003536878655418784733982303233503462500531545062832660", 10)
+	x, y := montgomery.FromBig(gx), montgomery.FromBig(gy)
+	fp.ToMont(&generator.x, &x)
+	fp.ToMont(&generator.y, &y)
+	generator.z = fp.One()
+}
+
+// point 以射影座標(X:Y:Z)表示的點，對應的仿射座標為(X/Z, Y/Z)，單位元素為(0:1:1)
+type point struct {
+	x, y, z element
+}
+
+func newIdentity() point {
+	return point{y: fp.One(), z: fp.One()}
+}
+
+// add 由於d不是平方數，此加法公式是complete的，單位元素或者P=Q都不需要特別處理
+// https://datatracker.ietf.org/doc/html/rfc8032#section-5.2.4
+func (p *point) add(p1, p2 *point) {
+	var a, b, c, d, e, f, g, h, t element
+	fp.Mul(&a, &p1.z, &p2.z)
+	fp.Square(&b, &a)
+	fp.Mul(&c, &p1.x, &p2.x)
+	fp.Mul(&d, &p1.y, &p2.y)
+	fp.Mul(&e, &curveD, &c)
+	fp.Mul(&e, &e, &d)
+	fp.Sub(&f, &b, &e)
+	fp.Add(&g, &b, &e)
+	fp.Add(&h, &p1.x, &p1.y)
+	fp.Add(&t, &p2.x, &p2.y)
+	fp.Mul(&h, &h, &t)
+
+	// X3 = A*F*(H-C-D)
+	fp.Sub(&h, &h, &c)
+	fp.Sub(&h, &h, &d)
+	fp.Mul(&h, &h, &f)
+	fp.Mul(&p.x, &h, &a)
+	// Y3 = A*G*(D-C)
+	fp.Sub(&t, &d, &c)
+	fp.Mul(&t, &t, &g)
+	fp.Mul(&p.y, &t, &a)
+	// Z3 = F*G
+	fp.Mul(&p.z, &f, &g)
+}
+
+// double https://datatracker.ietf.org/doc/html/rfc8032#section-5.2.4
+func (p *point) double(p1 *point) {
+	var b, c, d, e, h, j element
+	fp.Add(&b, &p1.x, &p1.y)
+	fp.Square(&b, &b)
+	fp.Square(&c, &p1.x)
+	fp.Square(&d, &p1.y)
+	fp.Add(&e, &c, &d)
+	fp.Square(&h, &p1.z)
+	fp.Add(&h, &h, &h)
+	fp.Sub(&j, &e, &h)
+
+	fp.Sub(&b, &b, &e)
+	fp.Mul(&p.x, &b, &j)
+	fp.Sub(&c, &c, &d)
+	fp.Mul(&p.y, &e, &c)
+	fp.Mul(&p.z, &e, &j)
+}
+
+func (p *point) negate(p1 *point) {
+	fp.Neg(&p.x, &p1.x)
+	p.y, p.z = p1.y, p1.z
+}
+
+// selectPoint cond為1時p=b，為0時p=a
+func (p *point) selectPoint(a, b *point, cond uint64) {
+	montgomery.Select(&p.x, &a.x, &b.x, cond)
+	montgomery.Select(&p.y, &a.y, &b.y, cond)
+	montgomery.Select(&p.z, &a.z, &b.z, cond)
+}
+
+// scalarMult p = k*q，k為little-endian的純量
+//
+// 採用4位元的固定視窗，每個視窗都做相同次數的倍點與加法，並且查表時會走訪整張表，
+// 所以執行的時間與記憶體的存取都與k無關
+func (p *point) scalarMult(q *point, k []byte) {
+	var table [16]point
+	table[0] = newIdentity()
+	table[1] = *q
+	for i := 2; i < 16; i++ {
+		if i%2 == 0 {
+			table[i].double(&table[i/2])
+		} else {
+			table[i].add(&table[i-1], q)
+		}
+	}
+
+	r := newIdentity()
+	var t point
+	for i := len(k) - 1; i >= 0; i-- {
+		for _, w := range [2]byte{k[i] >> 4, k[i] & 0x0f} {
+			r.double(&r)
+			r.double(&r)
+			r.double(&r)
+			r.double(&r)
+			t = newIdentity()
+			for j := range table {
+				t.selectPoint(&t, &table[j], uint64(subtleEq(uint8(j), w)))
+			}
+			r.add(&r, &t)
+		}
+	}
+	*p = r
+}
+
+// subtleEq 相等回傳1，否則回傳0
+func subtleEq(x, y uint8) uint8 {
+	z := uint32(x ^ y)
+	return uint8(((z - 1) >> 31) & 1)
+}
+
+// encode 57 bytes: y的little-endian，最後一個byte的最高位元為x的最低位元
+// https://datatracker.ietf.org/doc/html/rfc8032#section-5.2.2
+func (p *point) encode() []byte {
+	var zInv, x, y element
+	fp.Inv(&zInv, &p.z)
+	fp.Mul(&x, &p.x, &zInv)
+	fp.Mul(&y, &p.y, &zInv)
+
+	out := append(fp.bytes(&y), 0)
+	out[56] = (fp.bytes(&x)[0] & 1) << 7
+	return out
+}
+
+// decode https://datatracker.ietf.org/doc/html/rfc8032#section-5.2.3
+// 只用在公開的資料(公鑰, 簽名的R)，所以不需要常數時間
+func decode(b []byte) (*point, bool) {
+	if len(b) != 57 || b[56]&0x7f != 0 {
+		return nil, false
+	}
+	x0 := b[56] >> 7
+
+	var y element
+	if fp.setBytes(&y, b[:56]) != 1 {
+		return nil, false
+	}
+
+	// x^2 = (y^2 - 1) / (d*y^2 - 1)
+	var u, v, yy element
+	one := fp.One()
+	fp.Square(&yy, &y)
+	fp.Sub(&u, &yy, &one)
+	fp.Mul(&v, &yy, &curveD)
+	fp.Sub(&v, &v, &one)
+
+	// x = u^3 * v * (u^5 * v^3)^((p-3)/4)
+	var u3, u5v3, x, t element
+	fp.Square(&u3, &u)
+	fp.Mul(&u3, &u3, &u)
+	fp.Square(&u5v3, &u)
+	fp.Mul(&u5v3, &u5v3, &u3)
+	fp.Square(&t, &v)
+	fp.Mul(&t, &t, &v)
+	fp.Mul(&u5v3, &u5v3, &t)
+	fp.Exp(&x, &u5v3, &sqrtExp)
+	fp.Mul(&x, &x, &u3)
+	fp.Mul(&x, &x, &v)
+
+	// v*x^2 == u，否則不存在平方根
+	fp.Square(&t, &x)
+	fp.Mul(&t, &t, &v)
+	if t.Equal(&u) != 1 {
+		return nil, false
+	}
+	if x.IsZero() == 1 && x0 == 1 {
+		return nil, false
+	}
+	if fp.bytes(&x)[0]&1 != x0 {
+		fp.Neg(&x, &x)
+	}
+	return &point{x, y, fp.One()}, true
+}
@@ -0,0 +1,199 @@
+// Package ed448 實作RFC 8032的Ed448 (PureEdDSA，context為空)
+//
+// API與crypto/ed25519相同，私鑰為 seed || 公鑰
+//   - 有限體與純量的運算都是常數時間
+//   - 純量乘法使用固定視窗，不會依據私鑰或nonce產生分支
+package ed448
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha3"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"strconv"
+)
+
+const (
+	// PublicKeySize 公鑰的長度
+	PublicKeySize = 57
+	// SeedSize 私鑰的種子長度(RFC 8032所稱的私鑰)
+	SeedSize = 57
+	// PrivateKeySize seed || 公鑰
+	PrivateKeySize = SeedSize + PublicKeySize
+	// SignatureSize R || S
+	SignatureSize = 114
+)
+
+// PublicKey Ed448的公鑰
+type PublicKey []byte
+
+// Equal 比較兩把公鑰是否相同
+func (pub PublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(PublicKey)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(pub, other) == 1
+}
+
+// PrivateKey Ed448的私鑰 seed || 公鑰
+type PrivateKey []byte
+
+// Public implements the crypto.Signer interface
+func (priv PrivateKey) Public() crypto.PublicKey {
+	publicKey := make([]byte, PublicKeySize)
+	copy(publicKey, priv[SeedSize:])
+	return PublicKey(publicKey)
+}
+
+// Equal 比較兩把私鑰是否相同
+func (priv PrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(PrivateKey)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(priv, other) == 1
+}
+
+// Seed 取得私鑰的種子
+func (priv PrivateKey) Seed() []byte {
+	return bytes.Clone(priv[:SeedSize])
+}
+
+// Sign implements the crypto.Signer interface
+// 只支援PureEdDSA，opts必須為crypto.Hash(0)
+func (priv PrivateKey) Sign(_ io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != crypto.Hash(0) {
+		return nil, errors.New("ed448: cannot sign hashed message")
+	}
+	return Sign(priv, message), nil
+}
+
+// GenerateKey 產生新的鑰匙
+func GenerateKey(rand io.Reader) (PublicKey, PrivateKey, error) {
+	seed := make([]byte, SeedSize)
+	if _, err := io.ReadFull(rand, seed); err != nil {
+		return nil, nil, err
+	}
+	privateKey := NewKeyFromSeed(seed)
+	return privateKey.Public().(PublicKey), privateKey, nil
+}
+
+// NewKeyFromSeed 由種子產生私鑰，seed的長度必須是 SeedSize
+func NewKeyFromSeed(seed []byte) PrivateKey {
+	if l := len(seed); l != SeedSize {
+		panic("ed448: bad seed length: " + strconv.Itoa(l))
+	}
+	s, _ := expandSeed(seed)
+	var a point
+	a.scalarMult(&generator, s)
+
+	privateKey := make([]byte, 0, PrivateKeySize)
+	privateKey = append(privateKey, seed...)
+	return append(privateKey, a.encode()...)
+}
+
+// expandSeed https://datatracker.ietf.org/doc/html/rfc8032#section-5.2.5
+func expandSeed(seed []byte) (s, prefix []byte) {
+	h := sha3.SumSHAKE256(seed, 114)
+	s = h[:57]
+	s[0] &= 0xfc
+	s[55] |= 0x80
+	s[56] = 0
+	return s[:56], h[57:]
+}
+
+// dom4 https://datatracker.ietf.org/doc/html/rfc8032#section-2
+// phflag固定為0，context為空
+var dom4 = []byte("SigEd448\x00\x00")
+
+// hashToScalar SHAKE256(dom4 || parts..., 114) mod L
+func hashToScalar(z *element, parts ...[]byte) {
+	h := sha3.NewSHAKE256()
+	_, _ = h.Write(dom4)
+	for _, p := range parts {
+		_, _ = h.Write(p)
+	}
+	digest := make([]byte, 114)
+	_, _ = h.Read(digest)
+	reduceWide(z, digest)
+}
+
+// reduceWide 將114 bytes的little-endian數值對L取模數，結果為Montgomery形式
+// v = c0 + c1*R + c2*R^2 (R = 2^448)，而Montgomery形式的c*R^i即為 c 乘上 i+1 次 R^2
+func reduceWide(z *element, b []byte) {
+	var buf [limbs * 8]byte
+	var acc, t element
+	for i, chunk := range [][]byte{b[:56], b[56:112], b[112:]} {
+		clear(buf[:])
+		copy(buf[:], chunk)
+		t = elementFromBytes(buf[:])
+		for range i + 1 {
+			fl.ToMont(&t, &t)
+		}
+		fl.Add(&acc, &acc, &t)
+	}
+	*z = acc
+}
+
+// Sign 對message加簽 https://datatracker.ietf.org/doc/html/rfc8032#section-5.2.6
+func Sign(privateKey PrivateKey, message []byte) []byte {
+	if l := len(privateKey); l != PrivateKeySize {
+		panic("ed448: bad private key length: " + strconv.Itoa(l))
+	}
+	seed, publicKey := privateKey[:SeedSize], privateKey[SeedSize:]
+	sBytes, prefix := expandSeed(seed)
+
+	var r, k, s element
+	hashToScalar(&r, prefix, message)
+	var rPoint point
+	rPoint.scalarMult(&generator, fl.bytes(&r))
+	R := rPoint.encode()
+
+	hashToScalar(&k, R, publicKey, message)
+
+	// S = (r + k*s) mod L
+	var buf [limbs * 8]byte
+	copy(buf[:], sBytes)
+	s = elementFromBytes(buf[:])
+	fl.ToMont(&s, &s)
+	fl.Mul(&s, &s, &k)
+	fl.Add(&s, &s, &r)
+
+	signature := make([]byte, 0, SignatureSize)
+	signature = append(signature, R...)
+	signature = append(signature, fl.bytes(&s)...)
+	return append(signature, 0)
+}
+
+// Verify 驗證簽名 https://datatracker.ietf.org/doc/html/rfc8032#section-5.2.7
+func Verify(publicKey PublicKey, message, sig []byte) bool {
+	if len(publicKey) != PublicKeySize || len(sig) != SignatureSize {
+		return false
+	}
+	a, ok := decode(publicKey)
+	if !ok {
+		return false
+	}
+	if _, ok = decode(sig[:57]); !ok {
+		return false
+	}
+	// S必須小於L
+	var s element
+	if sig[113] != 0 || fl.setBytes(&s, sig[57:113]) != 1 {
+		return false
+	}
+
+	var k element
+	hashToScalar(&k, sig[:57], publicKey, message)
+
+	// [S]B - [k]A 必須等於 R
+	var sb, ka point
+	sb.scalarMult(&generator, fl.bytes(&s))
+	ka.scalarMult(a, fl.bytes(&k))
+	ka.negate(&ka)
+	sb.add(&sb, &ka)
+	return subtle.ConstantTimeCompare(sb.encode(), sig[:57]) == 1
+}
@@ -0,0 +1,52 @@
+package ed448
+
+import (
+	"encoding/binary"
+	"github.com/CarsonSlovoka/jwt/internal/montgomery"
+	"math/big"
+)
+
+// limbs p與L都是448位元，以7個uint64表示
+const limbs = 7
+
+// element 除非特別說明，否則都處於Montgomery的形式(x*R mod m, R = 2^448)
+type element = montgomery.Element
+
+// modulus Ed448需要兩種模數: 座標所在的有限體p，以及純量所在的群的階L
+// 運算的部分由 montgomery.Modulus 提供，這裡只處理Ed448的編碼(56 bytes的little-endian)
+type modulus struct {
+	*montgomery.Modulus
+}
+
+func newModulus(m *big.Int) *modulus {
+	return &modulus{montgomery.New(m, limbs)}
+}
+
+// elementFromBytes 將56 bytes的little-endian內容直接轉換(不做模數的檢查與Montgomery的轉換)
+func elementFromBytes(b []byte) element {
+	var e element
+	for i := range limbs {
+		e[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+	return e
+}
+
+// setBytes 將56 bytes的little-endian數值轉換成Montgomery形式
+// ok為1表示數值小於m
+func (md *modulus) setBytes(z *element, b []byte) (ok uint64) {
+	x := elementFromBytes(b)
+	ok = md.Less(&x)
+	md.ToMont(z, &x)
+	return ok
+}
+
+// bytes 轉換回56 bytes的little-endian數值
+func (md *modulus) bytes(x *element) []byte {
+	var t element
+	md.FromMont(&t, x)
+	b := make([]byte, limbs*8)
+	for i := range limbs {
+		binary.LittleEndian.PutUint64(b[i*8:], t[i])
+	}
+	return b
+}
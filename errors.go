@@ -10,6 +10,12 @@ var (
 	ErrTokenMalformed        = errors.New("token is malformed")
 	ErrTokenKeyFuncUnknown   = errors.New("token key func unknown")
 	ErrTokenSignatureInvalid = errors.New("token signature is invalid")
+	ErrTokenInvalidCritical  = errors.New("token has invalid critical header")
+	ErrTokenInvalidType      = errors.New("token has invalid type (typ)")
+
+	ErrAlgorithmNotRegistered = errors.New("signing method (alg) is not registered")
+	ErrAlgorithmUnsafe        = errors.New("signing method (alg) is unsafe")
+	ErrKeyAlgorithmMismatch   = errors.New("key is not allowed for the signing method (alg)")
 
 	ErrTokenRequiredClaimMissing = errors.New("token is missing required claim")
 	ErrClaimRequired             = errors.New("claim is required")
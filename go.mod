@@ -1,3 +1,4 @@
 module github.com/CarsonSlovoka/jwt
 
-go 1.23.0
+// ed448 使用 crypto/sha3 的SHAKE256 (Go 1.24加入標準庫)
+go 1.24.0
@@ -0,0 +1,23 @@
+package jwt
+
+// RegisteredHeader JOSE Header之中常用的標準欄位
+// https://datatracker.ietf.org/doc/html/rfc7515#section-4.1
+// https://datatracker.ietf.org/doc/html/rfc7519#section-5
+//
+// 完整的內容(包含自定義的header)仍然保存在 Token.Header
+type RegisteredHeader struct {
+	// the `alg` (Algorithm) header. See https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.1
+	Algorithm string `json:"alg"`
+
+	// the `typ` (Type) header. See https://datatracker.ietf.org/doc/html/rfc7519#section-5.1
+	Type string `json:"typ,omitempty"`
+
+	// the `cty` (Content Type) header. See https://datatracker.ietf.org/doc/html/rfc7519#section-5.2
+	ContentType string `json:"cty,omitempty"`
+
+	// the `kid` (Key ID) header. See https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.4
+	KeyID string `json:"kid,omitempty"`
+
+	// the `crit` (Critical) header. See https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.11
+	Critical []string `json:"crit,omitempty"`
+}
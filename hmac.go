@@ -12,6 +12,9 @@ import (
 type SigningMethodHMAC struct {
 	Name string
 	Hash crypto.Hash // Hash本質是一個uint
+
+	// KeyPolicy 鑰匙強度的要求，nil表示使用 StrictKeyPolicy，請透過 WithKeyPolicy 設定
+	KeyPolicy *KeyPolicy
 }
 
 var (
@@ -21,20 +24,30 @@ var (
 )
 
 func init() {
-	SigningMethodHMAC256 = &SigningMethodHMAC{"HS256", crypto.SHA256}
-	SigningMethodHMAC384 = &SigningMethodHMAC{"HS384", crypto.SHA384}
-	SigningMethodHMAC512 = &SigningMethodHMAC{"HS512", crypto.SHA512}
+	SigningMethodHMAC256 = &SigningMethodHMAC{Name: "HS256", Hash: crypto.SHA256}
+	SigningMethodHMAC384 = &SigningMethodHMAC{Name: "HS384", Hash: crypto.SHA384}
+	SigningMethodHMAC512 = &SigningMethodHMAC{Name: "HS512", Hash: crypto.SHA512}
 }
 
 func (m *SigningMethodHMAC) AlgName() string {
 	return m.Name
 }
 
+// WithKeyPolicy 回傳使用policy的副本，原本的SigningMethod不會被異動
+func (m *SigningMethodHMAC) WithKeyPolicy(policy *KeyPolicy) *SigningMethodHMAC {
+	clone := *m
+	clone.KeyPolicy = policy
+	return &clone
+}
+
 func (m *SigningMethodHMAC) Sign(signingBytes []byte, key any) ([]byte, error) {
 	privateKey, ok := key.([]byte)
 	if !ok {
 		return nil, fmt.Errorf("HMAC sign expects []byte. %w", ErrInvalidKeyType)
 	}
+	if err := m.KeyPolicy.checkHMAC(m, privateKey); err != nil {
+		return nil, err
+	}
 
 	// 由於hash本身是一個uint，所以若你不是從標準庫的變數去給，那麼數值就可能會有問題
 	if !m.Hash.Available() {
@@ -55,6 +68,9 @@ func (m *SigningMethodHMAC) Verify(
 	if !ok {
 		return fmt.Errorf("HMAC verify expects []byte. %w", ErrInvalidKeyType)
 	}
+	if err = m.KeyPolicy.checkHMAC(m, privateKey); err != nil {
+		return err
+	}
 
 	if !m.Hash.Available() {
 		return ErrHashUnavailable
@@ -19,7 +19,7 @@ var testClaims = jwt.MapClaims{
 // http://jwt.io/
 func TestSigningMethodHMAC_Verify(t *testing.T) {
 	m := jwt.SigningMethodHMAC256
-	privateKey := []byte("my key")
+	privateKey := []byte("my key: 0123456789abcdefghijklmn")
 	signature, err := m.Sign([]byte("Hello"), privateKey)
 	if err != nil {
 		t.Fatal(err)
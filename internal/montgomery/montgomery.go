@@ -0,0 +1,221 @@
+// Package montgomery 以Montgomery乘法對任意的奇數模數做有限體的運算
+//
+// ed448與secp256k1共用這份實作，各自只保留曲線的常數與編碼(位元組的順序、長度)
+// 所有的運算都不會依據數值的內容產生分支或者查表，也就是常數時間(constant-time)
+package montgomery
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+)
+
+// MaxLimbs 所支援的最大limb數，448位元(ed448)需要7個
+const MaxLimbs = 7
+
+// Element 以little-endian的uint64表示的數值，只會使用前 Modulus.Limbs 個limb，其餘必須為0
+// 除非特別說明，否則都處於Montgomery的形式(x*R mod m, R = 2^(64*limbs))
+type Element [MaxLimbs]uint64
+
+// Modulus 模數與其Montgomery運算所需的常數
+type Modulus struct {
+	n     int     // limb數
+	m     Element // 模數本身(非Montgomery形式)
+	r2    Element // R^2 mod m，用來轉換成Montgomery形式
+	m0inv uint64  // -m^-1 mod 2^64
+	one   Element // R mod m，也就是Montgomery形式的1
+	mm2   Element // m-2，用於費馬小定理求反元素(公開的常數，所以可以依據其位元做分支)
+}
+
+// New 建立以limbs個uint64表示的模數m，m必須是奇數並且不能超過limbs*64位元
+func New(m *big.Int, limbs int) *Modulus {
+	if limbs <= 0 || limbs > MaxLimbs || m.Bit(0) == 0 || m.BitLen() > limbs*64 {
+		panic("montgomery: invalid modulus")
+	}
+	md := &Modulus{n: limbs, m: FromBig(m)}
+
+	// Newton法求m[0]在2^64之下的反元素，每一輪正確的位元數都會加倍
+	inv := uint64(1)
+	for range 6 {
+		inv *= 2 - md.m[0]*inv
+	}
+	md.m0inv = -inv
+
+	r := new(big.Int).Lsh(big.NewInt(1), uint(limbs*64))
+	md.one = FromBig(new(big.Int).Mod(r, m))
+	md.r2 = FromBig(new(big.Int).Mod(new(big.Int).Mul(r, r), m))
+	md.mm2 = FromBig(new(big.Int).Sub(m, big.NewInt(2)))
+	return md
+}
+
+// FromBig 直接轉換(不做模數的檢查與Montgomery的轉換)，只在初始化常數的時候使用
+func FromBig(v *big.Int) Element {
+	var buf [MaxLimbs * 8]byte
+	v.FillBytes(buf[:])
+	var e Element
+	for i := range e {
+		e[i] = binary.BigEndian.Uint64(buf[len(buf)-8*(i+1):])
+	}
+	return e
+}
+
+// IsZero 若為0則回傳1，否則回傳0
+func (e *Element) IsZero() uint64 {
+	var v uint64
+	for i := range e {
+		v |= e[i]
+	}
+	return 1 ^ ((v | -v) >> 63)
+}
+
+// Equal 相等回傳1，否則回傳0
+func (e *Element) Equal(x *Element) uint64 {
+	var d Element
+	for i := range e {
+		d[i] = e[i] ^ x[i]
+	}
+	return d.IsZero()
+}
+
+// Select cond為1時z=b，為0時z=a
+func Select(z, a, b *Element, cond uint64) {
+	mask := -cond
+	for i := range z {
+		z[i] = a[i] ^ (mask & (a[i] ^ b[i]))
+	}
+}
+
+// Limbs 模數所使用的limb數
+func (md *Modulus) Limbs() int {
+	return md.n
+}
+
+// One Montgomery形式的1
+func (md *Modulus) One() Element {
+	return md.one
+}
+
+// Less 若x(非Montgomery形式)小於m則回傳1，否則回傳0
+func (md *Modulus) Less(x *Element) uint64 {
+	var b uint64
+	for i := range md.n {
+		_, b = bits.Sub64(x[i], md.m[i], b)
+	}
+	return b
+}
+
+// Reduce 若x大於等於m則減去一次m，x必須小於2m
+func (md *Modulus) Reduce(z, x *Element) {
+	md.reduce(z, x, 0)
+}
+
+// reduce 對 hi:x (hi只會是0或1) 減去一次m，x必須小於2m
+func (md *Modulus) reduce(z *Element, x *Element, hi uint64) {
+	var t Element
+	var b uint64
+	for i := range md.n {
+		t[i], b = bits.Sub64(x[i], md.m[i], b)
+	}
+	_, b = bits.Sub64(hi, 0, b)
+	// 有借位代表x < m，保留原本的數值
+	Select(z, &t, x, b)
+}
+
+func (md *Modulus) Add(z, x, y *Element) {
+	var t Element
+	var c uint64
+	for i := range md.n {
+		t[i], c = bits.Add64(x[i], y[i], c)
+	}
+	md.reduce(z, &t, c)
+}
+
+func (md *Modulus) Sub(z, x, y *Element) {
+	var t Element
+	var b uint64
+	for i := range md.n {
+		t[i], b = bits.Sub64(x[i], y[i], b)
+	}
+	// 若有借位則加回m
+	mask := -b
+	var c uint64
+	for i := range md.n {
+		z[i], c = bits.Add64(t[i], md.m[i]&mask, c)
+	}
+}
+
+func (md *Modulus) Neg(z, x *Element) {
+	var zero Element
+	md.Sub(z, &zero, x)
+}
+
+// Mul Montgomery乘法 z = x*y*R^-1 mod m (CIOS)
+func (md *Modulus) Mul(z, x, y *Element) {
+	n := md.n
+	var t [MaxLimbs + 2]uint64
+	for i := range n {
+		// t += x * y[i]
+		var c, cc uint64
+		for j := range n {
+			hi, lo := bits.Mul64(x[j], y[i])
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j], c = lo, hi
+		}
+		t[n], cc = bits.Add64(t[n], c, 0)
+		t[n+1] = cc
+
+		// t = (t + q*m) / 2^64，其中q讓最低的limb變為0
+		q := t[0] * md.m0inv
+		hi, lo := bits.Mul64(q, md.m[0])
+		_, cc = bits.Add64(lo, t[0], 0)
+		c = hi + cc
+		for j := 1; j < n; j++ {
+			hi, lo = bits.Mul64(q, md.m[j])
+			lo, cc = bits.Add64(lo, t[j], 0)
+			hi += cc
+			lo, cc = bits.Add64(lo, c, 0)
+			hi += cc
+			t[j-1], c = lo, hi
+		}
+		t[n-1], cc = bits.Add64(t[n], c, 0)
+		t[n] = t[n+1] + cc
+	}
+	hi := t[n]
+	t[n], t[n+1] = 0, 0 // 超過n的limb必須為0
+	md.reduce(z, (*Element)(t[:MaxLimbs]), hi)
+}
+
+func (md *Modulus) Square(z, x *Element) {
+	md.Mul(z, x, x)
+}
+
+// ToMont 轉換成Montgomery的形式，x只需要小於R(不一定要小於m)
+func (md *Modulus) ToMont(z, x *Element) {
+	md.Mul(z, x, &md.r2)
+}
+
+// FromMont 從Montgomery的形式轉換回來
+func (md *Modulus) FromMont(z, x *Element) {
+	md.Mul(z, x, &Element{1})
+}
+
+// Exp z = x^e，e是公開的常數(非Montgomery形式)，所以可以依據其位元做分支
+func (md *Modulus) Exp(z, x *Element, e *Element) {
+	r := md.one
+	base := *x
+	for i := md.n*64 - 1; i >= 0; i-- {
+		md.Square(&r, &r)
+		if (e[i/64]>>(i%64))&1 == 1 {
+			md.Mul(&r, &r, &base)
+		}
+	}
+	*z = r
+}
+
+// Inv 使用費馬小定理 z = x^(m-2)，m必須是質數，x為0時結果也是0
+func (md *Modulus) Inv(z, x *Element) {
+	md.Exp(z, x, &md.mm2)
+}
@@ -0,0 +1,86 @@
+package montgomery_test
+
+import (
+	"crypto/rand"
+	"github.com/CarsonSlovoka/jwt/internal/montgomery"
+	"math/big"
+	"testing"
+)
+
+// 與math/big的結果比對，涵蓋secp256k1(4 limbs)與ed448(7 limbs)所使用的模數
+func TestModulus(t *testing.T) {
+	p448 := new(big.Int).Lsh(big.NewInt(1), 448)
+	p448.Sub(p448, new(big.Int).Lsh(big.NewInt(1), 224))
+	p448.Sub(p448, big.NewInt(1))
+	p256, _ := new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	n256, _ := new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+	for _, tt := range []struct {
+		name  string
+		m     *big.Int
+		limbs int
+	}{
+		{"secp256k1 p", p256, 4},
+		{"secp256k1 n", n256, 4},
+		{"ed448 p", p448, 7},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			md := montgomery.New(tt.m, tt.limbs)
+			r := new(big.Int).Lsh(big.NewInt(1), uint(tt.limbs*64))
+			fromMont := func(e *montgomery.Element) *big.Int {
+				var out montgomery.Element
+				md.FromMont(&out, e)
+				return toBig(&out)
+			}
+
+			for range 50 {
+				a, _ := rand.Int(rand.Reader, tt.m)
+				b, _ := rand.Int(rand.Reader, tt.m)
+				ea, eb := montgomery.FromBig(a), montgomery.FromBig(b)
+				md.ToMont(&ea, &ea)
+				md.ToMont(&eb, &eb)
+
+				var z montgomery.Element
+				md.Mul(&z, &ea, &eb)
+				if want := new(big.Int).Mod(new(big.Int).Mul(a, b), tt.m); fromMont(&z).Cmp(want) != 0 {
+					t.Fatalf("mul: %x * %x", a, b)
+				}
+				md.Add(&z, &ea, &eb)
+				if want := new(big.Int).Mod(new(big.Int).Add(a, b), tt.m); fromMont(&z).Cmp(want) != 0 {
+					t.Fatalf("add: %x + %x", a, b)
+				}
+				md.Sub(&z, &ea, &eb)
+				if want := new(big.Int).Mod(new(big.Int).Sub(a, b), tt.m); fromMont(&z).Cmp(want) != 0 {
+					t.Fatalf("sub: %x - %x", a, b)
+				}
+				md.Inv(&z, &ea)
+				if want := new(big.Int).ModInverse(a, tt.m); fromMont(&z).Cmp(want) != 0 {
+					t.Fatalf("inv: %x", a)
+				}
+				// Montgomery形式本身應為 a*R mod m
+				if want := new(big.Int).Mod(new(big.Int).Mul(a, r), tt.m); toBig(&ea).Cmp(want) != 0 {
+					t.Fatalf("toMont: %x", a)
+				}
+			}
+
+			// 超出m的數值
+			over := montgomery.FromBig(new(big.Int).Add(tt.m, big.NewInt(1)))
+			if md.Less(&over) != 0 {
+				t.Fatal("m+1 should not be less than m")
+			}
+			md.Reduce(&over, &over)
+			if toBig(&over).Cmp(big.NewInt(1)) != 0 {
+				t.Fatal("reduce: (m+1) mod m != 1")
+			}
+		})
+	}
+}
+
+func toBig(e *montgomery.Element) *big.Int {
+	v := new(big.Int)
+	for i := len(e) - 1; i >= 0; i-- {
+		v.Lsh(v, 64)
+		v.Or(v, new(big.Int).SetUint64(e[i]))
+	}
+	return v
+}
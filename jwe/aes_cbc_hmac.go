@@ -0,0 +1,123 @@
+package jwe
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+)
+
+// ContentEncryptionCBCHMAC AES_CBC_HMAC_SHA2 https://datatracker.ietf.org/doc/html/rfc7518#section-5.2
+//
+// CEK = MAC_KEY || ENC_KEY 兩者各佔一半，tag的長度也是一半
+type ContentEncryptionCBCHMAC struct {
+	Name    string
+	KeySize int // CEK的byte數: 32, 48, 64
+	Hash    crypto.Hash
+}
+
+var (
+	ContentEncryptionA128CBCHS256 *ContentEncryptionCBCHMAC
+	ContentEncryptionA192CBCHS384 *ContentEncryptionCBCHMAC
+	ContentEncryptionA256CBCHS512 *ContentEncryptionCBCHMAC
+)
+
+func init() {
+	ContentEncryptionA128CBCHS256 = &ContentEncryptionCBCHMAC{"A128CBC-HS256", 32, crypto.SHA256}
+	ContentEncryptionA192CBCHS384 = &ContentEncryptionCBCHMAC{"A192CBC-HS384", 48, crypto.SHA384}
+	ContentEncryptionA256CBCHS512 = &ContentEncryptionCBCHMAC{"A256CBC-HS512", 64, crypto.SHA512}
+}
+
+func (m *ContentEncryptionCBCHMAC) EncName() string {
+	return m.Name
+}
+
+func (m *ContentEncryptionCBCHMAC) CEKSize() int {
+	return m.KeySize
+}
+
+func (m *ContentEncryptionCBCHMAC) Encrypt(cek, plaintext, aad []byte) (iv, ciphertext, tag []byte, err error) {
+	macKey, block, err := m.keys(cek)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if iv, err = randomBytes(aes.BlockSize); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// PKCS #7 padding
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	ciphertext = make([]byte, len(plaintext)+padding)
+	copy(ciphertext, plaintext)
+	for i := len(plaintext); i < len(ciphertext); i++ {
+		ciphertext[i] = byte(padding)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+	return iv, ciphertext, m.authTag(macKey, aad, iv, ciphertext), nil
+}
+
+func (m *ContentEncryptionCBCHMAC) Decrypt(cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	macKey, block, err := m.keys(cek)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, ErrDecryption
+	}
+
+	// 先驗證tag再解密，避免padding oracle
+	if !hmac.Equal(m.authTag(macKey, aad, iv, ciphertext), tag) {
+		return nil, ErrDecryption
+	}
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, ErrDecryption
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, ErrDecryption
+	}
+	expected := make([]byte, padding)
+	for i := range expected {
+		expected[i] = byte(padding)
+	}
+	if subtle.ConstantTimeCompare(plaintext[len(plaintext)-padding:], expected) != 1 {
+		return nil, ErrDecryption
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+// keys 將CEK拆成MAC_KEY與ENC_KEY
+func (m *ContentEncryptionCBCHMAC) keys(cek []byte) ([]byte, cipher.Block, error) {
+	if len(cek) != m.KeySize {
+		return nil, nil, fmt.Errorf("%s: cek size %d, expected %d. %w", m.Name, len(cek), m.KeySize, ErrInvalidKeySize)
+	}
+	if !m.Hash.Available() {
+		return nil, nil, jwt.ErrHashUnavailable
+	}
+	block, err := aes.NewCipher(cek[m.KeySize/2:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return cek[:m.KeySize/2], block, nil
+}
+
+// authTag T = HMAC(MAC_KEY, A || IV || E || AL) 取前半
+// AL為AAD的bit數，以64-bit big-endian表示
+func (m *ContentEncryptionCBCHMAC) authTag(macKey, aad, iv, ciphertext []byte) []byte {
+	hasher := hmac.New(m.Hash.New, macKey)
+	hasher.Write(aad)
+	hasher.Write(iv)
+	hasher.Write(ciphertext)
+	hasher.Write(binary.BigEndian.AppendUint64(nil, uint64(len(aad))*8))
+	return hasher.Sum(nil)[:len(macKey)]
+}
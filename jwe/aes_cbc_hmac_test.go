@@ -0,0 +1,87 @@
+package jwe_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/CarsonSlovoka/jwt/jwe"
+	"testing"
+)
+
+// https://datatracker.ietf.org/doc/html/rfc7516#appendix-A.2
+func TestContentEncryptionCBCHMAC_A128CBCHS256(t *testing.T) {
+	cek := []byte{
+		4, 211, 31, 197, 84, 157, 252, 254, 11, 100, 157, 250, 63, 170, 106, 206,
+		107, 124, 212, 45, 111, 107, 9, 219, 200, 177, 0, 240, 143, 156, 44, 207,
+	}
+	iv := []byte{3, 22, 60, 12, 43, 67, 104, 105, 108, 108, 105, 99, 111, 116, 104, 101}
+	aad := []byte("eyJhbGciOiJSU0ExXzUiLCJlbmMiOiJBMTI4Q0JDLUhTMjU2In0")
+	ciphertext := []byte{
+		40, 57, 83, 181, 119, 33, 133, 148, 198, 185, 243, 24, 152, 230, 6,
+		75, 129, 223, 127, 19, 210, 82, 183, 230, 168, 33, 215, 104, 143,
+		112, 56, 102,
+	}
+	tag := []byte{246, 17, 244, 190, 4, 95, 98, 3, 231, 0, 115, 157, 242, 203, 100, 191}
+
+	plaintext, err := jwe.ContentEncryptionA128CBCHS256.Decrypt(cek, iv, ciphertext, tag, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "Live long and prosper." {
+		t.Fatal(string(plaintext))
+	}
+
+	// 任何一個部分被竄改都不可以解密成功
+	for i, tamper := range []func() ([]byte, []byte, []byte, []byte){
+		func() ([]byte, []byte, []byte, []byte) { return flip(iv), ciphertext, tag, aad },
+		func() ([]byte, []byte, []byte, []byte) { return iv, flip(ciphertext), tag, aad },
+		func() ([]byte, []byte, []byte, []byte) { return iv, ciphertext, flip(tag), aad },
+		func() ([]byte, []byte, []byte, []byte) { return iv, ciphertext, tag, flip(aad) },
+		func() ([]byte, []byte, []byte, []byte) { return iv, ciphertext, tag[:8], aad },
+		func() ([]byte, []byte, []byte, []byte) { return iv, ciphertext[:16], tag, aad },
+	} {
+		iv, ciphertext, tag, aad := tamper()
+		if _, err = jwe.ContentEncryptionA128CBCHS256.Decrypt(cek, iv, ciphertext, tag, aad); !errors.Is(err, jwe.ErrDecryption) {
+			t.Fatal(i, err)
+		}
+	}
+}
+
+// https://datatracker.ietf.org/doc/html/rfc7518#appendix-B.3
+func TestContentEncryptionCBCHMAC_A256CBCHS512(t *testing.T) {
+	cek := make([]byte, 64)
+	for i := range cek {
+		cek[i] = byte(i)
+	}
+	plaintext := []byte("A cipher system must not be required to be secret, and it must be able to fall into the hands of the enemy without inconvenience")
+	iv := []byte{0x1a, 0xf3, 0x8c, 0x2d, 0xc2, 0xb9, 0x6f, 0xfd, 0xd8, 0x66, 0x94, 0x09, 0x23, 0x41, 0xbc, 0x04}
+	aad := []byte("The second principle of Auguste Kerckhoffs")
+	ciphertext := []byte{
+		0x4a, 0xff, 0xaa, 0xad, 0xb7, 0x8c, 0x31, 0xc5, 0xda, 0x4b, 0x1b, 0x59, 0x0d, 0x10, 0xff, 0xbd,
+		0x3d, 0xd8, 0xd5, 0xd3, 0x02, 0x42, 0x35, 0x26, 0x91, 0x2d, 0xa0, 0x37, 0xec, 0xbc, 0xc7, 0xbd,
+		0x82, 0x2c, 0x30, 0x1d, 0xd6, 0x7c, 0x37, 0x3b, 0xcc, 0xb5, 0x84, 0xad, 0x3e, 0x92, 0x79, 0xc2,
+		0xe6, 0xd1, 0x2a, 0x13, 0x74, 0xb7, 0x7f, 0x07, 0x75, 0x53, 0xdf, 0x82, 0x94, 0x10, 0x44, 0x6b,
+		0x36, 0xeb, 0xd9, 0x70, 0x66, 0x29, 0x6a, 0xe6, 0x42, 0x7e, 0xa7, 0x5c, 0x2e, 0x08, 0x46, 0xa1,
+		0x1a, 0x09, 0xcc, 0xf5, 0x37, 0x0d, 0xc8, 0x0b, 0xfe, 0xcb, 0xad, 0x28, 0xc7, 0x3f, 0x09, 0xb3,
+		0xa3, 0xb7, 0x5e, 0x66, 0x2a, 0x25, 0x94, 0x41, 0x0a, 0xe4, 0x96, 0xb2, 0xe2, 0xe6, 0x60, 0x9e,
+		0x31, 0xe6, 0xe0, 0x2c, 0xc8, 0x37, 0xf0, 0x53, 0xd2, 0x1f, 0x37, 0xff, 0x4f, 0x51, 0x95, 0x0b,
+		0xbe, 0x26, 0x38, 0xd0, 0x9d, 0xd7, 0xa4, 0x93, 0x09, 0x30, 0x80, 0x6d, 0x07, 0x03, 0xb1, 0xf6,
+	}
+	tag := []byte{
+		0x4d, 0xd3, 0xb4, 0xc0, 0x88, 0xa7, 0xf4, 0x5c, 0x21, 0x68, 0x39, 0x64, 0x5b, 0x20, 0x12, 0xbf,
+		0x2e, 0x62, 0x69, 0xa8, 0xc5, 0x6a, 0x81, 0x6d, 0xbc, 0x1b, 0x26, 0x77, 0x61, 0x95, 0x5b, 0xc5,
+	}
+
+	got, err := jwe.ContentEncryptionA256CBCHS512.Decrypt(cek, iv, ciphertext, tag, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatal(string(got))
+	}
+}
+
+func flip(bs []byte) []byte {
+	out := bytes.Clone(bs)
+	out[len(out)-1] ^= 1
+	return out
+}
@@ -0,0 +1,79 @@
+package jwe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+)
+
+const (
+	gcmIVSize  = 12 // 96 bits
+	gcmTagSize = 16 // 128 bits
+)
+
+// ContentEncryptionGCM AES GCM https://datatracker.ietf.org/doc/html/rfc7518#section-5.3
+type ContentEncryptionGCM struct {
+	Name    string
+	KeySize int // CEK的byte數: 16, 24, 32
+}
+
+var (
+	ContentEncryptionA128GCM *ContentEncryptionGCM
+	ContentEncryptionA192GCM *ContentEncryptionGCM
+	ContentEncryptionA256GCM *ContentEncryptionGCM
+)
+
+func init() {
+	ContentEncryptionA128GCM = &ContentEncryptionGCM{"A128GCM", 16}
+	ContentEncryptionA192GCM = &ContentEncryptionGCM{"A192GCM", 24}
+	ContentEncryptionA256GCM = &ContentEncryptionGCM{"A256GCM", 32}
+}
+
+func (m *ContentEncryptionGCM) EncName() string {
+	return m.Name
+}
+
+func (m *ContentEncryptionGCM) CEKSize() int {
+	return m.KeySize
+}
+
+func (m *ContentEncryptionGCM) Encrypt(cek, plaintext, aad []byte) (iv, ciphertext, tag []byte, err error) {
+	aead, err := m.aead(cek)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if iv, err = randomBytes(gcmIVSize); err != nil {
+		return nil, nil, nil, err
+	}
+	sealed := aead.Seal(nil, iv, plaintext, aad) // ciphertext || tag
+	offset := len(sealed) - gcmTagSize
+	return iv, sealed[:offset], sealed[offset:], nil
+}
+
+func (m *ContentEncryptionGCM) Decrypt(cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
+	aead, err := m.aead(cek)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != gcmIVSize || len(tag) != gcmTagSize {
+		return nil, ErrDecryption
+	}
+	sealed := make([]byte, 0, len(ciphertext)+len(tag))
+	sealed = append(append(sealed, ciphertext...), tag...)
+	plaintext, err := aead.Open(nil, iv, sealed, aad)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+func (m *ContentEncryptionGCM) aead(cek []byte) (cipher.AEAD, error) {
+	if len(cek) != m.KeySize {
+		return nil, fmt.Errorf("%s: cek size %d, expected %d. %w", m.Name, len(cek), m.KeySize, ErrInvalidKeySize)
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
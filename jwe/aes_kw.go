@@ -0,0 +1,133 @@
+package jwe
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+)
+
+// defaultIV RFC 3394 section 2.2.3.1
+var defaultIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// KeyManagementAESKW AES Key Wrap https://datatracker.ietf.org/doc/html/rfc7518#section-4.4
+//
+// 使用共享的對稱鑰匙(KEK)將隨機產生的CEK加密
+type KeyManagementAESKW struct {
+	Name    string
+	KeySize int // KEK的byte數: 16, 24, 32
+}
+
+var (
+	KeyManagementA128KW *KeyManagementAESKW
+	KeyManagementA192KW *KeyManagementAESKW
+	KeyManagementA256KW *KeyManagementAESKW
+)
+
+func init() {
+	KeyManagementA128KW = &KeyManagementAESKW{"A128KW", 16}
+	KeyManagementA192KW = &KeyManagementAESKW{"A192KW", 24}
+	KeyManagementA256KW = &KeyManagementAESKW{"A256KW", 32}
+}
+
+func (m *KeyManagementAESKW) AlgName() string {
+	return m.Name
+}
+
+func (m *KeyManagementAESKW) WrapKey(cekSize int, key any, _ map[string]any) ([]byte, []byte, error) {
+	block, err := m.kek(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	cek, err := randomBytes(cekSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptedKey, err := keyWrap(block, cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cek, encryptedKey, nil
+}
+
+func (m *KeyManagementAESKW) UnwrapKey(encryptedKey []byte, cekSize int, key any, _ map[string]any) ([]byte, error) {
+	block, err := m.kek(key)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := keyUnwrap(block, encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(cek) != cekSize {
+		return nil, ErrDecryption
+	}
+	return cek, nil
+}
+
+func (m *KeyManagementAESKW) kek(key any) (cipher.Block, error) {
+	kek, ok := key.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("%s expects []byte. %w", m.Name, jwt.ErrInvalidKeyType)
+	}
+	if len(kek) != m.KeySize {
+		return nil, fmt.Errorf("%s: key size %d, expected %d. %w", m.Name, len(kek), m.KeySize, ErrInvalidKeySize)
+	}
+	return aes.NewCipher(kek)
+}
+
+// keyWrap https://datatracker.ietf.org/doc/html/rfc3394#section-2.2.1
+func keyWrap(block cipher.Block, plaintext []byte) ([]byte, error) {
+	if len(plaintext) < 16 || len(plaintext)%8 != 0 {
+		return nil, fmt.Errorf("key wrap input must be a multiple of 8 bytes and at least 16 bytes. %w", ErrInvalidKeySize)
+	}
+	n := len(plaintext) / 8
+	out := make([]byte, 8+len(plaintext)) // A || R[1] || ... || R[n]
+	copy(out, defaultIV)
+	copy(out[8:], plaintext)
+
+	buf := make([]byte, 16)
+	for j := 0; j <= 5; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf, out[:8])
+			copy(buf[8:], out[i*8:(i+1)*8])
+			block.Encrypt(buf, buf)
+
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(out[i*8:], buf[8:])
+		}
+	}
+	return out, nil
+}
+
+// keyUnwrap https://datatracker.ietf.org/doc/html/rfc3394#section-2.2.2
+func keyUnwrap(block cipher.Block, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 24 || len(ciphertext)%8 != 0 {
+		return nil, ErrDecryption
+	}
+	n := len(ciphertext)/8 - 1
+	a := binary.BigEndian.Uint64(ciphertext[:8])
+	out := make([]byte, len(ciphertext)-8)
+	copy(out, ciphertext[8:])
+
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], a^t)
+			copy(buf[8:], out[(i-1)*8:i*8])
+			block.Decrypt(buf, buf)
+
+			a = binary.BigEndian.Uint64(buf[:8])
+			copy(out[(i-1)*8:], buf[8:])
+		}
+	}
+
+	if subtle.ConstantTimeCompare(binary.BigEndian.AppendUint64(nil, a), defaultIV) != 1 {
+		return nil, ErrDecryption
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+package jwe_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"github.com/CarsonSlovoka/jwt/jwe"
+	"testing"
+)
+
+// https://datatracker.ietf.org/doc/html/rfc3394#section-4
+func TestKeyManagementAESKW_rfc3394(t *testing.T) {
+	for _, tt := range []struct {
+		km        *jwe.KeyManagementAESKW
+		kek       string
+		cek       string
+		encrypted string
+	}{
+		{jwe.KeyManagementA128KW, "000102030405060708090A0B0C0D0E0F", "00112233445566778899AABBCCDDEEFF", "1FA68B0A8112B447AEF34BD8FB5A7B829D3E862371D2CFE5"},
+		{jwe.KeyManagementA192KW, "000102030405060708090A0B0C0D0E0F1011121314151617", "00112233445566778899AABBCCDDEEFF", "96778B25AE6CA435F92B5B97C050AED2468AB8A17AD84E5D"},
+		{jwe.KeyManagementA256KW, "000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F", "00112233445566778899AABBCCDDEEFF0001020304050607", "A8F9BC1612C68B3FF6E6F4FBE30E71E4769C8B80A32CB8958CD5D17D6B254DA1"},
+	} {
+		kek, _ := hex.DecodeString(tt.kek)
+		expected, _ := hex.DecodeString(tt.cek)
+		encrypted, _ := hex.DecodeString(tt.encrypted)
+		cek, err := tt.km.UnwrapKey(encrypted, len(expected), kek, nil)
+		if err != nil {
+			t.Fatal(tt.km.Name, err)
+		}
+		if !bytes.Equal(cek, expected) {
+			t.Fatal(tt.km.Name, hex.EncodeToString(cek))
+		}
+
+		encrypted[0] ^= 1
+		if _, err = tt.km.UnwrapKey(encrypted, len(expected), kek, nil); !errors.Is(err, jwe.ErrDecryption) {
+			t.Fatal(tt.km.Name, err)
+		}
+	}
+}
+
+// https://datatracker.ietf.org/doc/html/rfc7516#appendix-A.3
+func TestKeyManagementAESKW_rfc7516(t *testing.T) {
+	const token = "eyJhbGciOiJBMTI4S1ciLCJlbmMiOiJBMTI4Q0JDLUhTMjU2In0." +
+		"6KB707dM9YTIgHtLvtgWQ8mKwboJW3of9locizkDTHzBC2IlrT1oOQ." +
+		"AxY8DCtDaGlsbGljb3RoZQ." +
+		"KDlTtXchhZTGufMYmOYGS4HffxPSUrfmqCHXaI9wOGY." +
+		"U0m_YmjN04DJvceFICbCVQ"
+	plaintext, err := decryptPayload(t, token, mustParseJWK(t, `{"kty":"oct","k":"GawgguFyGrWKav7AX4VKUg"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "Live long and prosper." {
+		t.Fatal(string(plaintext))
+	}
+}
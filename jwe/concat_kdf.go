@@ -0,0 +1,31 @@
+package jwe
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// concatKDF NIST SP 800-56A Concatenation Key Derivation Function (使用SHA-256)
+// https://datatracker.ietf.org/doc/html/rfc7518#section-4.6.2
+//
+//	OtherInfo = AlgorithmID || PartyUInfo || PartyVInfo || SuppPubInfo
+//
+// 前三者皆以 32-bit big-endian 的長度作為前綴，SuppPubInfo為keyDataLen(bit數)
+func concatKDF(z []byte, algID string, apu, apv []byte, keySize int) []byte {
+	var otherInfo []byte
+	for _, data := range [][]byte{[]byte(algID), apu, apv} {
+		otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(len(data)))
+		otherInfo = append(otherInfo, data...)
+	}
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(keySize*8))
+
+	key := make([]byte, 0, keySize+sha256.Size)
+	for counter := uint32(1); len(key) < keySize; counter++ {
+		hasher := sha256.New()
+		hasher.Write(binary.BigEndian.AppendUint32(nil, counter))
+		hasher.Write(z)
+		hasher.Write(otherInfo)
+		key = hasher.Sum(key)
+	}
+	return key[:keySize]
+}
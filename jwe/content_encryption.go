@@ -0,0 +1,20 @@
+package jwe
+
+// IContentEncryption 用CEK對內容做認證加密(AEAD) (JWE header的enc)
+// https://datatracker.ietf.org/doc/html/rfc7518#section-5.1
+type IContentEncryption interface {
+	// EncName A128GCM, A128CBC-HS256, ...
+	EncName() string
+
+	// CEKSize CEK所需的byte數
+	CEKSize() int
+
+	// Encrypt 每次都會產生新的iv
+	Encrypt(cek []byte,
+		plaintext []byte,
+		aad []byte, // Additional Authenticated Data: ASCII(BASE64URL(JWE Protected Header))
+	) (iv, ciphertext, tag []byte, err error)
+
+	// Decrypt 認證失敗時回傳 ErrDecryption
+	Decrypt(cek, iv, ciphertext, tag, aad []byte) (plaintext []byte, err error)
+}
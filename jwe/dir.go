@@ -0,0 +1,42 @@
+package jwe
+
+import (
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+)
+
+// KeyManagementDir 直接使用共享的對稱鑰匙當作CEK，此時JWE Encrypted Key為空
+// https://datatracker.ietf.org/doc/html/rfc7518#section-4.5
+type KeyManagementDir struct{}
+
+var KeyManagementDirect = &KeyManagementDir{}
+
+func (m *KeyManagementDir) AlgName() string {
+	return "dir"
+}
+
+func (m *KeyManagementDir) WrapKey(cekSize int, key any, _ map[string]any) ([]byte, []byte, error) {
+	cek, err := m.sharedKey(cekSize, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cek, nil, nil
+}
+
+func (m *KeyManagementDir) UnwrapKey(encryptedKey []byte, cekSize int, key any, _ map[string]any) ([]byte, error) {
+	if len(encryptedKey) != 0 {
+		return nil, fmt.Errorf("dir: encrypted key must be empty. %w", jwt.ErrTokenMalformed)
+	}
+	return m.sharedKey(cekSize, key)
+}
+
+func (m *KeyManagementDir) sharedKey(cekSize int, key any) ([]byte, error) {
+	sharedKey, ok := key.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("dir expects []byte. %w", jwt.ErrInvalidKeyType)
+	}
+	if len(sharedKey) != cekSize {
+		return nil, fmt.Errorf("dir: key size %d, expected %d. %w", len(sharedKey), cekSize, ErrInvalidKeySize)
+	}
+	return append([]byte(nil), sharedKey...), nil
+}
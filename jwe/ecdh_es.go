@@ -0,0 +1,209 @@
+package jwe
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/jwk"
+	"math/big"
+)
+
+// KeyManagementECDH Elliptic Curve Diffie-Hellman Ephemeral Static
+// https://datatracker.ietf.org/doc/html/rfc7518#section-4.6
+//
+// 加密方產生一把臨時的鑰匙(epk)與對方的公鑰做ECDH，再經由Concat KDF衍生出鑰匙
+// 支援的曲線: P-256, P-384, P-521, X25519
+//
+// 鑰匙可以是 *ecdsa.PublicKey, *ecdh.PublicKey (解密時則為對應的私鑰)
+type KeyManagementECDH struct {
+	Name string
+
+	// KeyWrap 為nil表示Direct Key Agreement(ECDH-ES)，衍生出來的鑰匙直接當作CEK
+	// 否則衍生出來的鑰匙會作為KEK，將隨機產生的CEK包裝起來(ECDH-ES+A128KW, ...)
+	KeyWrap *KeyManagementAESKW
+}
+
+var (
+	KeyManagementECDHES       *KeyManagementECDH
+	KeyManagementECDHESA128KW *KeyManagementECDH
+	KeyManagementECDHESA192KW *KeyManagementECDH
+	KeyManagementECDHESA256KW *KeyManagementECDH
+)
+
+func init() {
+	KeyManagementECDHES = &KeyManagementECDH{"ECDH-ES", nil}
+	KeyManagementECDHESA128KW = &KeyManagementECDH{"ECDH-ES+A128KW", KeyManagementA128KW}
+	KeyManagementECDHESA192KW = &KeyManagementECDH{"ECDH-ES+A192KW", KeyManagementA192KW}
+	KeyManagementECDHESA256KW = &KeyManagementECDH{"ECDH-ES+A256KW", KeyManagementA256KW}
+}
+
+func (m *KeyManagementECDH) AlgName() string {
+	return m.Name
+}
+
+// WrapKey 會在header寫入epk，若header已經有apu, apv(base64url)，則會一併用於Concat KDF
+func (m *KeyManagementECDH) WrapKey(cekSize int, key any, header map[string]any) ([]byte, []byte, error) {
+	publicKey, err := toECDHPublicKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s wrap key: %w", m.Name, err)
+	}
+	ephemeral, err := publicKey.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if header["epk"], err = newEPK(ephemeral.PublicKey()); err != nil {
+		return nil, nil, err
+	}
+
+	z, err := ephemeral.ECDH(publicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w %w", err, jwt.ErrInvalidKey)
+	}
+	derivedKey, err := m.deriveKey(z, cekSize, header)
+	if err != nil {
+		return nil, nil, err
+	}
+	if m.KeyWrap == nil {
+		return derivedKey, nil, nil
+	}
+	return m.KeyWrap.WrapKey(cekSize, derivedKey, header)
+}
+
+func (m *KeyManagementECDH) UnwrapKey(encryptedKey []byte, cekSize int, key any, header map[string]any) ([]byte, error) {
+	privateKey, err := toECDHPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s unwrap key: %w", m.Name, err)
+	}
+	epk, err := parseEPK(header)
+	if err != nil {
+		return nil, err
+	}
+	if epk.Curve() != privateKey.Curve() {
+		return nil, fmt.Errorf("epk: curve mismatch. %w", jwt.ErrInvalidKey)
+	}
+
+	z, err := privateKey.ECDH(epk)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	derivedKey, err := m.deriveKey(z, cekSize, header)
+	if err != nil {
+		return nil, err
+	}
+	if m.KeyWrap == nil {
+		if len(encryptedKey) != 0 {
+			return nil, fmt.Errorf("%s: encrypted key must be empty. %w", m.Name, jwt.ErrTokenMalformed)
+		}
+		return derivedKey, nil
+	}
+	return m.KeyWrap.UnwrapKey(encryptedKey, cekSize, derivedKey, header)
+}
+
+// deriveKey https://datatracker.ietf.org/doc/html/rfc7518#section-4.6.2
+//
+// Direct Key Agreement的AlgorithmID為enc，長度為CEK的長度；否則為alg，長度為KEK的長度
+func (m *KeyManagementECDH) deriveKey(z []byte, cekSize int, header map[string]any) ([]byte, error) {
+	algID, keySize := m.Name, 0
+	if m.KeyWrap == nil {
+		enc, ok := header["enc"].(string)
+		if !ok {
+			return nil, fmt.Errorf("enc not found %w", jwt.ErrTokenMalformed)
+		}
+		algID, keySize = enc, cekSize
+	} else {
+		keySize = m.KeyWrap.KeySize
+	}
+
+	var partyInfo [2][]byte
+	for i, name := range []string{"apu", "apv"} {
+		value, exists := header[name]
+		if !exists {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a string. %w", name, jwt.ErrTokenMalformed)
+		}
+		var err error
+		if partyInfo[i], err = base64.RawURLEncoding.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("could not base64 decode %s. %w %w", name, err, jwt.ErrTokenMalformed)
+		}
+	}
+	return concatKDF(z, algID, partyInfo[0], partyInfo[1], keySize), nil
+}
+
+func toECDHPublicKey(key any) (*ecdh.PublicKey, error) {
+	switch k := key.(type) {
+	case *ecdh.PublicKey:
+		return k, nil
+	case *ecdsa.PublicKey:
+		publicKey, err := k.ECDH()
+		if err != nil {
+			return nil, fmt.Errorf("%w %w", err, jwt.ErrInvalidKey)
+		}
+		return publicKey, nil
+	}
+	return nil, fmt.Errorf("expects *ecdsa.PublicKey or *ecdh.PublicKey. %w", jwt.ErrInvalidKeyType)
+}
+
+func toECDHPrivateKey(key any) (*ecdh.PrivateKey, error) {
+	switch k := key.(type) {
+	case *ecdh.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		privateKey, err := k.ECDH()
+		if err != nil {
+			return nil, fmt.Errorf("%w %w", err, jwt.ErrInvalidKey)
+		}
+		return privateKey, nil
+	}
+	return nil, fmt.Errorf("expects *ecdsa.PrivateKey or *ecdh.PrivateKey. %w", jwt.ErrInvalidKeyType)
+}
+
+// newEPK 將臨時的公鑰轉成JWK, NIST的曲線以kty: EC表示，X25519則為kty: OKP
+func newEPK(publicKey *ecdh.PublicKey) (*jwk.Key, error) {
+	var curve elliptic.Curve
+	switch publicKey.Curve() {
+	case ecdh.X25519():
+		return jwk.New(publicKey)
+	case ecdh.P256():
+		curve = elliptic.P256()
+	case ecdh.P384():
+		curve = elliptic.P384()
+	case ecdh.P521():
+		curve = elliptic.P521()
+	}
+	bs := publicKey.Bytes() // 0x04 || X || Y
+	size := (len(bs) - 1) / 2
+	return jwk.New(&ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(bs[1 : 1+size]),
+		Y:     new(big.Int).SetBytes(bs[1+size:]),
+	})
+}
+
+// parseEPK 取出header的epk，jwk.Parse會確認點在曲線上
+func parseEPK(header map[string]any) (*ecdh.PublicKey, error) {
+	value, exists := header["epk"]
+	if !exists {
+		return nil, fmt.Errorf("epk not found %w", jwt.ErrTokenMalformed)
+	}
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("epk: %w %w", err, jwt.ErrTokenMalformed)
+	}
+	epk, err := jwk.Parse(bs)
+	if err != nil {
+		return nil, fmt.Errorf("epk: %w %w", err, jwt.ErrTokenMalformed)
+	}
+	publicKey, err := toECDHPublicKey(epk.Key) // 若epk含有私鑰也會在此被拒絕
+	if err != nil {
+		return nil, fmt.Errorf("epk: %w %w", err, jwt.ErrTokenMalformed)
+	}
+	return publicKey, nil
+}
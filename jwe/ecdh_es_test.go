@@ -0,0 +1,192 @@
+package jwe_test
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/jwe"
+	"github.com/CarsonSlovoka/jwt/jwk"
+	"github.com/CarsonSlovoka/jwt/validator"
+	"strings"
+	"testing"
+)
+
+// https://datatracker.ietf.org/doc/html/rfc7520#section-5
+const cookbookPlaintext = "You can trust us to stick with you through thick and thin–to the bitter end. And you can trust us to keep any secret of yours–closer than you keep it yourself. But you cannot trust us to let you face trouble alone, and go off without a word. We are your friends, Frodo."
+
+func mustParseJWK(t *testing.T, src string) any {
+	key, err := jwk.Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key.Key
+}
+
+func decryptPayload(t *testing.T, token string, key any) ([]byte, error) {
+	decryptFunc, err := jwe.NewParser().ParsePayload(token, getKeyManagement, getContentEncryption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decryptFunc(nil, func(*jwe.Token) (any, error) {
+		return key, nil
+	})
+}
+
+// RFC 7520的範例
+func TestKeyManagementECDH_cookbook(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		key   string
+		token string
+	}{
+		{
+			// https://datatracker.ietf.org/doc/html/rfc7520#section-5.4
+			"ECDH-ES+A128KW",
+			`{"kty":"EC","kid":"peregrin.took@tuckborough.example","use":"enc","crv":"P-384",
+			"x":"YU4rRUzdmVqmRtWOs2OpDE_T5fsNIodcG8G5FWPrTPMyxpzsSOGaQLpe2FpxBmu2",
+			"y":"A8-yxCHxkfBz3hKZfI1jUYMjUhsEveZ9THuwFjH2sCNdtksRJU7D5-SkgaFL1ETP",
+			"d":"iTx2pk7wW-GqJkHcEkFQb2EFyYcO7RugmaW3mRrQVAOUiPommT0IdnYK2xDlZh-j"}`,
+			"eyJhbGciOiJFQ0RILUVTK0ExMjhLVyIsImtpZCI6InBlcmVncmluLnRvb2tAdHVja2Jvcm91Z2guZXhhbXBsZSIsImVwayI6eyJrdHkiOiJFQyIsImNydiI6IlAtMzg0IiwieCI6InVCbzRrSFB3Nmtiang1bDB4b3dyZF9vWXpCbWF6LUdLRlp1NHhBRkZrYllpV2d1dEVLNml1RURzUTZ3TmROZzMiLCJ5Ijoic3AzcDVTR2haVkMyZmFYdW1JLWU5SlUyTW84S3BvWXJGRHI1eVBOVnRXNFBnRXdaT3lRVEEtSmRhWTh0YjdFMCJ9LCJlbmMiOiJBMTI4R0NNIn0." +
+				"0DJjBXri_kBcC46IkU5_Jk9BqaQeHdv2." +
+				"mH-G2zVqgztUtnW_." +
+				"tkZuOO9h95OgHJmkkrfLBisku8rGf6nzVxhRM3sVOhXgz5NJ76oID7lpnAi_cPWJRCjSpAaUZ5dOR3Spy7QuEkmKx8-3RCMhSYMzsXaEwDdXta9Mn5B7cCBoJKB0IgEnj_qfo1hIi-uEkUpOZ8aLTZGHfpl05jMwbKkTe2yK3mjF6SBAsgicQDVCkcY9BLluzx1RmC3ORXaM0JaHPB93YcdSDGgpgBWMVrNU1ErkjcMqMoT_wtCex3w03XdLkjXIuEr2hWgeP-nkUZTPU9EoGSPj6fAS-bSz87RCPrxZdj_iVyC6QWcqAu07WNhjzJEPc4jVntRJ6K53NgPQ5p99l3Z408OUqj4ioYezbS6vTPlQ." +
+				"WuGzxmcreYjpHGJoa17EBg",
+		},
+		{
+			// https://datatracker.ietf.org/doc/html/rfc7520#section-5.5
+			"ECDH-ES",
+			`{"kty":"EC","kid":"meriadoc.brandybuck@buckland.example","use":"enc","crv":"P-256",
+			"x":"Ze2loSV3wrroKUN_4zhwGhCqo3Xhu1td4QjeQ5wIVR0",
+			"y":"HlLtdXARY_f55A3fnzQbPcm6hgr34Mp8p-nuzQCE0Zw",
+			"d":"r_kHyZ-a06rmxM3yESK84r1otSg-aQcVStkRhA-iCM8"}`,
+			"eyJhbGciOiJFQ0RILUVTIiwia2lkIjoibWVyaWFkb2MuYnJhbmR5YnVja0BidWNrbGFuZC5leGFtcGxlIiwiZXBrIjp7Imt0eSI6IkVDIiwiY3J2IjoiUC0yNTYiLCJ4IjoibVBVS1RfYkFXR0hJaGcwVHBqanFWc1AxclhXUXVfdndWT0hIdE5rZFlvQSIsInkiOiI4QlFBc0ltR2VBUzQ2ZnlXdzVNaFlmR1RUMElqQnBGdzJTUzM0RHY0SXJzIn0sImVuYyI6IkExMjhDQkMtSFMyNTYifQ." +
+				"." +
+				"yc9N8v5sYyv3iGQT926IUg." +
+				"BoDlwPnTypYq-ivjmQvAYJLb5Q6l-F3LIgQomlz87yW4OPKbWE1zSTEFjDfhU9IPIOSA9Bml4m7iDFwA-1ZXvHteLDtw4R1XRGMEsDIqAYtskTTmzmzNa-_q4F_evAPUmwlO-ZG45Mnq4uhM1fm_D9rBtWolqZSF3xGNNkpOMQKF1Cl8i8wjzRli7-IXgyirlKQsbhhqRzkv8IcY6aHl24j03C-AR2le1r7URUhArM79BY8soZU0lzwI-sD5PZ3l4NDCCei9XkoIAfsXJWmySPoeRb2Ni5UZL4mYpvKDiwmyzGd65KqVw7MsFfI_K767G9C9Azp73gKZD0DyUn1mn0WW5LmyX_yJ-3AROq8p1WZBfG-ZyJ6195_JGG2m9Csg." +
+				"WCCkNa-x4BeB9hIDIfFuhg",
+		},
+	} {
+		plaintext, err := decryptPayload(t, tt.token, mustParseJWK(t, tt.key))
+		if err != nil {
+			t.Fatal(tt.name, err)
+		}
+		if string(plaintext) != cookbookPlaintext {
+			t.Fatal(tt.name, string(plaintext))
+		}
+	}
+}
+
+// https://datatracker.ietf.org/doc/html/rfc7518#appendix-C
+func TestKeyManagementECDH_concatKDF(t *testing.T) {
+	bob := mustParseJWK(t, `{"kty":"EC","crv":"P-256",
+		"x":"weNJy2HscCSM6AEDTDg04biOvhFhyyWvOHQfeF_PxMQ",
+		"y":"e8lnCO-AlStT-NJVX-crhB7QRYhiix03illJOVAOyck",
+		"d":"VEmDZpDXXK8p8N0Cndsxs924q6nS1RXFASRl6BfUqdw"}`)
+	var header map[string]any
+	_ = json.Unmarshal([]byte(`{"alg":"ECDH-ES","enc":"A128GCM","apu":"QWxpY2U","apv":"Qm9i",
+		"epk":{"kty":"EC","crv":"P-256",
+			"x":"gI0GAILBdu7T53akrFmMyGcsF3n5dO7MmwNBHKW5SV0",
+			"y":"SLW_xSffzlPWrHEVI30DHM_4egVwt3NQqeUD7nMFpps"}}`), &header)
+
+	cek, err := jwe.KeyManagementECDHES.UnwrapKey(nil, 16, bob, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base64.RawURLEncoding.EncodeToString(cek) != "VqqN6vgjbSBcIijNcacQGg" {
+		t.Fatal(cek)
+	}
+
+	// 曲線不一致
+	other, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if _, err = jwe.KeyManagementECDHES.UnwrapKey(nil, 16, other, header); !errors.Is(err, jwt.ErrInvalidKey) {
+		t.Fatal(err)
+	}
+
+	// epk不在曲線上
+	header["epk"].(map[string]any)["y"] = "SLW_xSffzlPWrHEVI30DHM_4egVwt3NQqeUD7nMFppo"
+	if _, err = jwe.KeyManagementECDHES.UnwrapKey(nil, 16, bob, header); !errors.Is(err, jwt.ErrTokenMalformed) {
+		t.Fatal(err)
+	}
+}
+
+func TestKeyManagementECDH_roundTrip(t *testing.T) {
+	p := jwe.NewParser(func(v *validator.Validator) {
+		v.ExpectedSubject = "carson"
+		v.RequireAudience = false
+		v.RequireIssuer = false
+	})
+
+	var recipients []*ecdh.PrivateKey
+	for _, curve := range []ecdh.Curve{ecdh.P256(), ecdh.P384(), ecdh.P521(), ecdh.X25519()} {
+		key, _ := curve.GenerateKey(rand.Reader)
+		recipients = append(recipients, key)
+	}
+	ecdsaKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	for _, km := range []jwe.IKeyManagement{
+		jwe.KeyManagementECDHES,
+		jwe.KeyManagementECDHESA128KW,
+		jwe.KeyManagementECDHESA192KW,
+		jwe.KeyManagementECDHESA256KW,
+	} {
+		for i, ce := range []jwe.IContentEncryption{
+			jwe.ContentEncryptionA128GCM,
+			jwe.ContentEncryptionA256GCM,
+			jwe.ContentEncryptionA128CBCHS256,
+			jwe.ContentEncryptionA256CBCHS512,
+		} {
+			// 也可以直接使用 *ecdsa.PrivateKey
+			var encryptKey, decryptKey any = recipients[i].PublicKey(), recipients[i]
+			if i == 0 {
+				encryptKey, decryptKey = &ecdsaKey.PublicKey, ecdsaKey
+			}
+			name := km.AlgName() + "+" + ce.EncName()
+
+			token := jwe.NewWithClaims(km, ce, &jwt.RegisteredClaims{Subject: "carson"})
+			token.Header["apu"] = base64.RawURLEncoding.EncodeToString([]byte("Alice"))
+			token.Header["apv"] = base64.RawURLEncoding.EncodeToString([]byte("Bob"))
+			bsToken, err := token.EncryptedBytes(encryptKey)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if _, exists := token.Header["epk"]; exists {
+				t.Fatal(name, "the token's header should not be modified")
+			}
+
+			vdFunc, err := p.Parse(string(bsToken), getKeyManagement, getContentEncryption)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if err = vdFunc(func(header map[string]any) error {
+				if _, exists := header["epk"]; !exists {
+					return errors.New("epk is missing")
+				}
+				return nil
+			}, nil, func(*jwe.Token) (any, error) {
+				return decryptKey, nil
+			}); err != nil {
+				t.Fatal(name, err)
+			}
+
+			// apv被竄改，衍生出來的鑰匙就不同
+			parts := strings.Split(string(bsToken), ".")
+			bsHeader, _ := base64.RawURLEncoding.DecodeString(parts[0])
+			var header map[string]any
+			_ = json.Unmarshal(bsHeader, &header)
+			header["apv"] = base64.RawURLEncoding.EncodeToString([]byte("Eve"))
+			bsHeader, _ = json.Marshal(header)
+			parts[0] = base64.RawURLEncoding.EncodeToString(bsHeader)
+			vdFunc, _ = p.Parse(strings.Join(parts, "."), getKeyManagement, getContentEncryption)
+			if err = vdFunc(nil, nil, func(*jwe.Token) (any, error) {
+				return decryptKey, nil
+			}); !errors.Is(err, jwe.ErrDecryption) {
+				t.Fatal(name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package jwe
+
+import (
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+)
+
+var (
+	// ErrDecryption 解密失敗
+	// 為了不讓攻擊者藉由錯誤訊息來猜測內容(padding oracle, Bleichenbacher)，不會區分是CEK、tag還是padding錯誤
+	ErrDecryption = errors.New("jwe: decryption failed")
+
+	// ErrInvalidKeySize 鑰匙(或CEK)的長度不符合演算法的要求
+	ErrInvalidKeySize = fmt.Errorf("%w %w",
+		errors.New("jwe: invalid key size"),
+		jwt.ErrInvalidKey,
+	)
+
+	// ErrUnsupportedHeader header之中有不支援的參數，例如: zip, crit
+	ErrUnsupportedHeader = fmt.Errorf("%w %w",
+		errors.New("jwe: unsupported header parameter"),
+		jwt.ErrTokenMalformed,
+	)
+
+	// ErrNotNested 預期為巢狀的JWT，但header的cty不是JWT
+	ErrNotNested = fmt.Errorf("%w %w",
+		errors.New("jwe: content type is not JWT"),
+		jwt.ErrTokenMalformed,
+	)
+)
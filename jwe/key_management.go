@@ -0,0 +1,23 @@
+package jwe
+
+// IKeyManagement 決定CEK(Content Encryption Key)要如何產生以及如何交給對方 (JWE header的alg)
+// https://datatracker.ietf.org/doc/html/rfc7518#section-4.1
+type IKeyManagement interface {
+	// AlgName RSA-OAEP, dir, ...
+	AlgName() string
+
+	// WrapKey 產生CEK，並將其加密成為JWE的第二段(JWE Encrypted Key)
+	WrapKey(
+		cekSize int, // IContentEncryption.CEKSize
+		key any, // 若為非對稱式加密用的是公鑰
+		header map[string]any, // JWE Protected Header，若演算法需要額外的參數(例如: ECDH-ES的epk)可以直接寫入
+	) (cek []byte, encryptedKey []byte, err error)
+
+	// UnwrapKey 從JWE Encrypted Key還原出CEK
+	UnwrapKey(
+		encryptedKey []byte, // parts[1]
+		cekSize int,
+		key any, // 若為非對稱式加密用的是私鑰
+		header map[string]any,
+	) (cek []byte, err error)
+}
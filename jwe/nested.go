@@ -0,0 +1,96 @@
+package jwe
+
+import (
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"strings"
+)
+
+// NestedToken 巢狀的JWT(先加簽再加密): 內層為JWS，外層為JWE，且外層的header cty為JWT
+// https://datatracker.ietf.org/doc/html/rfc7519#section-5.2
+type NestedToken struct {
+	Header map[string]any // 外層JWE的header
+	Inner  *jwt.Token     // 內層要被加簽的token
+
+	KeyManagement     IKeyManagement
+	ContentEncryption IContentEncryption
+}
+
+func NewNested(inner *jwt.Token, keyManagement IKeyManagement, contentEncryption IContentEncryption) *NestedToken {
+	return &NestedToken{
+		Header: map[string]any{
+			"alg": keyManagement.AlgName(),
+			"enc": contentEncryption.EncName(),
+			"cty": "JWT",
+		},
+		Inner:             inner,
+		KeyManagement:     keyManagement,
+		ContentEncryption: contentEncryption,
+	}
+}
+
+// EncryptedBytes 先用signKey將內層加簽，再將整個JWS字串以encryptKey加密
+func (t *NestedToken) EncryptedBytes(signKey any, encryptKey any) ([]byte, error) {
+	jws, err := t.Inner.SignedBytes(signKey)
+	if err != nil {
+		return nil, err
+	}
+	return (&Token{
+		Header:            t.Header,
+		Payload:           jws,
+		KeyManagement:     t.KeyManagement,
+		ContentEncryption: t.ContentEncryption,
+	}).EncryptedBytes(encryptKey)
+}
+
+// isNested cty為JWT時表示內容為另一個JWT，大小寫不拘，也可以帶有application/的前綴
+// https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.10
+func isNested(header map[string]any) bool {
+	cty, _ := header["cty"].(string)
+	cty = strings.ToLower(cty)
+	return cty == "jwt" || cty == "application/jwt"
+}
+
+// ParseNested 解密之後，將內層的JWS交由jwsParser處理
+//
+// vdOuterHeader 會在解密之前執行，可以用來檢查外層的header(例如限制alg, enc，或者要求特定的kid)，
+// 其錯誤會直接回傳，不會進行解密，不需要時可以給nil。cty一定要是JWT，這部分不需要自行檢查
+//
+// 此時解密已經完成，回傳的vdFunc即為jwsParser的vdFunc，
+// 因此標準claims的驗證是依據jwsParser的設定，而不是此Parser
+func (p *Parser) ParseNested(
+	tokenStr string,
+	getKeyManagement func(alg string) (IKeyManagement, error),
+	getContentEncryption func(enc string) (IContentEncryption, error),
+	vdOuterHeader func(header map[string]any) error, // 在解密之前檢查外層的header
+	keyFunc KeyFunc, // 提供解密用的鑰匙
+	jwsParser *parser.Parser,
+	getSigningMethod func(method string) (jwt.ISigningMethod, error),
+	iClaims jwt.IClaims,
+) (
+	vdFunc func(
+		vdHeader func(header map[string]any) error,
+		vdCustomClaims func(jwt.IClaims) error,
+		kf jwt.KeyFunc, // 提供驗證內層簽名的鑰匙
+	) error,
+	err error,
+) {
+	decryptFunc, err := p.ParsePayload(tokenStr, getKeyManagement, getContentEncryption)
+	if err != nil {
+		return nil, err
+	}
+	jws, err := decryptFunc(func(header map[string]any) error {
+		if !isNested(header) {
+			return fmt.Errorf("cty: %v %w", header["cty"], ErrNotNested)
+		}
+		if vdOuterHeader != nil {
+			return vdOuterHeader(header)
+		}
+		return nil
+	}, keyFunc)
+	if err != nil {
+		return nil, err
+	}
+	return jwsParser.ParseWithClaims(string(jws), getSigningMethod, iClaims)
+}
@@ -0,0 +1,155 @@
+package jwe_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/jwe"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"github.com/CarsonSlovoka/jwt/validator"
+	"testing"
+	"time"
+)
+
+func getSigningMethod(method string) (jwt.ISigningMethod, error) {
+	switch method {
+	case jwt.SigningMethodRSA256.Name:
+		return jwt.SigningMethodRSA256, nil
+	case jwt.SigningMethodECDSA256.Name:
+		return jwt.SigningMethodECDSA256, nil
+	}
+	return nil, fmt.Errorf("unsupport method: %q", method)
+}
+
+func TestNestedToken(t *testing.T) {
+	// 發行者用自己的私鑰加簽，再用接收者的公鑰加密
+	issuerKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	recipientKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	newToken := func(exp time.Time) []byte {
+		inner := jwt.NewWithClaims(jwt.SigningMethodECDSA256, &jwt.RegisteredClaims{
+			Issuer:    "https://issuer.example.com",
+			Subject:   "carson",
+			Audience:  jwt.ClaimStrings{"https://api.example.com"},
+			ExpiresAt: jwt.NewNumericDate(exp),
+		})
+		bsToken, err := jwe.NewNested(inner, jwe.KeyManagementRSAOAEP256, jwe.ContentEncryptionA256GCM).
+			EncryptedBytes(issuerKey, &recipientKey.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return bsToken
+	}
+
+	jweParser := jwe.NewParser()
+	jwsParser := parser.New(func(v *validator.Validator) {
+		v.ExpectedIssuer = "https://issuer.example.com"
+		v.ExpectedAudience = "https://api.example.com"
+		v.RequireExpirationTime = true
+	})
+	decryptKeyFunc := func(*jwe.Token) (any, error) {
+		return recipientKey, nil
+	}
+	verify := func(bsToken []byte, verifyKey any) error {
+		claims := &jwt.RegisteredClaims{}
+		vdFunc, err := jweParser.ParseNested(string(bsToken), getKeyManagement, getContentEncryption, nil, decryptKeyFunc,
+			jwsParser, getSigningMethod, claims)
+		if err != nil {
+			return err
+		}
+		if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+			return verifyKey, nil
+		}); err != nil {
+			return err
+		}
+		if claims.Subject != "carson" {
+			t.Fatal(claims)
+		}
+		return nil
+	}
+
+	bsToken := newToken(time.Now().Add(time.Hour))
+	if err := verify(bsToken, &issuerKey.PublicKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// 內層的簽名是由其他私鑰所簽
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err := verify(bsToken, &otherKey.PublicKey); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Fatal(err)
+	}
+
+	// 內層的claims會經過validator的驗證
+	if err := verify(newToken(time.Now().Add(-time.Hour)), &issuerKey.PublicKey); !errors.Is(err, jwt.ErrTokenExpired) {
+		t.Fatal(err)
+	}
+
+	// 巢狀的JWT不可以被當成一般的claims解析，否則內層的簽名就不會被驗證
+	vdFunc, err := jweParser.Parse(string(bsToken), getKeyManagement, getContentEncryption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, decryptKeyFunc); !errors.Is(err, jwt.ErrTokenMalformed) {
+		t.Fatal(err)
+	}
+
+	// 沒有cty的JWE不是巢狀的JWT
+	bsToken, _ = jwe.New(jwe.KeyManagementRSAOAEP256, jwe.ContentEncryptionA256GCM).EncryptedBytes(&recipientKey.PublicKey)
+	if err = verify(bsToken, &issuerKey.PublicKey); !errors.Is(err, jwe.ErrNotNested) {
+		t.Fatal(err)
+	}
+}
+
+// 外層的header在解密之前就可以被檢查，不允許的alg不會進行解密
+func TestParseNested_outerHeader(t *testing.T) {
+	issuerKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	recipientKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	inner := jwt.NewWithClaims(jwt.SigningMethodECDSA256, &jwt.RegisteredClaims{Subject: "carson"})
+	bsToken, err := jwe.NewNested(inner, jwe.KeyManagementRSAOAEP256, jwe.ContentEncryptionA256GCM).
+		EncryptedBytes(issuerKey, &recipientKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jweParser := jwe.NewParser()
+	jwsParser := parser.New()
+	errAlgNotAllowed := errors.New("alg not allowed")
+	decrypted := false
+	decryptKeyFunc := func(*jwe.Token) (any, error) {
+		decrypted = true
+		return recipientKey, nil
+	}
+	parse := func(allowedAlg string) error {
+		vdFunc, err := jweParser.ParseNested(string(bsToken), getKeyManagement, getContentEncryption,
+			func(header map[string]any) error {
+				if header["alg"] != allowedAlg || header["enc"] != "A256GCM" || header["cty"] != "JWT" {
+					return fmt.Errorf("%v %w", header, errAlgNotAllowed)
+				}
+				return nil
+			}, decryptKeyFunc,
+			jwsParser, getSigningMethod, &jwt.RegisteredClaims{})
+		if err != nil {
+			return err
+		}
+		return vdFunc(nil, nil, func(*jwt.Token) (any, error) {
+			return &issuerKey.PublicKey, nil
+		})
+	}
+
+	if err = parse("RSA-OAEP"); !errors.Is(err, errAlgNotAllowed) {
+		t.Fatal(err)
+	}
+	if decrypted {
+		t.Fatal("the outer header must be checked before decryption")
+	}
+	if err = parse("RSA-OAEP-256"); err != nil {
+		t.Fatal(err)
+	}
+	if !decrypted {
+		t.Fatal("keyFunc should be called")
+	}
+}
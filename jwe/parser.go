@@ -0,0 +1,235 @@
+package jwe
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/validator"
+	"strings"
+)
+
+// KeyFunc 提供解密所需的鑰匙，若為非對稱式加密則為私鑰
+// 此時內容尚未解密，所以只能依據token.Header(alg, enc, kid...)來決定
+type KeyFunc func(*Token) (any, error)
+
+// Parser 與 parser.Parser 的用法相同，差別在於JWE需要先解密之後才能驗證claims
+type Parser struct {
+	validator *validator.Validator
+}
+
+// NewParser 預設只對Audience, Issuer, Subject做驗證
+func NewParser(options ...validator.Option) *Parser {
+	p := &Parser{
+		validator: &validator.Validator{
+			RequireAudience: true,
+			RequireIssuer:   true,
+			RequireSubject:  true,
+		},
+	}
+
+	for _, option := range options {
+		option(p.validator)
+	}
+	return p
+}
+
+// encrypted compact serialization的各段內容(已經URLDecode)
+type encrypted struct {
+	aad          []byte // parts[0]，保持原本的編碼
+	encryptedKey []byte
+	iv           []byte
+	ciphertext   []byte
+	tag          []byte
+}
+
+// Parse 細節請參考 ParseWithClaims
+func (p *Parser) Parse(
+	tokenStr string,
+	getKeyManagement func(alg string) (IKeyManagement, error),
+	getContentEncryption func(enc string) (IContentEncryption, error),
+) (
+	vdFunc func(
+		vdHeader func(header map[string]any) error,
+		vdCustomClaims func(jwt.IClaims) error,
+		kf KeyFunc,
+	) error,
+	err error,
+) {
+	return p.ParseWithClaims(tokenStr, getKeyManagement, getContentEncryption, nil)
+}
+
+// ParseWithClaims 其完成時，只是將header解析出來，內容需要等到vdFunc時才會解密
+//
+// vdFunc的順序為: vdHeader -> kf -> 解密 -> 標準claims的驗證 -> vdCustomClaims
+func (p *Parser) ParseWithClaims(
+	tokenStr string,
+	getKeyManagement func(alg string) (IKeyManagement, error), // 自定義您server所允許的alg
+	getContentEncryption func(enc string) (IContentEncryption, error), // 自定義您server所允許的enc
+	iClaims jwt.IClaims, // 解密之後的內容保存在此，如果給nil，預設使用 jwt.MapClaims
+) (
+	vdFunc func(
+		vdHeader func(header map[string]any) error,
+		vdCustomClaims func(jwt.IClaims) error,
+		kf KeyFunc,
+	) error,
+	err error,
+) {
+	token, enc, err := p.parse(tokenStr, getKeyManagement, getContentEncryption)
+	if err != nil {
+		return nil, err
+	}
+	if iClaims == nil {
+		iClaims = &jwt.MapClaims{}
+	}
+	token.Claims = iClaims
+
+	return func(
+		validateHeader func(map[string]any) error,
+		validateCustomClaims func(jwt.IClaims) error,
+		keyFunc KeyFunc,
+	) error {
+		return p.validate(token, enc, validateHeader, validateCustomClaims, keyFunc)
+	}, nil
+}
+
+// ParsePayload 不把內容當作claims，只解密出原始的內容，適用於payload不是JSON的情況
+func (p *Parser) ParsePayload(
+	tokenStr string,
+	getKeyManagement func(alg string) (IKeyManagement, error),
+	getContentEncryption func(enc string) (IContentEncryption, error),
+) (
+	decryptFunc func(
+		vdHeader func(header map[string]any) error,
+		kf KeyFunc,
+	) ([]byte, error),
+	err error,
+) {
+	token, enc, err := p.parse(tokenStr, getKeyManagement, getContentEncryption)
+	if err != nil {
+		return nil, err
+	}
+	return func(validateHeader func(map[string]any) error, keyFunc KeyFunc) ([]byte, error) {
+		if keyFunc == nil {
+			return nil, fmt.Errorf("error keyFunc is nil. %w", jwt.ErrInvalidKeyType)
+		}
+		if validateHeader != nil {
+			if err := validateHeader(token.Header); err != nil {
+				return nil, err
+			}
+		}
+		return p.decrypt(token, enc, keyFunc)
+	}, nil
+}
+
+func (p *Parser) parse(
+	tokenStr string,
+	getKeyManagement func(alg string) (IKeyManagement, error),
+	getContentEncryption func(enc string) (IContentEncryption, error),
+) (*Token, *encrypted, error) {
+	parts := strings.Split(tokenStr, ".")
+	if len(parts) != 5 {
+		return nil, nil, fmt.Errorf("token contains an invalid number of segments: %d, %w", len(parts), jwt.ErrTokenMalformed)
+	}
+
+	header, err := p.parseHeader(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	token := &Token{Header: header}
+	if token.KeyManagement, err = getKeyManagement(header["alg"].(string)); err != nil {
+		return nil, nil, err
+	}
+	if token.ContentEncryption, err = getContentEncryption(header["enc"].(string)); err != nil {
+		return nil, nil, err
+	}
+
+	enc := &encrypted{aad: []byte(parts[0])}
+	for i, seg := range []*[]byte{&enc.encryptedKey, &enc.iv, &enc.ciphertext, &enc.tag} {
+		if *seg, err = base64.RawURLEncoding.DecodeString(parts[i+1]); err != nil {
+			return nil, nil, fmt.Errorf("could not base64 decode part %d. %w %w", i+1, err, jwt.ErrTokenMalformed)
+		}
+	}
+	return token, enc, nil
+}
+
+func (p *Parser) parseHeader(headerStr string) (map[string]any, error) {
+	bs, err := base64.RawURLEncoding.DecodeString(headerStr)
+	if err != nil {
+		return nil, fmt.Errorf("could not base64 decode header. %w %w", err, jwt.ErrTokenMalformed)
+	}
+	var header map[string]any
+	if err = json.Unmarshal(bs, &header); err != nil {
+		return nil, fmt.Errorf("failed to parse header: %w %w", err, jwt.ErrTokenMalformed)
+	}
+	for _, name := range []string{"alg", "enc"} {
+		if _, ok := header[name].(string); !ok {
+			return nil, fmt.Errorf("token %s not found or not string %w", name, jwt.ErrTokenMalformed)
+		}
+	}
+	// 不認識的crit以及壓縮都必須拒絕: https://datatracker.ietf.org/doc/html/rfc7516#section-4.1.3
+	for _, name := range []string{"zip", "crit"} {
+		if _, exists := header[name]; exists {
+			return nil, fmt.Errorf("%s is not supported. %w", name, ErrUnsupportedHeader)
+		}
+	}
+	return header, nil
+}
+
+// decrypt 取得鑰匙並解密出原始的內容
+func (p *Parser) decrypt(token *Token, enc *encrypted, keyFunc KeyFunc) ([]byte, error) {
+	key, err := keyFunc(token)
+	if err != nil {
+		return nil, fmt.Errorf("error while executing keyfunc. %w %w", err, jwt.ErrTokenKeyFuncUnknown)
+	}
+
+	cek, err := token.KeyManagement.UnwrapKey(enc.encryptedKey, token.ContentEncryption.CEKSize(), key, token.Header)
+	if err != nil {
+		return nil, err
+	}
+	return token.ContentEncryption.Decrypt(cek, enc.iv, enc.ciphertext, enc.tag, enc.aad)
+}
+
+func (p *Parser) validate(
+	token *Token,
+	enc *encrypted,
+	validateHeader func(map[string]any) error,
+	customValidate func(jwt.IClaims) error,
+	keyFunc KeyFunc,
+) error {
+	if keyFunc == nil {
+		return fmt.Errorf("error keyFunc is nil. %w", jwt.ErrInvalidKeyType)
+	}
+
+	if validateHeader != nil {
+		if err := validateHeader(token.Header); err != nil {
+			return err
+		}
+	}
+
+	// 內容為JWT時還需要驗證內層的簽名，不可以直接當成claims
+	if isNested(token.Header) {
+		return fmt.Errorf("nested JWT must be parsed with ParseNested. %w", jwt.ErrTokenMalformed)
+	}
+
+	plaintext, err := p.decrypt(token, enc, keyFunc)
+	if err != nil {
+		return err
+	}
+	token.Payload = plaintext
+
+	if err = json.Unmarshal(plaintext, token.Claims); err != nil {
+		return fmt.Errorf("could not parse the decrypted claims %w. %w", err, jwt.ErrTokenMalformed)
+	}
+
+	if err = p.validator.Validate(token.Claims); err != nil {
+		return err
+	}
+
+	if customValidate != nil {
+		if err = customValidate(token.Claims); err != nil {
+			return err
+		}
+	}
+	return nil
+}
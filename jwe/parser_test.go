@@ -0,0 +1,234 @@
+package jwe_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/jwe"
+	"github.com/CarsonSlovoka/jwt/jwk"
+	"github.com/CarsonSlovoka/jwt/validator"
+	"strings"
+	"testing"
+)
+
+func getKeyManagement(alg string) (jwe.IKeyManagement, error) {
+	for _, m := range []jwe.IKeyManagement{
+		jwe.KeyManagementRSAOAEP,
+		jwe.KeyManagementRSAOAEP256,
+		jwe.KeyManagementDirect,
+		jwe.KeyManagementA128KW,
+		jwe.KeyManagementA192KW,
+		jwe.KeyManagementA256KW,
+		jwe.KeyManagementECDHES,
+		jwe.KeyManagementECDHESA128KW,
+		jwe.KeyManagementECDHESA192KW,
+		jwe.KeyManagementECDHESA256KW,
+	} {
+		if m.AlgName() == alg {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupport alg: %q", alg)
+}
+
+func getContentEncryption(enc string) (jwe.IContentEncryption, error) {
+	for _, m := range []jwe.IContentEncryption{
+		jwe.ContentEncryptionA128GCM,
+		jwe.ContentEncryptionA192GCM,
+		jwe.ContentEncryptionA256GCM,
+		jwe.ContentEncryptionA128CBCHS256,
+		jwe.ContentEncryptionA192CBCHS384,
+		jwe.ContentEncryptionA256CBCHS512,
+	} {
+		if m.EncName() == enc {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupport enc: %q", enc)
+}
+
+// https://datatracker.ietf.org/doc/html/rfc7516#appendix-A.1
+func TestParser_ParsePayload_rfc7516(t *testing.T) {
+	const token = "eyJhbGciOiJSU0EtT0FFUCIsImVuYyI6IkEyNTZHQ00ifQ." +
+		"OKOawDo13gRp2ojaHV7LFpZcgV7T6DVZKTyKOMTYUmKoTCVJRgckCL9kiMT03JGeipsEdY3mx_etLbbWSrFr05kLzcSr4qKAq7YN7e9jwQRb23nfa6c9d-StnImGyFDbSv04uVuxIp5Zms1gNxKKK2Da14B8S4rzVRltdYwam_lDp5XnZAYpQdb76FdIKLaVmqgfwX7XWRxv2322i-vDxRfqNzo_tETKzpVLzfiwQyeyPGLBIO56YJ7eObdv0je81860ppamavo35UgoRdbYaBcoh9QcfylQr66oc6vFWXRcZ_ZT2LawVCWTIy3brGPi6UklfCpIMfIjf7iGdXKHzg." +
+		"48V1_ALb6US04U3b." +
+		"5eym8TW_c8SuK0ltJ3rpYIzOeDQz7TALvtu6UG9oMo4vpzs9tX_EFShS8iB7j6jiSdiwkIr3ajwQzaBtQD_A." +
+		"XFBoMYUZodetZdvTiFvSkQ"
+	key, err := jwk.Parse([]byte(`{"kty":"RSA",
+      "n":"oahUIoWw0K0usKNuOR6H4wkf4oBUXHTxRvgb48E-BVvxkeDNjbC4he8rUWcJoZmds2h7M70imEVhRU5djINXtqllXI4DFqcI1DgjT9LewND8MW2Krf3Spsk_ZkoFnilakGygTwpZ3uesH-PFABNIUYpOiN15dsQRkgr0vEhxN92i2asbOenSZeyaxziK72UwxrrKoExv6kc5twXTq4h-QChLOln0_mtUZwfsRaMStPs6mS6XrgxnxbWhojf663tuEQueGC-FCMfra36C9knDFGzKsNa7LZK2djYgyD3JR_MB_4NUJW_TqOQtwHYbxevoJArm-L5StowjzGy-_bq6Gw",
+      "e":"AQAB",
+      "d":"kLdtIj6GbDks_ApCSTYQtelcNttlKiOyPzMrXHeI-yk1F7-kpDxY4-WY5NWV5KntaEeXS1j82E375xxhWMHXyvjYecPT9fpwR_M9gV8n9Hrh2anTpTD93Dt62ypW3yDsJzBnTnrYu1iwWRgBKrEYY46qAZIrA2xAwnm2X7uGR1hghkqDp0Vqj3kbSCz1XyfCs6_LehBwtxHIyh8Ripy40p24moOAbgxVw3rxT_vlt3UVe4WO3JkJOzlpUf-KTVI2Ptgm-dARxTEtE-id-4OJr0h-K-VFs3VSndVTIznSxfyrj8ILL6MG_Uv8YAu7VILSB3lOW085-4qE3DzgrTjgyQ",
+      "p":"1r52Xk46c-LsfB5P442p7atdPUrxQSy4mti_tZI3Mgf2EuFVbUoDBvaRQ-SWxkbkmoEzL7JXroSBjSrK3YIQgYdMgyAEPTPjXv_hI2_1eTSPVZfzL0lffNn03IXqWF5MDFuoUYE0hzb2vhrlN_rKrbfDIwUbTrjjgieRbwC6Cl0",
+      "q":"wLb35x7hmQWZsWJmB_vle87ihgZ19S8lBEROLIsZG4ayZVe9Hi9gDVCOBmUDdaDYVTSNx_8Fyw1YYa9XGrGnDew00J28cRUoeBB_jKI1oma0Orv1T9aXIWxKwd4gvxFImOWr3QRL9KEBRzk2RatUBnmDZJTIAfwTs0g68UZHvtc",
+      "dp":"ZK-YwE7diUh0qR1tR7w8WHtolDx3MZ_OTowiFvgfeQ3SiresXjm9gZ5KLhMXvo-uz-KUJWDxS5pFQ_M0evdo1dKiRTjVw_x4NyqyXPM5nULPkcpU827rnpZzAJKpdhWAgqrXGKAECQH0Xt4taznjnd_zVpAmZZq60WPMBMfKcuE",
+      "dq":"Dq0gfgJ1DdFGXiLvQEZnuKEN0UUmsJBxkjydc3j4ZYdBiMRAy86x0vHCjywcMlYYg4yoC4YZa9hNVcsjqA3FeiL19rk8g6Qn29Tt0cj8qqyFpz9vNDBUfCAiJVeESOjJDZPYHdHY8v1b-o-Z2X5tvLx-TCekf7oxyeKDUqKWjis",
+      "qi":"VIMpMYbPf47dT1w_zDUXfPimsSegnMOA1zTaX7aGk_8urY6R8-ZW1FxU7AlWAyLWybqq6t16VFd7hQd0y6flUK4SlOydB61gwanOsXGOAOv82cHq0E3eL4HrtZkUuKvnPrMnsUUFlfUdybVzxyjz9JF_XyaY14ardLSjf4L_FNY"
+     }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decryptFunc, err := jwe.NewParser().ParsePayload(token, getKeyManagement, getContentEncryption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := decryptFunc(nil, func(token *jwe.Token) (any, error) {
+		return key.Key, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "The true sign of intelligence is not knowledge but imagination." {
+		t.Fatal(string(plaintext))
+	}
+
+	// 使用其他的私鑰，錯誤訊息不可以透露是CEK錯誤
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, err = decryptFunc(nil, func(token *jwe.Token) (any, error) {
+		return otherKey, nil
+	})
+	if !errors.Is(err, jwe.ErrDecryption) {
+		t.Fatal(err)
+	}
+}
+
+func TestToken_EncryptedBytes(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	claims := &jwt.RegisteredClaims{
+		Issuer:   "https://issuer.example.com",
+		Subject:  "carson",
+		Audience: jwt.ClaimStrings{"https://api.example.com"},
+	}
+
+	p := jwe.NewParser(func(v *validator.Validator) {
+		v.ExpectedIssuer = "https://issuer.example.com"
+		v.ExpectedSubject = "carson"
+		v.ExpectedAudience = "https://api.example.com"
+	})
+
+	for _, km := range []jwe.IKeyManagement{
+		jwe.KeyManagementRSAOAEP,
+		jwe.KeyManagementRSAOAEP256,
+		jwe.KeyManagementDirect,
+		jwe.KeyManagementA128KW,
+		jwe.KeyManagementA256KW,
+	} {
+		for _, ce := range []jwe.IContentEncryption{
+			jwe.ContentEncryptionA128GCM,
+			jwe.ContentEncryptionA192GCM,
+			jwe.ContentEncryptionA256GCM,
+			jwe.ContentEncryptionA128CBCHS256,
+			jwe.ContentEncryptionA192CBCHS384,
+			jwe.ContentEncryptionA256CBCHS512,
+		} {
+			name := km.AlgName() + "+" + ce.EncName()
+
+			var encryptKey, decryptKey any = &rsaKey.PublicKey, rsaKey
+			if km == jwe.KeyManagementDirect {
+				sharedKey := make([]byte, ce.CEKSize())
+				_, _ = rand.Read(sharedKey)
+				encryptKey, decryptKey = sharedKey, sharedKey
+			}
+			if kw, ok := km.(*jwe.KeyManagementAESKW); ok {
+				kek := make([]byte, kw.KeySize)
+				_, _ = rand.Read(kek)
+				encryptKey, decryptKey = kek, kek
+			}
+
+			bsToken, err := jwe.NewWithClaims(km, ce, claims).EncryptedBytes(encryptKey)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if strings.Contains(string(bsToken), "carson") {
+				t.Fatal(name, "the claims must not be readable")
+			}
+
+			outClaims := &jwt.RegisteredClaims{}
+			vdFunc, err := p.ParseWithClaims(string(bsToken), getKeyManagement, getContentEncryption, outClaims)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if err = vdFunc(nil, nil, func(token *jwe.Token) (any, error) {
+				return decryptKey, nil
+			}); err != nil {
+				t.Fatal(name, err)
+			}
+			if outClaims.Subject != "carson" {
+				t.Fatal(name, outClaims)
+			}
+
+			// 竄改密文
+			parts := strings.Split(string(bsToken), ".")
+			parts[3] = strings.Repeat("A", len(parts[3]))
+			vdFunc, err = p.ParseWithClaims(strings.Join(parts, "."), getKeyManagement, getContentEncryption, nil)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if err = vdFunc(nil, nil, func(token *jwe.Token) (any, error) {
+				return decryptKey, nil
+			}); !errors.Is(err, jwe.ErrDecryption) {
+				t.Fatal(name, err)
+			}
+		}
+	}
+}
+
+func TestParser_ParseWithClaims_invalid(t *testing.T) {
+	sharedKey := make([]byte, 16)
+	token := jwe.New(jwe.KeyManagementDirect, jwe.ContentEncryptionA128GCM)
+	bsToken, err := token.EncryptedBytes(sharedKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := jwe.NewParser(func(v *validator.Validator) {
+		v.RequireAudience = false
+		v.RequireIssuer = false
+		v.RequireSubject = false
+	})
+
+	// 只允許RSA-OAEP-256
+	if _, err = p.Parse(string(bsToken), func(alg string) (jwe.IKeyManagement, error) {
+		if alg != jwe.KeyManagementRSAOAEP256.AlgName() {
+			return nil, fmt.Errorf("unsupport alg: %q", alg)
+		}
+		return jwe.KeyManagementRSAOAEP256, nil
+	}, getContentEncryption); err == nil {
+		t.Fatal("must fatal")
+	}
+
+	// 鑰匙長度錯誤
+	vdFunc, err := p.Parse(string(bsToken), getKeyManagement, getContentEncryption)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, func(*jwe.Token) (any, error) {
+		return make([]byte, 32), nil
+	}); !errors.Is(err, jwe.ErrInvalidKeySize) || !errors.Is(err, jwt.ErrInvalidKey) {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, func(*jwe.Token) (any, error) {
+		return sharedKey, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 不支援壓縮
+	token.Header["zip"] = "DEF"
+	if _, err = token.EncryptedBytes(sharedKey); !errors.Is(err, jwe.ErrUnsupportedHeader) {
+		t.Fatal(err)
+	}
+
+	// 段數錯誤
+	if _, err = p.Parse("a.b.c", getKeyManagement, getContentEncryption); !errors.Is(err, jwt.ErrTokenMalformed) {
+		t.Fatal(err)
+	}
+
+	// RSA-OAEP必須使用2048以上的鑰匙
+	smallKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	if _, err = jwe.New(jwe.KeyManagementRSAOAEP, jwe.ContentEncryptionA128GCM).
+		EncryptedBytes(&smallKey.PublicKey); !errors.Is(err, jwe.ErrInvalidKeySize) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,79 @@
+package jwe
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+)
+
+// minRSAKeySize A key of size 2048 bits or larger MUST be used with these algorithms.
+// https://datatracker.ietf.org/doc/html/rfc7518#section-4.3
+const minRSAKeySize = 2048
+
+// KeyManagementRSA RSAES OAEP https://datatracker.ietf.org/doc/html/rfc7518#section-4.3
+type KeyManagementRSA struct {
+	Name string
+	Hash crypto.Hash // OAEP與MGF1所用的hash
+}
+
+var (
+	KeyManagementRSAOAEP    *KeyManagementRSA
+	KeyManagementRSAOAEP256 *KeyManagementRSA
+)
+
+func init() {
+	KeyManagementRSAOAEP = &KeyManagementRSA{"RSA-OAEP", crypto.SHA1}
+	KeyManagementRSAOAEP256 = &KeyManagementRSA{"RSA-OAEP-256", crypto.SHA256}
+}
+
+func (m *KeyManagementRSA) AlgName() string {
+	return m.Name
+}
+
+func (m *KeyManagementRSA) WrapKey(cekSize int, key any, _ map[string]any) ([]byte, []byte, error) {
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s wrap key expects *rsa.PublicKey. %w", m.Name, jwt.ErrInvalidKeyType)
+	}
+	if publicKey.N == nil || publicKey.N.BitLen() < minRSAKeySize {
+		return nil, nil, fmt.Errorf("%s requires a key of at least %d bits. %w", m.Name, minRSAKeySize, ErrInvalidKeySize)
+	}
+	if !m.Hash.Available() {
+		return nil, nil, jwt.ErrHashUnavailable
+	}
+
+	cek, err := randomBytes(cekSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptedKey, err := rsa.EncryptOAEP(m.Hash.New(), rand.Reader, publicKey, cek, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cek, encryptedKey, nil
+}
+
+func (m *KeyManagementRSA) UnwrapKey(encryptedKey []byte, cekSize int, key any, _ map[string]any) ([]byte, error) {
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s unwrap key expects *rsa.PrivateKey. %w", m.Name, jwt.ErrInvalidKeyType)
+	}
+	if privateKey.N == nil || privateKey.N.BitLen() < minRSAKeySize {
+		return nil, fmt.Errorf("%s requires a key of at least %d bits. %w", m.Name, minRSAKeySize, ErrInvalidKeySize)
+	}
+	if !m.Hash.Available() {
+		return nil, jwt.ErrHashUnavailable
+	}
+
+	cek, err := rsa.DecryptOAEP(m.Hash.New(), nil, privateKey, encryptedKey, nil)
+	if err != nil || len(cek) != cekSize {
+		// 不直接回傳錯誤，而是以隨機的CEK繼續，讓錯誤只會在內容解密時發生，避免攻擊者能區分是哪一個步驟失敗
+		// https://datatracker.ietf.org/doc/html/rfc7516#section-11.5
+		return randomBytes(cekSize)
+	}
+	return cek, nil
+}
@@ -0,0 +1,87 @@
+package jwe
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"maps"
+)
+
+// Token JWE(加密的token) https://datatracker.ietf.org/doc/html/rfc7516
+//
+// jwt.Token 只有加簽，任何人都能看到claims的內容，若claims有個資，則應該使用此Token將其加密
+type Token struct {
+	Header map[string]any
+	Claims jwt.IClaims
+
+	// Payload 若不為nil則加密此內容，而不使用Claims
+	Payload []byte
+
+	KeyManagement     IKeyManagement
+	ContentEncryption IContentEncryption
+}
+
+func New(keyManagement IKeyManagement, contentEncryption IContentEncryption) *Token {
+	return NewWithClaims(keyManagement, contentEncryption, &jwt.MapClaims{})
+}
+
+func NewWithClaims(keyManagement IKeyManagement, contentEncryption IContentEncryption, claims jwt.IClaims) *Token {
+	return &Token{
+		Header: map[string]any{
+			"typ": "JWT",
+			"alg": keyManagement.AlgName(),
+			"enc": contentEncryption.EncName(),
+		},
+		Claims:            claims,
+		KeyManagement:     keyManagement,
+		ContentEncryption: contentEncryption,
+	}
+}
+
+// Plaintext 取得要被加密的內容
+func (t *Token) Plaintext() ([]byte, error) {
+	if t.Payload != nil {
+		return t.Payload, nil
+	}
+	return json.Marshal(t.Claims)
+}
+
+// EncryptedBytes 取得完整的JWE字串內容(compact serialization)
+//
+//	BASE64URL(header) . BASE64URL(encryptedKey) . BASE64URL(iv) . BASE64URL(ciphertext) . BASE64URL(tag)
+//
+// key 為KeyManagement所需的鑰匙，若為非對稱式則用的是對方的公鑰
+func (t *Token) EncryptedBytes(key any) ([]byte, error) {
+	if _, exists := t.Header["zip"]; exists {
+		return nil, fmt.Errorf("zip is not supported. %w", ErrUnsupportedHeader)
+	}
+	plaintext, err := t.Plaintext()
+	if err != nil {
+		return nil, err
+	}
+
+	// 複製一份，讓KeyManagement可以寫入參數(例如: epk)而不影響t.Header，如此同一個Token可以重複加密
+	header := maps.Clone(t.Header)
+	cek, encryptedKey, err := t.KeyManagement.WrapKey(t.ContentEncryption.CEKSize(), key, header)
+	if err != nil {
+		return nil, err
+	}
+
+	bsHeader, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	aad := base64.RawURLEncoding.AppendEncode(nil, bsHeader)
+	iv, ciphertext, tag, err := t.ContentEncryption.Encrypt(cek, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := [][]byte{aad}
+	for _, seg := range [][]byte{encryptedKey, iv, ciphertext, tag} {
+		parts = append(parts, base64.RawURLEncoding.AppendEncode(nil, seg))
+	}
+	return bytes.Join(parts, []byte{'.'}), nil
+}
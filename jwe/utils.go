@@ -0,0 +1,14 @@
+package jwe
+
+import (
+	"crypto/rand"
+	"io"
+)
+
+func randomBytes(n int) ([]byte, error) {
+	bs := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, bs); err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
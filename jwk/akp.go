@@ -0,0 +1,99 @@
+//go:build go1.27
+
+package jwk
+
+import (
+	"crypto/mldsa"
+	"fmt"
+)
+
+// akpParameters alg與ML-DSA參數集的對應
+var akpParameters = map[string]mldsa.Parameters{
+	"ML-DSA-44": mldsa.MLDSA44(),
+	"ML-DSA-65": mldsa.MLDSA65(),
+	"ML-DSA-87": mldsa.MLDSA87(),
+}
+
+// akpKey AKP的alg為必要欄位，pub為公鑰，priv為私鑰的seed(32 bytes)
+func (raw *rawKey) akpKey() (any, error) {
+	if raw.Alg == "" {
+		return nil, errMember("alg", "is missing")
+	}
+	params, ok := akpParameters[raw.Alg]
+	if !ok {
+		return nil, fmt.Errorf("alg: %q %w", raw.Alg, ErrUnsupportedKeyType)
+	}
+
+	pub, err := decodeFixedMember("pub", raw.Pub, params.PublicKeySize())
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := mldsa.NewPublicKey(params, pub)
+	if err != nil {
+		return nil, errMember("pub", err.Error())
+	}
+	if raw.Priv == "" {
+		return publicKey, nil
+	}
+
+	seed, err := decodeFixedMember("priv", raw.Priv, mldsa.PrivateKeySize)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := mldsa.NewPrivateKey(params, seed)
+	if err != nil {
+		return nil, errMember("priv", err.Error())
+	}
+	if !privateKey.PublicKey().Equal(publicKey) {
+		return nil, errMember("priv", "does not match the public key")
+	}
+	return privateKey, nil
+}
+
+func marshalAKP(key any) (raw *rawKey, ok bool, err error) {
+	var (
+		publicKey  *mldsa.PublicKey
+		privateKey *mldsa.PrivateKey
+	)
+	switch k := key.(type) {
+	case *mldsa.PublicKey:
+		publicKey = k
+	case *mldsa.PrivateKey:
+		privateKey = k
+		publicKey = k.PublicKey()
+	default:
+		return nil, false, nil
+	}
+	if publicKey == nil {
+		return nil, true, fmt.Errorf("ML-DSA public key is empty. %w", ErrInvalidMember)
+	}
+
+	raw = &rawKey{
+		Kty: KeyTypeAKP,
+		Alg: publicKey.Parameters().String(),
+		Pub: encodeMember(publicKey.Bytes()),
+	}
+	if privateKey != nil {
+		raw.Priv = encodeMember(privateKey.Bytes())
+	}
+	return raw, true, nil
+}
+
+func publicAKP(key any) (any, bool) {
+	switch k := key.(type) {
+	case *mldsa.PublicKey:
+		return k, true
+	case *mldsa.PrivateKey:
+		return k.PublicKey(), true
+	}
+	return nil, false
+}
+
+// akpKeyAlgorithm 取得key所屬的alg，若非AKP的key則回傳空字串
+func akpKeyAlgorithm(key any) string {
+	pub, ok := publicAKP(key)
+	if !ok {
+		return ""
+	}
+	return pub.(*mldsa.PublicKey).Parameters().String()
+}
@@ -0,0 +1,22 @@
+//go:build !go1.27
+
+package jwk
+
+import "fmt"
+
+// akpKey Go 1.27以前沒有crypto/mldsa，AKP一律視為不支援
+func (raw *rawKey) akpKey() (any, error) {
+	return nil, fmt.Errorf("alg: %q requires Go 1.27 or later. %w", raw.Alg, ErrUnsupportedKeyType)
+}
+
+func marshalAKP(any) (*rawKey, bool, error) {
+	return nil, false, nil
+}
+
+func publicAKP(any) (any, bool) {
+	return nil, false
+}
+
+func akpKeyAlgorithm(any) string {
+	return ""
+}
@@ -0,0 +1,84 @@
+//go:build go1.27
+
+package jwk_test
+
+import (
+	"crypto/mldsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/jwk"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"testing"
+)
+
+func TestParse_akp(t *testing.T) {
+	seed, _ := hex.DecodeString("93EF2E6EF1FB08999D142ABE0295482370D3F43BDB254A78E2B0D5168ECA065F")
+	privateKey, _ := mldsa.NewPrivateKey(mldsa.MLDSA44(), seed)
+	key, err := jwk.New(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.KeyType != jwk.KeyTypeAKP {
+		t.Fatal(key.KeyType)
+	}
+	bs, _ := json.Marshal(key)
+	var m map[string]string
+	_ = json.Unmarshal(bs, &m)
+	if m["alg"] != "ML-DSA-44" || m["priv"] != "k-8ubvH7CJmdFCq-ApVII3DT9DvbJUp44rDVFo7KBl8" {
+		t.Fatal(string(bs))
+	}
+
+	priv, err := jwk.Parse(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !privateKey.Equal(priv.Key) || priv.Algorithm != "ML-DSA-44" {
+		t.Fatal(priv)
+	}
+	pub, _ := priv.Public()
+	bs, _ = json.Marshal(pub)
+	m = nil
+	_ = json.Unmarshal(bs, &m)
+	if _, ok := m["priv"]; ok {
+		t.Fatal(string(bs))
+	}
+
+	// alg由key決定，ML-DSA-44的key只能驗證ML-DSA-44的token
+	set := &jwk.KeySet{Keys: []*jwk.Key{pub}}
+	bsToken, _ := jwt.New(jwt.SigningMethodMLDSA44).SignedBytes(privateKey)
+	vdFunc, err := parser.New().Parse(string(bsToken), func(method string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodMLDSA44, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, set.KeyFunc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = set.KeyFunc(&jwt.Token{SigningMethod: jwt.SigningMethodMLDSA65}); !errors.Is(err, jwk.ErrNoCompatibleKey) {
+		t.Fatal(err)
+	}
+
+	// 不可以把AKP的key標示成其他的演算法
+	pub.Algorithm = "ML-DSA-65"
+	if _, err = json.Marshal(pub); !errors.Is(err, jwk.ErrInvalidMember) {
+		t.Fatal(err)
+	}
+
+	for i, src := range []string{
+		`{"kty":"AKP","pub":"AA"}`,
+		`{"kty":"AKP","alg":"ML-DSA-44","pub":"AA"}`,
+		`{"kty":"AKP","alg":"ML-DSA-65","pub":"` + m["pub"] + `"}`,
+		// priv與公鑰不匹配
+		`{"kty":"AKP","alg":"ML-DSA-44","pub":"` + m["pub"] + `","priv":"k-8ubvH7CJmdFCq-ApVII3DT9DvbJUp44rDVFo7KBl4"}`,
+	} {
+		if _, err = jwk.Parse([]byte(src)); !errors.Is(err, jwk.ErrInvalidMember) {
+			t.Fatal(i, err)
+		}
+	}
+	if _, err = jwk.Parse([]byte(`{"kty":"AKP","alg":"ML-DSA-99","pub":"AA"}`)); !errors.Is(err, jwk.ErrUnsupportedKeyType) {
+		t.Fatal(err)
+	}
+}
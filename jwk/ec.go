@@ -0,0 +1,180 @@
+package jwk
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+)
+
+// https://datatracker.ietf.org/doc/html/rfc7518#section-6.2.1.1
+const (
+	CurveP256 = "P-256"
+	CurveP384 = "P-384"
+	CurveP521 = "P-521"
+)
+
+func ecCurve(crv string) (elliptic.Curve, ecdh.Curve, error) {
+	switch crv {
+	case CurveP256:
+		return elliptic.P256(), ecdh.P256(), nil
+	case CurveP384:
+		return elliptic.P384(), ecdh.P384(), nil
+	case CurveP521:
+		return elliptic.P521(), ecdh.P521(), nil
+	case "":
+		return nil, nil, errMember("crv", "is missing")
+	}
+	return nil, nil, fmt.Errorf("crv: %q %w", crv, ErrUnsupportedCurve)
+}
+
+func ecCurveName(curve elliptic.Curve) (string, error) {
+	switch curve {
+	case elliptic.P256():
+		return CurveP256, nil
+	case elliptic.P384():
+		return CurveP384, nil
+	case elliptic.P521():
+		return CurveP521, nil
+	}
+	if curve == nil {
+		return "", fmt.Errorf("curve is nil. %w", ErrUnsupportedCurve)
+	}
+	return "", fmt.Errorf("%s %w", curve.Params().Name, ErrUnsupportedCurve)
+}
+
+// ecCoordinateSize 座標與私鑰所需的byte數，P-521 => 66
+func ecCoordinateSize(curve elliptic.Curve) int {
+	return (curve.Params().BitSize + 7) >> 3
+}
+
+// https://datatracker.ietf.org/doc/html/rfc7518#section-6.2
+func (raw *rawKey) ecKey() (any, error) {
+	if raw.Crv == CurveSecp256k1 {
+		return raw.secp256k1Key()
+	}
+	curve, ecdhCurve, err := ecCurve(raw.Crv)
+	if err != nil {
+		return nil, err
+	}
+	size := ecCoordinateSize(curve)
+
+	// 座標必須是完整的長度，不可以省略前面的0: https://datatracker.ietf.org/doc/html/rfc7518#section-6.2.1.2
+	x, err := decodeFixedMember("x", raw.X, size)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeFixedMember("y", raw.Y, size)
+	if err != nil {
+		return nil, err
+	}
+	publicKey := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(x),
+		Y:     new(big.Int).SetBytes(y),
+	}
+	// ECDH() 會檢查點是否在曲線上
+	ecdhPublicKey, err := publicKey.ECDH()
+	if err != nil {
+		return nil, fmt.Errorf("point is not on the curve %s. %w", raw.Crv, ErrInvalidMember)
+	}
+
+	if raw.D == "" {
+		return publicKey, nil
+	}
+
+	d, err := decodeFixedMember("d", raw.D, size)
+	if err != nil {
+		return nil, err
+	}
+	ecdhPrivateKey, err := ecdhCurve.NewPrivateKey(d) // 會確認d在合法的範圍之內
+	if err != nil {
+		return nil, errMember("d", err.Error())
+	}
+	if !ecdhPrivateKey.PublicKey().Equal(ecdhPublicKey) {
+		return nil, errMember("d", "does not match the public key")
+	}
+	return &ecdsa.PrivateKey{
+		PublicKey: *publicKey,
+		D:         new(big.Int).SetBytes(d),
+	}, nil
+}
+
+func marshalEC(key any) (raw *rawKey, ok bool, err error) {
+	var (
+		publicKey  *ecdsa.PublicKey
+		privateKey *ecdsa.PrivateKey
+	)
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		publicKey = k
+	case *ecdsa.PrivateKey:
+		privateKey = k
+		publicKey = &k.PublicKey
+	default:
+		return marshalSecp256k1(key)
+	}
+
+	if publicKey == nil || publicKey.X == nil || publicKey.Y == nil {
+		return nil, true, fmt.Errorf("ECDSA public key is empty. %w", ErrInvalidMember)
+	}
+	crv, err := ecCurveName(publicKey.Curve)
+	if err != nil {
+		return nil, true, err
+	}
+	if _, err = publicKey.ECDH(); err != nil {
+		return nil, true, fmt.Errorf("point is not on the curve %s. %w", crv, ErrInvalidMember)
+	}
+
+	size := ecCoordinateSize(publicKey.Curve)
+	raw = &rawKey{
+		Kty: KeyTypeEC,
+		Crv: crv,
+		X:   encodeMember(publicKey.X.FillBytes(make([]byte, size))),
+		Y:   encodeMember(publicKey.Y.FillBytes(make([]byte, size))),
+	}
+	if privateKey == nil {
+		return raw, true, nil
+	}
+
+	if privateKey.D == nil || privateKey.D.Sign() <= 0 || privateKey.D.BitLen() > size*8 {
+		return nil, true, fmt.Errorf("ECDSA private key is invalid. %w", ErrInvalidMember)
+	}
+	d := privateKey.D.FillBytes(make([]byte, size))
+	ecdhPrivateKey, err := privateKey.ECDH()
+	if err != nil {
+		return nil, true, fmt.Errorf("%w %w", err, ErrInvalidMember)
+	}
+	ecdhPublicKey, _ := publicKey.ECDH()
+	if subtle.ConstantTimeCompare(ecdhPrivateKey.PublicKey().Bytes(), ecdhPublicKey.Bytes()) != 1 {
+		return nil, true, errMember("d", "does not match the public key")
+	}
+	raw.D = encodeMember(d)
+	return raw, true, nil
+}
+
+func publicEC(key any) (any, bool) {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return k, true
+	case *ecdsa.PrivateKey:
+		return &k.PublicKey, true
+	}
+	return publicSecp256k1(key)
+}
+
+// ecKeyCurve 取得key的crv名稱，若非EC的key則回傳空字串
+func ecKeyCurve(key any) string {
+	pub, ok := publicEC(key)
+	if !ok {
+		return ""
+	}
+	ecdsaKey, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return CurveSecp256k1
+	}
+	crv, _ := ecCurveName(ecdsaKey.Curve)
+	return crv
+}
@@ -0,0 +1,32 @@
+package jwk
+
+import (
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+)
+
+var (
+	// ErrUnsupportedKeyType kty不支援，或者Go的key型別無法轉換成jwk
+	ErrUnsupportedKeyType = fmt.Errorf("%w %w",
+		errors.New("jwk: unsupported key type"),
+		jwt.ErrInvalidKeyType,
+	)
+
+	// ErrUnsupportedCurve crv不支援
+	ErrUnsupportedCurve = fmt.Errorf("%w %w",
+		errors.New("jwk: unsupported curve"),
+		jwt.ErrInvalidKey,
+	)
+
+	// ErrInvalidMember 某一個欄位的內容不合法，例如: 缺少必要欄位、長度不對、base64url解碼失敗、點不在曲線上...
+	ErrInvalidMember = fmt.Errorf("%w %w",
+		errors.New("jwk: invalid member"),
+		jwt.ErrInvalidKey,
+	)
+)
+
+// errMember 標註出是哪一個欄位出錯
+func errMember(name string, reason string) error {
+	return fmt.Errorf("%q %s. %w", name, reason, ErrInvalidMember)
+}
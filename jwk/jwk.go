@@ -0,0 +1,241 @@
+// Package jwk 提供 JSON Web Key (RFC 7517) 與Go的key型別之間的轉換
+// 轉換出來的key可以直接給 jwt.ISigningMethod 的Sign, Verify使用
+//
+//	kty | 公鑰                | 私鑰
+//	RSA | *rsa.PublicKey     | *rsa.PrivateKey
+//	EC  | *ecdsa.PublicKey   | *ecdsa.PrivateKey
+//	OKP | ed25519.PublicKey  | ed25519.PrivateKey
+//	AKP | *mldsa.PublicKey   | *mldsa.PrivateKey
+//	oct | []byte (對稱式，沒有公私鑰之分)
+package jwk
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	KeyTypeRSA = "RSA"
+	KeyTypeEC  = "EC"
+	KeyTypeOKP = "OKP"
+	KeyTypeOct = "oct"
+
+	// KeyTypeAKP Algorithm Key Pair，key只能用在alg所指定的演算法，目前用於ML-DSA(需要Go 1.27以上)
+	// https://datatracker.ietf.org/doc/draft-ietf-cose-dilithium/
+	KeyTypeAKP = "AKP"
+)
+
+// Key JSON Web Key https://datatracker.ietf.org/doc/html/rfc7517#section-4
+type Key struct {
+	// KeyType kty 由Key的型別決定，在New或者Parse的時候會自動填寫
+	KeyType string
+
+	KeyID     string   // kid
+	Use       string   // use: "sig", "enc"
+	KeyOps    []string // key_ops: "sign", "verify", "encrypt", ...
+	Algorithm string   // alg: 此key只允許用在哪一種演算法, 例如: "RS256"
+
+	// Key 實際的鑰匙，型別請參考package的說明
+	Key any
+}
+
+// rawKey 為jwk的json格式，所有的二進位資料都是base64url(不含padding)編碼
+// https://datatracker.ietf.org/doc/html/rfc7518#section-6
+type rawKey struct {
+	Kty    string   `json:"kty"`
+	Kid    string   `json:"kid,omitempty"`
+	Use    string   `json:"use,omitempty"`
+	KeyOps []string `json:"key_ops,omitempty"`
+	Alg    string   `json:"alg,omitempty"`
+
+	// EC, OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+
+	// RSA
+	N   string          `json:"n,omitempty"`
+	E   string          `json:"e,omitempty"`
+	P   string          `json:"p,omitempty"`
+	Q   string          `json:"q,omitempty"`
+	Dp  string          `json:"dp,omitempty"`
+	Dq  string          `json:"dq,omitempty"`
+	Qi  string          `json:"qi,omitempty"`
+	Oth json.RawMessage `json:"oth,omitempty"` // 多質數的RSA不支援，只用來判斷是否有提供
+
+	// 私鑰: RSA, EC, OKP 都用d
+	D string `json:"d,omitempty"`
+
+	// AKP
+	Pub  string `json:"pub,omitempty"`
+	Priv string `json:"priv,omitempty"`
+
+	// oct
+	K string `json:"k,omitempty"`
+}
+
+// New 將Go的key包裝成Key，若key的型別不支援或者內容不合法會報錯
+func New(key any) (*Key, error) {
+	raw, err := marshalKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{KeyType: raw.Kty, Key: key}, nil
+}
+
+// Parse 解析jwk的json內容
+func Parse(data []byte) (*Key, error) {
+	k := &Key{}
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (k *Key) MarshalJSON() ([]byte, error) {
+	raw, err := marshalKey(k.Key)
+	if err != nil {
+		return nil, err
+	}
+	raw.Kid = k.KeyID
+	raw.Use = k.Use
+	raw.KeyOps = k.KeyOps
+	if raw.Kty == KeyTypeAKP {
+		// AKP的alg由key決定，不可以被改成其他的演算法
+		if k.Algorithm != "" && k.Algorithm != raw.Alg {
+			return nil, errMember("alg", fmt.Sprintf("%q does not match the key (%s)", k.Algorithm, raw.Alg))
+		}
+	} else {
+		raw.Alg = k.Algorithm
+	}
+	return json.Marshal(raw)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (k *Key) UnmarshalJSON(data []byte) error {
+	var raw rawKey
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("%w %w", err, ErrInvalidMember)
+	}
+
+	// https://datatracker.ietf.org/doc/html/rfc7517#section-4.3
+	// Duplicate key operation values MUST NOT be present in the array.
+	seen := make(map[string]bool, len(raw.KeyOps))
+	for _, op := range raw.KeyOps {
+		if seen[op] {
+			return errMember("key_ops", fmt.Sprintf("contains duplicate value %q", op))
+		}
+		seen[op] = true
+	}
+
+	var (
+		key any
+		err error
+	)
+	switch raw.Kty {
+	case KeyTypeRSA:
+		key, err = raw.rsaKey()
+	case KeyTypeEC:
+		key, err = raw.ecKey()
+	case KeyTypeOKP:
+		key, err = raw.okpKey()
+	case KeyTypeAKP:
+		key, err = raw.akpKey()
+	case KeyTypeOct:
+		key, err = raw.octKey()
+	case "":
+		return errMember("kty", "is missing")
+	default:
+		return fmt.Errorf("kty: %q %w", raw.Kty, ErrUnsupportedKeyType)
+	}
+	if err != nil {
+		return err
+	}
+
+	*k = Key{
+		KeyType:   raw.Kty,
+		KeyID:     raw.Kid,
+		Use:       raw.Use,
+		KeyOps:    raw.KeyOps,
+		Algorithm: raw.Alg,
+		Key:       key,
+	}
+	return nil
+}
+
+// Public 回傳只包含公鑰的Key，其餘的欄位(kid, use, ...)保持不變
+// 對稱式的鑰匙(oct)沒有公鑰，會回傳錯誤
+func (k *Key) Public() (*Key, error) {
+	pub, err := publicKey(k.Key)
+	if err != nil {
+		return nil, err
+	}
+	out := *k
+	out.Key = pub
+	return &out, nil
+}
+
+func marshalKey(key any) (*rawKey, error) {
+	if raw, ok, err := marshalRSA(key); ok {
+		return raw, err
+	}
+	if raw, ok, err := marshalEC(key); ok {
+		return raw, err
+	}
+	if raw, ok, err := marshalOKP(key); ok {
+		return raw, err
+	}
+	if raw, ok, err := marshalAKP(key); ok {
+		return raw, err
+	}
+	if raw, ok, err := marshalOct(key); ok {
+		return raw, err
+	}
+	return nil, fmt.Errorf("%T %w", key, ErrUnsupportedKeyType)
+}
+
+func publicKey(key any) (any, error) {
+	if pub, ok := publicRSA(key); ok {
+		return pub, nil
+	}
+	if pub, ok := publicEC(key); ok {
+		return pub, nil
+	}
+	if pub, ok := publicOKP(key); ok {
+		return pub, nil
+	}
+	if pub, ok := publicAKP(key); ok {
+		return pub, nil
+	}
+	return nil, fmt.Errorf("%T has no public key. %w", key, ErrUnsupportedKeyType)
+}
+
+// decodeMember 將base64url的欄位解碼，欄位為空視為錯誤
+func decodeMember(name, value string) ([]byte, error) {
+	if value == "" {
+		return nil, errMember(name, "is missing")
+	}
+	bs, err := base64.RawURLEncoding.Strict().DecodeString(value)
+	if err != nil {
+		return nil, errMember(name, "is not a valid base64url string: "+err.Error())
+	}
+	return bs, nil
+}
+
+// decodeFixedMember 除了解碼以外，還要求長度必須剛好為size
+func decodeFixedMember(name, value string, size int) ([]byte, error) {
+	bs, err := decodeMember(name, value)
+	if err != nil {
+		return nil, err
+	}
+	if len(bs) != size {
+		return nil, errMember(name, fmt.Sprintf("must be %d bytes, got %d", size, len(bs)))
+	}
+	return bs, nil
+}
+
+func encodeMember(bs []byte) string {
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
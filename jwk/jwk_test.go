@@ -0,0 +1,314 @@
+package jwk_test
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/ed448"
+	"github.com/CarsonSlovoka/jwt/jwk"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"github.com/CarsonSlovoka/jwt/secp256k1"
+	"regexp"
+	"testing"
+)
+
+func stripWhitespace(s string) string {
+	return regexp.MustCompile(`\s`).ReplaceAllString(s, "")
+}
+
+// https://datatracker.ietf.org/doc/html/rfc7517#appendix-A.2
+var rsaPrivateJWK = stripWhitespace(`{
+	"kty":"RSA",
+	"n":"0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+	"e":"AQAB",
+	"d":"X4cTteJY_gn4FYPsXB8rdXix5vwsg1FLN5E3EaG6RJoVH-HLLKD9M7dx5oo7GURknchnrRweUkC7hT5fJLM0WbFAKNLWY2vv7B6NqXSzUvxT0_YSfqijwp3RTzlBaCxWp4doFk5N2o8Gy_nHNKroADIkJ46pRUohsXywbReAdYaMwFs9tv8d_cPVY3i07a3t8MN6TNwm0dSawm9v47UiCl3Sk5ZiG7xojPLu4sbg1U2jx4IBTNBznbJSzFHK66jT8bgkuqsk0GjskDJk19Z4qwjwbsnn4j2WBii3RL-Us2lGVkY8fkFzme1z0HbIkfz0Y6mqnOYtqc0X4jfcKoAC8Q",
+	"p":"83i-7IvMGXoMXCskv73TKr8637FiO7Z27zv8oj6pbWUQyLPQBQxtPVnwD20R-60eTDmD2ujnMt5PoqMrm8RfmNhVWDtjjMmCMjOpSXicFHj7XOuVIYQyqVWlWEh6dN36GVZYk93N8Bc9vY41xy8B9RzzOGVQzXvNEvn7O0nVbfs",
+	"q":"3dfOR9cuYq-0S-mkFLzgItgMEfFzB2q3hWehMuG0oCuqnb3vobLyumqjVZQO1dIrdwgTnCdpYzBcOfW5r370AFXjiWft_NGEiovonizhKpo9VVS78TzFgxkIdrecRezsZ-1kYd_s1qDbxtkDEgfAITAG9LUnADun4vIcb6yelxk",
+	"dp":"G4sPXkc6Ya9y8oJW9_ILj4xuppu0lzi_H7VTkS8xj5SdX3coE0oimYwxIi2emTAue0UOa5dpgFGyBJ4c8tQ2VF402XRugKDTP8akYhFo5tAA77Qe_NmtuYZc3C3m3I24G2GvR5sSDxUyAN2zq8Lfn9EUms6rY3Ob8YeiKkTiBj0",
+	"dq":"s9lAH9fggBsoFR8Oac2R_E2gw282rT2kGOAhvIllETE1efrA6huUUvMfBcMpn8lqeW6vzznYY5SSQF7pMdC_agI3nG8Ibp1BUb0JUiraRNqUfLhcQb_d9GF4Dh7e74WbRsobRonujTYN1xCaP6TO61jvWrX-L18txXw494Q_cgk",
+	"qi":"GyM_p6JrXySiz1toFgKbWV-JdI3jQ4ypu9rbMWx3rQJBfmt0FoYzgUIZEVFEcOqwemRN81zoDAaa-Bk0KWNGDjJHZDdDmFhW3AN7lI-puxk_mHZGJ11rxyR8O55XLSe3SPmRfKwZI6yU24ZxvQKFYItdldUKGzO6Ia6zTKhAVRU",
+	"alg":"RS256",
+	"kid":"2011-04-29"
+}`)
+
+// https://datatracker.ietf.org/doc/html/rfc7520#section-3.2
+var ecPrivateJWK = stripWhitespace(`{
+	"kty": "EC",
+	"kid": "bilbo.baggins@hobbiton.example",
+	"use": "sig",
+	"crv": "P-521",
+	"x": "AHKZLLOsCOzz5cY97ewNUajB957y-C-U88c3v13nmGZx6sYl_oJXu9A5RkTKqjqvjyekWF-7ytDyRXYgCF5cj0Kt",
+	"y": "AdymlHvOiLxXkEhayXQnNCvDX4h9htZaCJN34kfmC6pV5OhQHiraVySsUdaQkAgDPrwQrJmbnX9cwlGfP-HqHZR1",
+	"d": "AAhRON2r9cqXX1hg-RoI6R1tX5p2rUAYdmpHZoC1XNM56KtscrX6zbKipQrCW9CGZH3T4ubpnoTKLDYJ_fF3_rJt"
+}`)
+
+// https://datatracker.ietf.org/doc/html/rfc8037#appendix-A.1
+var ed25519PrivateJWK = stripWhitespace(`{
+	"kty":"OKP",
+	"crv":"Ed25519",
+	"d":"nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A",
+	"x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"
+}`)
+
+// https://datatracker.ietf.org/doc/html/rfc7517#appendix-A.3
+const octJWK = `{
+	"kty":"oct",
+	"alg":"HS256",
+	"k":"AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow",
+	"kid":"HMAC key used in JWS spec Appendix A.1 example"
+}`
+
+func TestParse(t *testing.T) {
+	msg := []byte("hello")
+	for i, tt := range []struct {
+		src    string
+		method jwt.ISigningMethod
+		check  func(key any) bool
+	}{
+		{rsaPrivateJWK, jwt.SigningMethodRSA256, func(key any) bool { _, ok := key.(*rsa.PrivateKey); return ok }},
+		{ecPrivateJWK, jwt.SigningMethodECDSA512, func(key any) bool { _, ok := key.(*ecdsa.PrivateKey); return ok }},
+		{ed25519PrivateJWK, &jwt.SigningMethodED25519{}, func(key any) bool { _, ok := key.(ed25519.PrivateKey); return ok }},
+	} {
+		key, err := jwk.Parse([]byte(tt.src))
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if !tt.check(key.Key) {
+			t.Fatalf("%d unexpected type %T", i, key.Key)
+		}
+
+		// 轉換出來的key可以直接給ISigningMethod使用
+		signature, err := tt.method.Sign(msg, key.Key)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		pub, err := key.Public()
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if err = tt.method.Verify(msg, signature, pub.Key); err != nil {
+			t.Fatal(i, err)
+		}
+
+		// 再轉回json，內容要與原本的一致
+		bs, err := json.Marshal(key)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		var expected, actual map[string]any
+		_ = json.Unmarshal([]byte(tt.src), &expected)
+		_ = json.Unmarshal(bs, &actual)
+		for k, v := range expected {
+			if actual[k] != v {
+				t.Fatalf("%d %s: expected %v, got %v", i, k, v, actual[k])
+			}
+		}
+	}
+}
+
+func TestParse_oct(t *testing.T) {
+	key, err := jwk.Parse([]byte(octJWK))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Algorithm != "HS256" || key.KeyID != "HMAC key used in JWS spec Appendix A.1 example" {
+		t.Fatal()
+	}
+	if _, ok := key.Key.([]byte); !ok {
+		t.Fatal()
+	}
+	if _, err = key.Public(); !errors.Is(err, jwk.ErrUnsupportedKeyType) {
+		t.Fatal("oct has no public key")
+	}
+	bs, err := json.Marshal(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != `{"kty":"oct","kid":"HMAC key used in JWS spec Appendix A.1 example","alg":"HS256","k":"AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow"}` {
+		t.Fatal(string(bs))
+	}
+}
+
+// https://datatracker.ietf.org/doc/html/rfc7748#section-6.1
+func TestParse_x25519(t *testing.T) {
+	alice, err := jwk.Parse([]byte(`{"kty":"OKP","crv":"X25519","x":"hSDwCYkwp1R0i33ctD73Wg2_Og0mOBr066SpjqqbTmo","d":"dwdtCnMYpX08FsFyUbJmRd9ML4frwJkqsXf7pR25LCo"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := jwk.Parse([]byte(`{"kty":"OKP","crv":"X25519","x":"3p7bfXt9wbTTW2HC7OQ1Nz-DQ8hbeGdNrfx-FG-IK08"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := alice.Key.(*ecdh.PrivateKey).ECDH(bob.Key.(*ecdh.PublicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(secret) != "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742" {
+		t.Fatal(hex.EncodeToString(secret))
+	}
+
+	pub, err := alice.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := json.Marshal(pub)
+	if string(bs) != `{"kty":"OKP","crv":"X25519","x":"hSDwCYkwp1R0i33ctD73Wg2_Og0mOBr066SpjqqbTmo"}` {
+		t.Fatal(string(bs))
+	}
+
+	// X25519只能用於加密，不可以拿來驗證EdDSA
+	set := &jwk.KeySet{Keys: []*jwk.Key{pub}}
+	if _, err = set.KeyFunc(jwt.New(&jwt.SigningMethodED25519{})); !errors.Is(err, jwk.ErrNoCompatibleKey) {
+		t.Fatal(err)
+	}
+}
+
+// 私鑰與公鑰的數值來自 github.com/decred/dcrd/dcrec/secp256k1 的測試向量
+func TestParse_secp256k1(t *testing.T) {
+	const pubJWK = `{"kty":"EC","kid":"k1","crv":"secp256k1","x":"NPlGDw5PCDk9GSs8UTOmugmaoK2f1U68z6zfojn_ScY","y":"C3Hqm9cw_Ykj9tJaepHn3XcoqWBobLWpAbtBng8sojI"}`
+	priv, err := jwk.Parse([]byte(`{"kty":"EC","kid":"k1","crv":"secp256k1","x":"NPlGDw5PCDk9GSs8UTOmugmaoK2f1U68z6zfojn_ScY","y":"C3Hqm9cw_Ykj9tJaepHn3XcoqWBobLWpAbtBng8sojI","d":"ql4o1ql6JHmmVSf3KQMRo2JNTMD6FXhZjuPCYTv5lSI"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := priv.Key.(*secp256k1.PrivateKey); !ok {
+		t.Fatalf("%T", priv.Key)
+	}
+	pub, err := priv.Public()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := json.Marshal(pub)
+	if string(bs) != pubJWK {
+		t.Fatal(string(bs))
+	}
+
+	token := jwt.New(jwt.SigningMethodES256K)
+	token.Header["kid"] = "k1"
+	bsToken, err := token.SignedBytes(priv.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := jwk.ParseSet([]byte(`{"keys":[` + pubJWK + `]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vdFunc, err := parser.New().Parse(string(bsToken), func(method string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodES256K, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, set.KeyFunc); err != nil {
+		t.Fatal(err)
+	}
+
+	// secp256k1的key不可以用在ES256
+	if _, err = set.KeyFunc(jwt.New(jwt.SigningMethodECDSA256)); !errors.Is(err, jwk.ErrNoCompatibleKey) {
+		t.Fatal(err)
+	}
+
+	// 點不在曲線上
+	if _, err = jwk.Parse([]byte(`{"kty":"EC","crv":"secp256k1","x":"NPlGDw5PCDk9GSs8UTOmugmaoK2f1U68z6zfojn_ScY","y":"C3Hqm9cw_Ykj9tJaepHn3XcoqWBobLWpAbtBng8sojM"}`)); !errors.Is(err, jwk.ErrInvalidMember) {
+		t.Fatal(err)
+	}
+}
+
+// 鑰匙來自 https://datatracker.ietf.org/doc/html/rfc8032#section-7.4 (-----Blank)
+func TestParse_ed448(t *testing.T) {
+	const pubJWK = `{"kty":"OKP","crv":"Ed448","x":"X9dEm1m0Yf0s54fsYWrUah2hNCSFpw4fig6nXYDpZ3jt8SR2m0bHBhvWeD3x5Q9s0foavq_oJWGA"}`
+	priv, err := jwk.Parse([]byte(`{"kty":"OKP","crv":"Ed448","x":"X9dEm1m0Yf0s54fsYWrUah2hNCSFpw4fig6nXYDpZ3jt8SR2m0bHBhvWeD3x5Q9s0foavq_oJWGA","d":"bIKlYsuAjRDWMr6JyFE-v2ySnzTd-oyfY8mWDvbjSKNSjIo_zC8ETjmj_FuUSS-PAy51SaIAmPlb"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := priv.Key.(ed448.PrivateKey); !ok {
+		t.Fatalf("%T", priv.Key)
+	}
+	pub, _ := priv.Public()
+	bs, _ := json.Marshal(pub)
+	if string(bs) != pubJWK {
+		t.Fatal(string(bs))
+	}
+
+	// 同一個KeySet之中同時有Ed25519與Ed448，EdDSA的token都可以被驗證
+	ed25519Key, _ := jwk.Parse([]byte(ed25519PrivateJWK))
+	ed25519Pub, _ := ed25519Key.Public()
+	set := &jwk.KeySet{Keys: []*jwk.Key{ed25519Pub, pub}}
+	for _, key := range []any{priv.Key, ed25519Key.Key} {
+		bsToken, err := jwt.New(&jwt.SigningMethodEdDSA{}).SignedBytes(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vdFunc, err := parser.New().Parse(string(bsToken), func(method string) (jwt.ISigningMethod, error) {
+			return &jwt.SigningMethodEdDSA{}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = vdFunc(nil, nil, set.KeyFunc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// d與公鑰不匹配
+	if _, err = jwk.Parse([]byte(`{"kty":"OKP","crv":"Ed448","x":"X9dEm1m0Yf0s54fsYWrUah2hNCSFpw4fig6nXYDpZ3jt8SR2m0bHBhvWeD3x5Q9s0foavq_oJWGA","d":"bIKlYsuAjRDWMr6JyFE-v2ySnzTd-oyfY8mWDvbjSKNSjIo_zC8ETjmj_FuUSS-PAy51SaIAmPla"}`)); !errors.Is(err, jwk.ErrInvalidMember) {
+		t.Fatal(err)
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	for i, tt := range []struct {
+		src    string
+		expect error
+	}{
+		{`{}`, jwk.ErrInvalidMember},
+		{`{"kty":"XXX"}`, jwk.ErrUnsupportedKeyType},
+		{`{"kty":"oct","k":"a2V5","key_ops":["sign","sign"]}`, jwk.ErrInvalidMember},
+		{`{"kty":"oct","k":"a2V5="}`, jwk.ErrInvalidMember}, // 不可以有padding
+		{`{"kty":"RSA","e":"AQAB"}`, jwk.ErrInvalidMember},
+		{`{"kty":"EC","crv":"P-192","x":"AA","y":"AA"}`, jwk.ErrUnsupportedCurve},
+		{`{"kty":"EC","crv":"secp256k1","x":"AA","y":"AA"}`, jwk.ErrInvalidMember},
+		{`{"kty":"EC","x":"AA","y":"AA"}`, jwk.ErrInvalidMember},
+		// x少了開頭的0
+		{`{"kty":"EC","crv":"P-521","x":"cpkss6wI7PPlxj3t7A1RqMH3nvL4L5Tzxzf_XeeYZnHqxiX-glu70DlGRMqqOq-PJ6RYX7vK0PJFdiAIXlyPQq0","y":"AdymlHvOiLxXkEhayXQnNCvDX4h9htZaCJN34kfmC6pV5OhQHiraVySsUdaQkAgDPrwQrJmbnX9cwlGfP-HqHZR1"}`, jwk.ErrInvalidMember},
+		// 點不在曲線上(y的最後一個byte被修改)
+		{`{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyA"}`, jwk.ErrInvalidMember},
+		// d與公鑰不匹配
+		{`{"kty":"EC","crv":"P-256","x":"MKBCTNIcKUSDii11ySs3526iDZ8AiTo7Tu6KPAqv7D4","y":"4Etl6SRW2YiLUrN5vfvVHuhp7x8PxltmWWlbbM4IFyM","d":"0g5vAEKzugrXaRbgKG0Tj2qJ5lMP4Bezds1_sTybkfk"}`, jwk.ErrInvalidMember},
+		{`{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcH"}`, jwk.ErrInvalidMember},
+		{`{"kty":"OKP","crv":"X448","x":"AA"}`, jwk.ErrUnsupportedCurve},
+	} {
+		_, err := jwk.Parse([]byte(tt.src))
+		if !errors.Is(err, tt.expect) {
+			t.Fatalf("%d expected %v, got %v", i, tt.expect, err)
+		}
+		if !errors.Is(err, jwt.ErrInvalidKey) && !errors.Is(err, jwt.ErrInvalidKeyType) {
+			t.Fatal(i, "jwk errors should be classified as jwt key errors")
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := jwk.New("not a key"); !errors.Is(err, jwk.ErrUnsupportedKeyType) {
+		t.Fatal(err)
+	}
+	if _, err := jwk.New(&ecdsa.PublicKey{}); !errors.Is(err, jwk.ErrInvalidMember) {
+		t.Fatal(err)
+	}
+
+	k, _ := jwk.Parse([]byte(ed25519PrivateJWK))
+	key, err := jwk.New(k.Key.(ed25519.PrivateKey).Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.KeyID = "ed"
+	key.Use = "sig"
+	bs, _ := json.Marshal(key)
+	if string(bs) != `{"kty":"OKP","kid":"ed","use":"sig","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}` {
+		t.Fatal(string(bs))
+	}
+}
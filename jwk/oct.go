@@ -0,0 +1,21 @@
+package jwk
+
+// https://datatracker.ietf.org/doc/html/rfc7518#section-6.4
+func (raw *rawKey) octKey() (any, error) {
+	k, err := decodeMember("k", raw.K)
+	if err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+func marshalOct(key any) (raw *rawKey, ok bool, err error) {
+	k, ok := key.([]byte)
+	if !ok {
+		return nil, false, nil
+	}
+	if len(k) == 0 {
+		return nil, true, errMember("k", "is empty")
+	}
+	return &rawKey{Kty: KeyTypeOct, K: encodeMember(k)}, true, nil
+}
@@ -0,0 +1,184 @@
+package jwk
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/subtle"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt/ed448"
+)
+
+// https://datatracker.ietf.org/doc/html/rfc8037#section-2
+const (
+	CurveEd25519 = "Ed25519"
+	CurveEd448   = "Ed448"
+	CurveX25519  = "X25519" // 用於ECDH-ES
+)
+
+// https://datatracker.ietf.org/doc/html/rfc8037#section-2
+func (raw *rawKey) okpKey() (any, error) {
+	switch raw.Crv {
+	case CurveEd25519:
+		return raw.ed25519Key()
+	case CurveEd448:
+		return raw.ed448Key()
+	case CurveX25519:
+		return raw.x25519Key()
+	case "":
+		return nil, errMember("crv", "is missing")
+	}
+	return nil, fmt.Errorf("crv: %q %w", raw.Crv, ErrUnsupportedCurve)
+}
+
+func (raw *rawKey) ed25519Key() (any, error) {
+	x, err := decodeFixedMember("x", raw.X, ed25519.PublicKeySize)
+	if err != nil {
+		return nil, err
+	}
+	if raw.D == "" {
+		return ed25519.PublicKey(x), nil
+	}
+
+	// d為私鑰的seed
+	d, err := decodeFixedMember("d", raw.D, ed25519.SeedSize)
+	if err != nil {
+		return nil, err
+	}
+	privateKey := ed25519.NewKeyFromSeed(d)
+	if subtle.ConstantTimeCompare(privateKey.Public().(ed25519.PublicKey), x) != 1 {
+		return nil, errMember("d", "does not match the public key")
+	}
+	return privateKey, nil
+}
+
+func (raw *rawKey) ed448Key() (any, error) {
+	x, err := decodeFixedMember("x", raw.X, ed448.PublicKeySize)
+	if err != nil {
+		return nil, err
+	}
+	if raw.D == "" {
+		return ed448.PublicKey(x), nil
+	}
+
+	d, err := decodeFixedMember("d", raw.D, ed448.SeedSize)
+	if err != nil {
+		return nil, err
+	}
+	privateKey := ed448.NewKeyFromSeed(d)
+	if subtle.ConstantTimeCompare(privateKey.Public().(ed448.PublicKey), x) != 1 {
+		return nil, errMember("d", "does not match the public key")
+	}
+	return privateKey, nil
+}
+
+// x25519Key X25519的鑰匙以 *ecdh.PublicKey, *ecdh.PrivateKey 表示
+func (raw *rawKey) x25519Key() (any, error) {
+	x, err := decodeFixedMember("x", raw.X, 32)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := ecdh.X25519().NewPublicKey(x)
+	if err != nil {
+		return nil, errMember("x", err.Error())
+	}
+	if raw.D == "" {
+		return publicKey, nil
+	}
+
+	d, err := decodeFixedMember("d", raw.D, 32)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := ecdh.X25519().NewPrivateKey(d)
+	if err != nil {
+		return nil, errMember("d", err.Error())
+	}
+	if !privateKey.PublicKey().Equal(publicKey) {
+		return nil, errMember("d", "does not match the public key")
+	}
+	return privateKey, nil
+}
+
+func marshalOKP(key any) (raw *rawKey, ok bool, err error) {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		if len(k) != ed25519.PublicKeySize {
+			return nil, true, errMember("x", "is not a valid Ed25519 public key")
+		}
+		return &rawKey{Kty: KeyTypeOKP, Crv: CurveEd25519, X: encodeMember(k)}, true, nil
+	case ed25519.PrivateKey:
+		if len(k) != ed25519.PrivateKeySize {
+			return nil, true, errMember("d", "is not a valid Ed25519 private key")
+		}
+		return &rawKey{
+			Kty: KeyTypeOKP,
+			Crv: CurveEd25519,
+			X:   encodeMember(k.Public().(ed25519.PublicKey)),
+			D:   encodeMember(k.Seed()),
+		}, true, nil
+	case ed448.PublicKey:
+		if len(k) != ed448.PublicKeySize {
+			return nil, true, errMember("x", "is not a valid Ed448 public key")
+		}
+		return &rawKey{Kty: KeyTypeOKP, Crv: CurveEd448, X: encodeMember(k)}, true, nil
+	case ed448.PrivateKey:
+		if len(k) != ed448.PrivateKeySize {
+			return nil, true, errMember("d", "is not a valid Ed448 private key")
+		}
+		return &rawKey{
+			Kty: KeyTypeOKP,
+			Crv: CurveEd448,
+			X:   encodeMember(k.Public().(ed448.PublicKey)),
+			D:   encodeMember(k.Seed()),
+		}, true, nil
+	case *ecdh.PublicKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, false, nil // NIST的曲線請使用 *ecdsa.PublicKey
+		}
+		return &rawKey{Kty: KeyTypeOKP, Crv: CurveX25519, X: encodeMember(k.Bytes())}, true, nil
+	case *ecdh.PrivateKey:
+		if k.Curve() != ecdh.X25519() {
+			return nil, false, nil
+		}
+		return &rawKey{
+			Kty: KeyTypeOKP,
+			Crv: CurveX25519,
+			X:   encodeMember(k.PublicKey().Bytes()),
+			D:   encodeMember(k.Bytes()),
+		}, true, nil
+	}
+	return nil, false, nil
+}
+
+func publicOKP(key any) (any, bool) {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return k, true
+	case ed25519.PrivateKey:
+		return k.Public(), true
+	case ed448.PublicKey:
+		return k, true
+	case ed448.PrivateKey:
+		return k.Public(), true
+	case *ecdh.PublicKey:
+		return k, k.Curve() == ecdh.X25519()
+	case *ecdh.PrivateKey:
+		return k.PublicKey(), k.Curve() == ecdh.X25519()
+	}
+	return nil, false
+}
+
+// okpKeyCurve 取得key的crv名稱，若非OKP的key則回傳空字串
+func okpKeyCurve(key any) string {
+	pub, ok := publicOKP(key)
+	if !ok {
+		return ""
+	}
+	switch pub.(type) {
+	case ed25519.PublicKey:
+		return CurveEd25519
+	case ed448.PublicKey:
+		return CurveEd448
+	}
+	return CurveX25519
+}
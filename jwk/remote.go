@@ -0,0 +1,271 @@
+package jwk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxKeySetSize 避免遠端回傳過大的內容
+const maxKeySetSize = 1 << 20
+
+// RemoteKeySet 從jwks_uri取得KeySet，並且會
+//  1. 依據回應的Cache-Control, Expires決定快取的時間
+//  2. 在背景於快取到期時自動更新
+//  3. 當token的kid找不到時(可能是對方剛換了鑰匙)，會重新抓取，但每 minRefetchInterval 最多只會抓一次，避免被惡意的kid打爆
+//
+// RemoteKeySet.KeyFunc 可以直接當成 jwt.KeyFunc 給 parser 使用
+type RemoteKeySet struct {
+	uri string
+
+	// 以下的設定請透過 RemoteOption 設定
+	client             *http.Client
+	fetchTimeout       time.Duration // 單次抓取的時間上限，避免KeyFunc因為對方沒有回應而一直等待
+	refreshInterval    time.Duration // 當回應沒有提供快取資訊時，所使用的快取時間
+	minRefetchInterval time.Duration // 兩次抓取之間最短的間隔，同時也是快取時間的下限
+	maxCacheDuration   time.Duration // 快取時間的上限，避免對方設定了過長的max-age導致鑰匙無法更新
+	timeFunc           func() time.Time
+
+	fetchMu sync.Mutex // 確保同一時間只會有一個請求
+
+	mu        sync.RWMutex
+	set       *KeySet
+	expiresAt time.Time
+	lastFetch time.Time
+	lastErr   error         // 最後一次抓取的錯誤，成功時為nil
+	fetched   uint64        // 成功抓取的次數
+	updated   chan struct{} // 每當更新過快取，就關閉並換一個新的，讓背景的goroutine可以重新計算下一次的更新時間
+}
+
+// NewRemoteKeySet 建立一個RemoteKeySet，並在背景持續更新，直到ctx結束
+// 此時還不會立即發出請求，第一次的抓取會在背景或者第一次呼叫KeyFunc時進行
+func NewRemoteKeySet(ctx context.Context, jwksURI string, options ...RemoteOption) *RemoteKeySet {
+	r := &RemoteKeySet{
+		uri:                jwksURI,
+		client:             http.DefaultClient,
+		fetchTimeout:       10 * time.Second,
+		refreshInterval:    time.Hour,
+		minRefetchInterval: time.Minute,
+		maxCacheDuration:   24 * time.Hour,
+		timeFunc:           time.Now,
+		updated:            make(chan struct{}),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	go r.refreshLoop(ctx)
+	return r
+}
+
+// KeyFunc implements the jwt.KeyFunc
+func (r *RemoteKeySet) KeyFunc(token *jwt.Token) (any, error) {
+	ctx := context.Background()
+	set, err := r.KeySet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := set.KeyFunc(token)
+	if !errors.Is(err, ErrKeyNotFound) {
+		return key, err
+	}
+
+	// 不認識的kid，有可能是對方已經輪替了鑰匙，在允許的頻率之內重新抓取
+	// 頻率的檢查在fetch之中(取得fetchMu之後)進行，同時有很多個不認識的kid也只會發出一個請求
+	if set, err = r.fetch(ctx, fetchIfAllowed); err != nil {
+		return nil, err
+	}
+	return set.KeyFunc(token)
+}
+
+// KeySet 取得目前的KeySet，若尚未抓取或者快取已經過期，則會重新抓取
+//
+// 重新抓取同樣受到 minRefetchInterval 的限制，避免對方故障時每一次驗證都發出請求
+// 抓取失敗時會繼續使用上一次成功取得的KeySet，直到下一次允許重試
+func (r *RemoteKeySet) KeySet(ctx context.Context) (*KeySet, error) {
+	r.mu.RLock()
+	set, expiresAt := r.set, r.expiresAt
+	r.mu.RUnlock()
+	if set != nil && r.timeFunc().Before(expiresAt) {
+		return set, nil
+	}
+	newSet, err := r.fetch(ctx, fetchIfExpired)
+	if err != nil && set != nil {
+		return set, nil
+	}
+	return newSet, err
+}
+
+// Refresh 強制重新抓取(不受 minRefetchInterval 的限制)
+func (r *RemoteKeySet) Refresh(ctx context.Context) error {
+	_, err := r.fetch(ctx, fetchForce)
+	return err
+}
+
+// fetchMode 決定fetch在什麼情況下才會真的發出請求
+type fetchMode int
+
+const (
+	fetchIfExpired fetchMode = iota // 快取到期才抓取，受 minRefetchInterval 的限制
+	fetchIfAllowed                  // 快取尚未到期也抓取(例如不認識的kid)，受 minRefetchInterval 的限制
+	fetchForce                      // 一律抓取
+)
+
+// fetch 抓取遠端的jwks，若在排隊的期間其他人已經抓取成功，就直接使用其結果
+// 除了 fetchForce 以外，在 minRefetchInterval 之內回傳目前的KeySet(可能已過期)或者上一次的錯誤
+// 此檢查在取得fetchMu之後才進行，排在失敗的請求後面的呼叫者不會再各自發出請求
+func (r *RemoteKeySet) fetch(ctx context.Context, mode fetchMode) (*KeySet, error) {
+	r.mu.RLock()
+	fetched := r.fetched
+	r.mu.RUnlock()
+
+	r.fetchMu.Lock()
+	defer r.fetchMu.Unlock()
+
+	r.mu.RLock()
+	set, expiresAt, lastFetch, lastErr := r.set, r.expiresAt, r.lastFetch, r.lastErr
+	alreadyFetched := r.fetched != fetched
+	r.mu.RUnlock()
+	if set != nil && (alreadyFetched || (mode == fetchIfExpired && r.timeFunc().Before(expiresAt))) {
+		return set, nil
+	}
+	if mode != fetchForce && !lastFetch.IsZero() && r.timeFunc().Sub(lastFetch) < r.minRefetchInterval {
+		if set != nil {
+			return set, nil
+		}
+		return nil, lastErr
+	}
+
+	set, ttl, err := r.download(ctx)
+	now := r.timeFunc()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastFetch = now // 失敗也算，避免對方故障時被不斷的重試
+	r.lastErr = err
+	if err != nil {
+		return nil, err
+	}
+	r.set = set
+	r.expiresAt = now.Add(ttl)
+	r.fetched++
+	close(r.updated)
+	r.updated = make(chan struct{})
+	return set, nil
+}
+
+func (r *RemoteKeySet) download(ctx context.Context) (*KeySet, time.Duration, error) {
+	if r.fetchTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.fetchTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.uri, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("jwk: fetch %q: %w", r.uri, err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("jwk: fetch %q: unexpected status %s", r.uri, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxKeySetSize))
+	if err != nil {
+		return nil, 0, fmt.Errorf("jwk: fetch %q: %w", r.uri, err)
+	}
+	set, err := ParseSet(body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("jwk: fetch %q: %w", r.uri, err)
+	}
+	return set, r.cacheDuration(resp.Header), nil
+}
+
+// cacheDuration 依據 Cache-Control 的max-age(優先)或者 Expires 決定快取的時間
+// https://datatracker.ietf.org/doc/html/rfc9111#section-4.2.1
+func (r *RemoteKeySet) cacheDuration(header http.Header) time.Duration {
+	ttl := r.refreshInterval
+	found := false
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-cache", "no-store":
+			ttl, found = 0, true
+		case "max-age":
+			if found {
+				continue // no-cache, no-store優先
+			}
+			if seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64); err == nil && seconds >= 0 {
+				ttl, found = time.Duration(seconds)*time.Second, true
+			}
+		}
+	}
+	if !found {
+		if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+			ttl = expires.Sub(r.timeFunc())
+		}
+	}
+
+	if ttl < r.minRefetchInterval {
+		ttl = r.minRefetchInterval
+	}
+	if ttl > r.maxCacheDuration {
+		ttl = r.maxCacheDuration
+	}
+	return ttl
+}
+
+// nextRefresh 下一次背景更新的時間，呼叫前必須持有r.mu
+// 快取到期的時候才需要更新，但也不能早於 minRefetchInterval 所允許的時間
+// 否則在間隔之內fetch只會回傳舊的KeySet，背景會因為等待的時間為負數而不斷的重試
+func (r *RemoteKeySet) nextRefresh() time.Time {
+	if r.lastFetch.IsZero() {
+		return time.Time{} // 還沒有抓取過，立即抓取
+	}
+	next := r.lastFetch.Add(r.minRefetchInterval)
+	if r.set != nil && r.expiresAt.After(next) {
+		next = r.expiresAt
+	}
+	return next
+}
+
+// refreshLoop 在快取到期的時候於背景更新，如此驗證token時就不需要等待網路請求
+func (r *RemoteKeySet) refreshLoop(ctx context.Context) {
+	for {
+		r.mu.RLock()
+		wait := max(r.nextRefresh().Sub(r.timeFunc()), 0)
+		updated := r.updated
+		r.mu.RUnlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-updated: // 其他途徑已經更新過，重新計算等待的時間
+			timer.Stop()
+		case <-timer.C:
+			// 不強制抓取: 若在等待的期間已經由KeyFunc更新過，就不需要再發出請求
+			if _, err := r.fetch(ctx, fetchIfExpired); err != nil {
+				// 失敗的時候保留舊的KeySet，等待一段時間後再試
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(max(r.minRefetchInterval, time.Second)):
+				}
+			}
+		}
+	}
+}
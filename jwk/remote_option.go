@@ -0,0 +1,53 @@
+package jwk
+
+import (
+	"net/http"
+	"time"
+)
+
+type RemoteOption func(r *RemoteKeySet)
+
+// WithHTTPClient 自定義發出請求的client，例如: 設定proxy, mTLS...
+// 預設為 http.DefaultClient，不論使用哪一個client，每次抓取都會受到 WithFetchTimeout 的限制
+func WithHTTPClient(client *http.Client) RemoteOption {
+	return func(r *RemoteKeySet) {
+		r.client = client
+	}
+}
+
+// WithFetchTimeout 單次抓取(包含讀取內容)的時間上限，預設為10秒，設定為0表示不限制
+// KeyFunc 沒有ctx可以取消，若沒有上限，對方沒有回應時token的驗證會一直等待
+func WithFetchTimeout(d time.Duration) RemoteOption {
+	return func(r *RemoteKeySet) {
+		r.fetchTimeout = d
+	}
+}
+
+// WithRefreshInterval 當回應沒有Cache-Control, Expires時，所使用的快取時間，預設為1小時
+func WithRefreshInterval(d time.Duration) RemoteOption {
+	return func(r *RemoteKeySet) {
+		r.refreshInterval = d
+	}
+}
+
+// WithMinRefetchInterval 兩次抓取之間最短的間隔，預設為1分鐘
+// 遇到不認識的kid時，在此間隔內最多只會重新抓取一次；它同時也是快取時間的下限
+func WithMinRefetchInterval(d time.Duration) RemoteOption {
+	return func(r *RemoteKeySet) {
+		r.minRefetchInterval = d
+	}
+}
+
+// WithMaxCacheDuration 快取時間的上限，預設為24小時
+func WithMaxCacheDuration(d time.Duration) RemoteOption {
+	return func(r *RemoteKeySet) {
+		r.maxCacheDuration = d
+	}
+}
+
+// WithTimeFunc 判斷快取是否過期的時間基準，預設使用 time.Now
+func WithTimeFunc(f func() time.Time) RemoteOption {
+	return func(r *RemoteKeySet) {
+		r.timeFunc = f
+	}
+}
@@ -0,0 +1,406 @@
+package jwk_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/jwk"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksServer 模擬發行者的jwks_uri
+type jwksServer struct {
+	*httptest.Server
+	requests atomic.Int32
+
+	mu     sync.Mutex
+	body   []byte
+	header http.Header
+	status int // 不為0時回傳此錯誤，模擬發行者故障
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	s := &jwksServer{header: http.Header{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.status != 0 {
+			http.Error(w, "oops", s.status)
+			return
+		}
+		for k, v := range s.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(s.body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// publish 發佈新的鑰匙(模擬鑰匙輪替)
+func (s *jwksServer) publish(t *testing.T, keys map[string]*ecdsa.PrivateKey) {
+	set := jwk.KeySet{}
+	for kid, key := range keys {
+		k, err := jwk.New(&key.PublicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k.KeyID = kid
+		set.Keys = append(set.Keys, k)
+	}
+	bs, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.body = bs
+	s.mu.Unlock()
+}
+
+// fakeClock 讓測試可以控制快取是否過期
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func TestRemoteKeySet_KeyFunc(t *testing.T) {
+	key1, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	key2, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	srv := newJWKSServer(t)
+	srv.header.Set("Cache-Control", "public, max-age=300")
+	srv.publish(t, map[string]*ecdsa.PrivateKey{"k1": key1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := &fakeClock{now: time.Now()}
+	remote := jwk.NewRemoteKeySet(ctx, srv.URL,
+		jwk.WithHTTPClient(srv.Client()),
+		jwk.WithTimeFunc(clock.Now),
+		jwk.WithMinRefetchInterval(time.Minute),
+	)
+
+	p := parser.New()
+	verify := func(kid string, key *ecdsa.PrivateKey) error {
+		token := jwt.New(jwt.SigningMethodECDSA256)
+		token.Header["kid"] = kid
+		bsToken, _ := token.SignedBytes(key)
+		vdFunc, err := p.Parse(string(bsToken), getSigningMethod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vdFunc(nil, nil, remote.KeyFunc)
+	}
+
+	if err := verify("k1", key1); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify("k1", key1); err != nil { // 使用快取
+		t.Fatal(err)
+	}
+	if n := srv.requests.Load(); n != 1 {
+		t.Fatalf("expected 1 request, got %d", n)
+	}
+
+	// 發行者輪替了鑰匙，但距離上次抓取還不到1分鐘，所以不會重新抓取
+	srv.publish(t, map[string]*ecdsa.PrivateKey{"k1": key1, "k2": key2})
+	clock.Add(30 * time.Second)
+	if err := verify("k2", key2); !errors.Is(err, jwk.ErrKeyNotFound) {
+		t.Fatal(err)
+	}
+	if n := srv.requests.Load(); n != 1 {
+		t.Fatalf("expected 1 request, got %d", n)
+	}
+
+	// 超過間隔之後，不認識的kid會觸發一次重新抓取
+	clock.Add(31 * time.Second)
+	if err := verify("k2", key2); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.requests.Load(); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+
+	// 惡意的kid不能一直觸發請求
+	for range 10 {
+		if err := verify("unknown", key2); !errors.Is(err, jwk.ErrKeyNotFound) {
+			t.Fatal(err)
+		}
+	}
+	if n := srv.requests.Load(); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+
+	// 快取(max-age=300)過期之後會重新抓取
+	clock.Add(301 * time.Second)
+	if err := verify("k1", key1); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.requests.Load(); n != 3 {
+		t.Fatalf("expected 3 requests, got %d", n)
+	}
+}
+
+func TestRemoteKeySet_Expires(t *testing.T) {
+	key1, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	clock := &fakeClock{now: time.Now()}
+
+	srv := newJWKSServer(t)
+	srv.header.Set("Expires", clock.Now().Add(10*time.Minute).UTC().Format(http.TimeFormat))
+	srv.publish(t, map[string]*ecdsa.PrivateKey{"k1": key1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remote := jwk.NewRemoteKeySet(ctx, srv.URL,
+		jwk.WithHTTPClient(srv.Client()),
+		jwk.WithTimeFunc(clock.Now),
+	)
+	for _, d := range []time.Duration{0, 5 * time.Minute, 4 * time.Minute} {
+		clock.Add(d)
+		if _, err := remote.KeySet(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := srv.requests.Load(); n != 1 {
+		t.Fatalf("expected 1 request, got %d", n)
+	}
+	clock.Add(2 * time.Minute)
+	if _, err := remote.KeySet(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.requests.Load(); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+}
+
+// 在背景更新，不需要等到有token進來
+func TestRemoteKeySet_background(t *testing.T) {
+	key1, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	srv := newJWKSServer(t)
+	srv.publish(t, map[string]*ecdsa.PrivateKey{"k1": key1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = jwk.NewRemoteKeySet(ctx, srv.URL,
+		jwk.WithHTTPClient(srv.Client()),
+		jwk.WithRefreshInterval(20*time.Millisecond), // 沒有Cache-Control時使用
+		jwk.WithMinRefetchInterval(10*time.Millisecond),
+	)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.requests.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("background refresh did not happen, requests: %d", srv.requests.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 結束之後就不會再更新
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	n := srv.requests.Load()
+	time.Sleep(100 * time.Millisecond)
+	if srv.requests.Load() != n {
+		t.Fatal("refresh should stop after the context is done")
+	}
+}
+
+func (s *jwksServer) setStatus(status int) {
+	s.mu.Lock()
+	s.status = status
+	s.mu.Unlock()
+}
+
+// 快取過期但發行者故障時，繼續使用舊的KeySet，且重試的頻率受到minRefetchInterval的限制
+func TestRemoteKeySet_stale(t *testing.T) {
+	key1, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	clock := &fakeClock{now: time.Now()}
+
+	srv := newJWKSServer(t)
+	srv.header.Set("Cache-Control", "max-age=300")
+	srv.publish(t, map[string]*ecdsa.PrivateKey{"k1": key1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remote := jwk.NewRemoteKeySet(ctx, srv.URL,
+		jwk.WithHTTPClient(srv.Client()),
+		jwk.WithTimeFunc(clock.Now),
+		jwk.WithMinRefetchInterval(time.Minute),
+	)
+	if _, err := remote.KeySet(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.requests.Load(); n != 1 {
+		t.Fatalf("expected 1 request, got %d", n)
+	}
+
+	srv.setStatus(http.StatusServiceUnavailable)
+	clock.Add(301 * time.Second)
+	for range 10 {
+		set, err := remote.KeySet(ctx)
+		if err != nil || len(set.LookupKeyID("k1")) != 1 {
+			t.Fatal("the previous key set should be served", err)
+		}
+	}
+	if n := srv.requests.Load(); n != 2 {
+		t.Fatalf("expected 2 requests, got %d", n)
+	}
+
+	// 超過間隔之後才會再重試
+	clock.Add(61 * time.Second)
+	if _, err := remote.KeySet(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.requests.Load(); n != 3 {
+		t.Fatalf("expected 3 requests, got %d", n)
+	}
+
+	srv.setStatus(0)
+	clock.Add(61 * time.Second)
+	if _, err := remote.KeySet(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := srv.requests.Load(); n != 4 {
+		t.Fatalf("expected 4 requests, got %d", n)
+	}
+}
+
+// 對方沒有回應時，KeyFunc不可以一直等待
+func TestRemoteKeySet_timeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remote := jwk.NewRemoteKeySet(ctx, srv.URL,
+		jwk.WithHTTPClient(srv.Client()),
+		jwk.WithFetchTimeout(50*time.Millisecond),
+	)
+	start := time.Now()
+	if _, err := remote.KeyFunc(jwt.New(jwt.SigningMethodECDSA256)); err == nil {
+		t.Fatal("must fatal")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatal(elapsed)
+	}
+}
+
+func TestRemoteKeySet_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remote := jwk.NewRemoteKeySet(ctx, srv.URL, jwk.WithHTTPClient(srv.Client()))
+	if _, err := remote.KeyFunc(jwt.New(jwt.SigningMethodECDSA256)); err == nil {
+		t.Fatal("must fatal")
+	}
+}
+
+// 強制的抓取在快取快要到期時失敗，背景的更新必須等到 minRefetchInterval 之後，不可以不斷的重試
+func TestRemoteKeySet_refreshLoop(t *testing.T) {
+	key1, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	srv := newJWKSServer(t)
+	srv.publish(t, map[string]*ecdsa.PrivateKey{"k1": key1})
+
+	var calls atomic.Int64 // 每一次背景的迴圈都至少會取得一次時間
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remote := jwk.NewRemoteKeySet(ctx, srv.URL,
+		jwk.WithHTTPClient(srv.Client()),
+		jwk.WithTimeFunc(func() time.Time {
+			calls.Add(1)
+			return time.Now()
+		}),
+		jwk.WithRefreshInterval(300*time.Millisecond), // 沒有Cache-Control時使用
+		jwk.WithMinRefetchInterval(200*time.Millisecond),
+	)
+	if _, err := remote.KeySet(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(250 * time.Millisecond)
+	srv.setStatus(http.StatusServiceUnavailable)
+	if err := remote.Refresh(ctx); err == nil {
+		t.Fatal("must fatal")
+	}
+	start := calls.Load()
+	time.Sleep(400 * time.Millisecond) // 經過快取到期的時間點(300ms)以及下一次允許重試的時間點(450ms)
+	if n := calls.Load() - start; n > 100 {
+		t.Fatalf("the refresh loop is spinning: %d calls", n)
+	}
+	if n := srv.requests.Load(); n != 3 {
+		t.Fatalf("expected 3 requests, got %d", n)
+	}
+}
+
+// 同時有很多個不認識的kid，或者排在失敗的請求後面，都只能發出一個請求
+func TestRemoteKeySet_concurrentUnknownKid(t *testing.T) {
+	key1, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	clock := &fakeClock{now: time.Now()}
+	srv := newJWKSServer(t)
+	srv.header.Set("Cache-Control", "max-age=300")
+	srv.publish(t, map[string]*ecdsa.PrivateKey{"k1": key1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remote := jwk.NewRemoteKeySet(ctx, srv.URL,
+		jwk.WithHTTPClient(srv.Client()),
+		jwk.WithTimeFunc(clock.Now),
+		jwk.WithMinRefetchInterval(time.Minute),
+	)
+	if _, err := remote.KeySet(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, status := range []int{http.StatusServiceUnavailable, 0} {
+		srv.setStatus(status)
+		clock.Add(61 * time.Second)
+		before := srv.requests.Load()
+		var wg sync.WaitGroup
+		for range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				token := jwt.New(jwt.SigningMethodECDSA256)
+				token.Header["kid"] = "unknown"
+				_, _ = remote.KeyFunc(token)
+			}()
+		}
+		wg.Wait()
+		if n := srv.requests.Load() - before; n != 1 {
+			t.Fatalf("status %d: expected 1 request, got %d", status, n)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package jwk
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"math/big"
+)
+
+// https://datatracker.ietf.org/doc/html/rfc7518#section-6.3
+func (raw *rawKey) rsaKey() (any, error) {
+	nBytes, err := decodeMember("n", raw.N)
+	if err != nil {
+		return nil, err
+	}
+	eBytes, err := decodeMember("e", raw.E)
+	if err != nil {
+		return nil, err
+	}
+	if len(eBytes) > 4 || eBytes[0] == 0 { // 4 byte已經足夠表示常見的e(65537)，且不允許有多餘的0
+		return nil, errMember("e", "is not a valid exponent")
+	}
+	e := int(new(big.Int).SetBytes(eBytes).Int64())
+	if e < 3 || e&1 == 0 {
+		return nil, errMember("e", "is not a valid exponent")
+	}
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: e,
+	}
+	if publicKey.N.Sign() == 0 {
+		return nil, errMember("n", "must not be zero")
+	}
+
+	if raw.D == "" {
+		if raw.P != "" || raw.Q != "" || raw.Dp != "" || raw.Dq != "" || raw.Qi != "" {
+			return nil, errMember("d", "is missing")
+		}
+		return publicKey, nil
+	}
+
+	// 私鑰
+	if len(raw.Oth) > 0 {
+		return nil, fmt.Errorf("multi-prime RSA is not supported. %w", ErrUnsupportedKeyType)
+	}
+	var d, p, q *big.Int
+	for _, m := range []struct {
+		name, value string
+		out         **big.Int
+	}{
+		{"d", raw.D, &d},
+		{"p", raw.P, &p},
+		{"q", raw.Q, &q},
+	} {
+		bs, err := decodeMember(m.name, m.value)
+		if err != nil {
+			return nil, err
+		}
+		*m.out = new(big.Int).SetBytes(bs)
+	}
+
+	privateKey := &rsa.PrivateKey{
+		PublicKey: *publicKey,
+		D:         d,
+		Primes:    []*big.Int{p, q},
+	}
+	if err = privateKey.Validate(); err != nil {
+		return nil, fmt.Errorf("%w %w", err, ErrInvalidMember)
+	}
+	privateKey.Precompute()
+
+	// dp, dq, qi 可以由其他的欄位算出來，如果有提供就要檢查是否一致
+	for _, m := range []struct {
+		name, value string
+		expected    *big.Int
+	}{
+		{"dp", raw.Dp, privateKey.Precomputed.Dp},
+		{"dq", raw.Dq, privateKey.Precomputed.Dq},
+		{"qi", raw.Qi, privateKey.Precomputed.Qinv},
+	} {
+		if m.value == "" {
+			continue
+		}
+		bs, err := decodeMember(m.name, m.value)
+		if err != nil {
+			return nil, err
+		}
+		if new(big.Int).SetBytes(bs).Cmp(m.expected) != 0 {
+			return nil, errMember(m.name, "does not match the private key")
+		}
+	}
+	return privateKey, nil
+}
+
+func marshalRSA(key any) (raw *rawKey, ok bool, err error) {
+	var (
+		publicKey  *rsa.PublicKey
+		privateKey *rsa.PrivateKey
+	)
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		publicKey = k
+	case *rsa.PrivateKey:
+		privateKey = k
+		publicKey = &k.PublicKey
+	default:
+		return nil, false, nil
+	}
+
+	if publicKey == nil || publicKey.N == nil || publicKey.E < 3 {
+		return nil, true, fmt.Errorf("RSA public key is empty. %w", ErrInvalidMember)
+	}
+	raw = &rawKey{
+		Kty: KeyTypeRSA,
+		N:   encodeMember(publicKey.N.Bytes()),
+		E:   encodeMember(big.NewInt(int64(publicKey.E)).Bytes()),
+	}
+	if privateKey == nil {
+		return raw, true, nil
+	}
+
+	if privateKey.D == nil || len(privateKey.Primes) != 2 {
+		return nil, true, fmt.Errorf("only two-prime RSA private keys are supported. %w", ErrUnsupportedKeyType)
+	}
+	p, q := privateKey.Primes[0], privateKey.Primes[1]
+	qi := new(big.Int).ModInverse(q, p)
+	if qi == nil {
+		return nil, true, fmt.Errorf("RSA private key is invalid. %w", ErrInvalidMember)
+	}
+	one := big.NewInt(1)
+	raw.D = encodeMember(privateKey.D.Bytes())
+	raw.P = encodeMember(p.Bytes())
+	raw.Q = encodeMember(q.Bytes())
+	// 不直接使用Precomputed，避免使用者沒有呼叫過Precompute
+	raw.Dp = encodeMember(new(big.Int).Mod(privateKey.D, new(big.Int).Sub(p, one)).Bytes())
+	raw.Dq = encodeMember(new(big.Int).Mod(privateKey.D, new(big.Int).Sub(q, one)).Bytes())
+	raw.Qi = encodeMember(qi.Bytes())
+	return raw, true, nil
+}
+
+func publicRSA(key any) (any, bool) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return k, true
+	case *rsa.PrivateKey:
+		return &k.PublicKey, true
+	}
+	return nil, false
+}
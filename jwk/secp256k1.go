@@ -0,0 +1,82 @@
+package jwk
+
+import (
+	"fmt"
+	"github.com/CarsonSlovoka/jwt/secp256k1"
+)
+
+// CurveSecp256k1 https://datatracker.ietf.org/doc/html/rfc8812#section-3.1
+const CurveSecp256k1 = "secp256k1"
+
+// secp256k1Key 與 ecKey 相同，但標準函式庫沒有secp256k1，所以使用 secp256k1.PublicKey, secp256k1.PrivateKey
+func (raw *rawKey) secp256k1Key() (any, error) {
+	x, err := decodeFixedMember("x", raw.X, 32)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeFixedMember("y", raw.Y, 32)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := secp256k1.NewPublicKey(append(append([]byte{4}, x...), y...))
+	if err != nil {
+		return nil, fmt.Errorf("point is not on the curve %s. %w", raw.Crv, ErrInvalidMember)
+	}
+	if raw.D == "" {
+		return publicKey, nil
+	}
+
+	d, err := decodeFixedMember("d", raw.D, 32)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := secp256k1.NewPrivateKey(d)
+	if err != nil {
+		return nil, errMember("d", err.Error())
+	}
+	if !privateKey.PublicKey().Equal(publicKey) {
+		return nil, errMember("d", "does not match the public key")
+	}
+	return privateKey, nil
+}
+
+func marshalSecp256k1(key any) (raw *rawKey, ok bool, err error) {
+	var (
+		publicKey  *secp256k1.PublicKey
+		privateKey *secp256k1.PrivateKey
+	)
+	switch k := key.(type) {
+	case *secp256k1.PublicKey:
+		publicKey = k
+	case *secp256k1.PrivateKey:
+		privateKey = k
+		publicKey = k.PublicKey()
+	default:
+		return nil, false, nil
+	}
+	if publicKey == nil {
+		return nil, true, fmt.Errorf("secp256k1 public key is empty. %w", ErrInvalidMember)
+	}
+
+	bs := publicKey.Bytes()
+	raw = &rawKey{
+		Kty: KeyTypeEC,
+		Crv: CurveSecp256k1,
+		X:   encodeMember(bs[1:33]),
+		Y:   encodeMember(bs[33:]),
+	}
+	if privateKey != nil {
+		raw.D = encodeMember(privateKey.Bytes())
+	}
+	return raw, true, nil
+}
+
+func publicSecp256k1(key any) (any, bool) {
+	switch k := key.(type) {
+	case *secp256k1.PublicKey:
+		return k, true
+	case *secp256k1.PrivateKey:
+		return k.PublicKey(), true
+	}
+	return nil, false
+}
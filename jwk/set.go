@@ -0,0 +1,179 @@
+package jwk
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"slices"
+	"strings"
+)
+
+var (
+	// ErrKeyNotFound token的header所指定的kid，在KeySet之中找不到
+	ErrKeyNotFound = errors.New("jwk: no key with this kid")
+
+	// ErrNoCompatibleKey 有找到key，但是都不能用在此token上(alg, use, key_ops, kty不符合)
+	ErrNoCompatibleKey = errors.New("jwk: no compatible key")
+)
+
+// KeySet JWK Set https://datatracker.ietf.org/doc/html/rfc7517#section-5
+//
+// KeySet.KeyFunc 可以直接當成 jwt.KeyFunc 給 parser 使用
+type KeySet struct {
+	Keys []*Key `json:"keys"`
+}
+
+// ParseSet 解析jwks的json內容
+// 依據RFC 7517 section-5，不認識的kty(或crv)會被略過，但格式錯誤的key仍然會報錯
+func ParseSet(data []byte) (*KeySet, error) {
+	set := &KeySet{}
+	if err := json.Unmarshal(data, set); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (s *KeySet) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("%w %w", err, ErrInvalidMember)
+	}
+	if raw.Keys == nil {
+		return errMember("keys", "is missing")
+	}
+	keys := make([]*Key, 0, len(raw.Keys))
+	for i, bs := range raw.Keys {
+		key := &Key{}
+		if err := json.Unmarshal(bs, key); err != nil {
+			if errors.Is(err, ErrUnsupportedKeyType) || errors.Is(err, ErrUnsupportedCurve) {
+				continue // Implementations SHOULD ignore JWKs within a JWK Set that use "kty" values that are not understood by them
+			}
+			return fmt.Errorf("keys[%d]: %w", i, err)
+		}
+		keys = append(keys, key)
+	}
+	s.Keys = keys
+	return nil
+}
+
+// LookupKeyID 找出所有kid相同的key，kid在同一個set之中理論上要是唯一的，但不同的kty可以共用
+func (s *KeySet) LookupKeyID(kid string) []*Key {
+	var keys []*Key
+	for _, k := range s.Keys {
+		if k.KeyID == kid {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// KeyFunc implements the jwt.KeyFunc
+//
+// 若token的header有kid，則只會使用該kid的key；找不到時回傳 ErrKeyNotFound
+// 若沒有kid，則會回傳所有可以用在此演算法上的key ([]crypto.PublicKey)，由parser一把一把嘗試
+func (s *KeySet) KeyFunc(token *jwt.Token) (any, error) {
+	alg, _ := token.Header["alg"].(string)
+	if token.SigningMethod != nil {
+		alg = token.SigningMethod.AlgName()
+	}
+
+	candidates := s.Keys
+	v, hasKid := token.Header["kid"]
+	kid, ok := v.(string)
+	if hasKid && !ok {
+		// kid不是字串時不可以當作沒有kid，否則會改為嘗試所有的key
+		return nil, fmt.Errorf("kid must be a string, got %T %w", v, jwt.ErrTokenMalformed)
+	}
+	if hasKid {
+		candidates = s.LookupKeyID(kid)
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("kid: %q %w", kid, ErrKeyNotFound)
+		}
+	}
+
+	var keys []crypto.PublicKey
+	for _, k := range candidates {
+		if !k.usableForVerify(alg) {
+			continue
+		}
+		verifyKey, err := k.verifyKey()
+		if err != nil {
+			continue
+		}
+		keys = append(keys, verifyKey)
+	}
+
+	switch len(keys) {
+	case 0:
+		if hasKid {
+			return nil, fmt.Errorf("kid: %q alg: %q %w", kid, alg, ErrNoCompatibleKey)
+		}
+		return nil, fmt.Errorf("alg: %q %w", alg, ErrNoCompatibleKey)
+	case 1:
+		return keys[0], nil
+	}
+	return keys, nil
+}
+
+// usableForVerify 確認此key是否可以用來驗證alg所加簽出來的內容
+func (k *Key) usableForVerify(alg string) bool {
+	if k.Algorithm != "" && k.Algorithm != alg {
+		return false
+	}
+	if k.Use != "" && k.Use != "sig" {
+		return false
+	}
+	if len(k.KeyOps) > 0 && !slices.Contains(k.KeyOps, "verify") {
+		return false
+	}
+	return k.matchAlgorithm(alg)
+}
+
+// matchAlgorithm 依據演算法的名稱判斷kty, crv是否可以使用
+// https://datatracker.ietf.org/doc/html/rfc7518#section-3.1
+func (k *Key) matchAlgorithm(alg string) bool {
+	switch {
+	case strings.HasPrefix(alg, "HS"):
+		return k.KeyType == KeyTypeOct
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		return k.KeyType == KeyTypeRSA
+	case alg == "ES256", alg == "ES384", alg == "ES512":
+		return k.KeyType == KeyTypeEC &&
+			ecKeyCurve(k.Key) == map[string]string{"ES256": CurveP256, "ES384": CurveP384, "ES512": CurveP521}[alg]
+	case alg == "ES256K":
+		return k.KeyType == KeyTypeEC && ecKeyCurve(k.Key) == CurveSecp256k1
+	case alg == "EdDSA":
+		crv := okpKeyCurve(k.Key)
+		return k.KeyType == KeyTypeOKP && (crv == CurveEd25519 || crv == CurveEd448)
+	case strings.HasPrefix(alg, "ML-DSA-"):
+		return k.KeyType == KeyTypeAKP && akpKeyAlgorithm(k.Key) == alg
+	}
+	// 不認識的演算法，只有在key有明確指定alg的情況下才能使用
+	return k.Algorithm == alg
+}
+
+// KeyFor implements the jwt.IBoundKey interface
+// KeyFunc可以直接回傳 *Key，parser會先確認此key可以用在alg上(alg, use, key_ops, kty, crv)，再取出驗證所用的key
+func (k *Key) KeyFor(alg string) (any, error) {
+	if !k.usableForVerify(alg) {
+		return nil, fmt.Errorf("kid: %q alg: %q %w", k.KeyID, alg, jwt.ErrKeyAlgorithmMismatch)
+	}
+	return k.verifyKey()
+}
+
+// verifyKey 驗證時所用的key，非對稱式的只取公鑰
+func (k *Key) verifyKey() (any, error) {
+	if k.KeyType == KeyTypeOct {
+		return k.Key, nil
+	}
+	pub, err := k.Public()
+	if err != nil {
+		return nil, err
+	}
+	return pub.Key, nil
+}
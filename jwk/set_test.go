@@ -0,0 +1,146 @@
+package jwk_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/jwk"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"testing"
+)
+
+func getSigningMethod(method string) (jwt.ISigningMethod, error) {
+	switch method {
+	case jwt.SigningMethodRSA256.Name:
+		return jwt.SigningMethodRSA256, nil
+	case jwt.SigningMethodECDSA256.Name:
+		return jwt.SigningMethodECDSA256, nil
+	}
+	return nil, fmt.Errorf("unsupport method: %q", method)
+}
+
+// 模擬發行者公開的jwks
+func newTestKeySet(t *testing.T) (*jwk.KeySet, *rsa.PrivateKey, *ecdsa.PrivateKey) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	var keys []*jwk.Key
+	for kid, key := range map[string]any{
+		"rsa-1": &rsaKey.PublicKey,
+		"ec-1":  &ecKey.PublicKey,
+	} {
+		k, err := jwk.New(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		k.KeyID = kid
+		k.Use = "sig"
+		keys = append(keys, k)
+	}
+	bs, err := json.Marshal(jwk.KeySet{Keys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 不認識的kty要被略過
+	var doc map[string][]any
+	_ = json.Unmarshal(bs, &doc)
+	doc["keys"] = append(doc["keys"], map[string]any{"kty": "unknown", "kid": "x"})
+	bs, _ = json.Marshal(doc)
+
+	set, err := jwk.ParseSet(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Keys) != 2 {
+		t.Fatal("unsupported keys should be ignored")
+	}
+	return set, rsaKey, ecKey
+}
+
+func TestKeySet_KeyFunc(t *testing.T) {
+	set, rsaKey, ecKey := newTestKeySet(t)
+	p := parser.New()
+
+	verify := func(method jwt.ISigningMethod, kid string, key any) error {
+		token := jwt.New(method)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		bsToken, err := token.SignedBytes(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vdFunc, err := p.Parse(string(bsToken), getSigningMethod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return vdFunc(nil, nil, set.KeyFunc)
+	}
+
+	if err := verify(jwt.SigningMethodRSA256, "rsa-1", rsaKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(jwt.SigningMethodECDSA256, "ec-1", ecKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// 沒有kid的時候，會嘗試所有相容的key
+	if err := verify(jwt.SigningMethodECDSA256, "", ecKey); err != nil {
+		t.Fatal(err)
+	}
+
+	// 找不到kid
+	err := verify(jwt.SigningMethodRSA256, "rsa-2", rsaKey)
+	if !errors.Is(err, jwk.ErrKeyNotFound) || errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+		t.Fatal(err)
+	}
+
+	// kid存在，但是kty與演算法不符合
+	if err = verify(jwt.SigningMethodRSA256, "ec-1", rsaKey); !errors.Is(err, jwk.ErrNoCompatibleKey) {
+		t.Fatal(err)
+	}
+
+	// 鑰匙正確，但是簽名是由其他私鑰所簽出來的
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	err = verify(jwt.SigningMethodRSA256, "rsa-1", otherKey)
+	if !errors.Is(err, jwt.ErrTokenSignatureInvalid) || errors.Is(err, jwk.ErrKeyNotFound) {
+		t.Fatal(err)
+	}
+	// kid不是字串時不可以改為嘗試所有的key
+	token := jwt.New(jwt.SigningMethodECDSA256)
+	token.Header["kid"] = 1
+	if _, err = set.KeyFunc(token); !errors.Is(err, jwt.ErrTokenMalformed) {
+		t.Fatal(err)
+	}
+}
+
+func TestKeySet_KeyFunc_filter(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	for i, tt := range []struct {
+		key    *jwk.Key
+		usable bool
+	}{
+		{&jwk.Key{KeyType: jwk.KeyTypeEC, Key: &ecKey.PublicKey}, true},
+		{&jwk.Key{KeyType: jwk.KeyTypeEC, Key: &ecKey.PublicKey, Algorithm: "ES256"}, true},
+		{&jwk.Key{KeyType: jwk.KeyTypeEC, Key: &ecKey.PublicKey, Algorithm: "ES384"}, false},
+		{&jwk.Key{KeyType: jwk.KeyTypeEC, Key: &ecKey.PublicKey, Use: "enc"}, false},
+		{&jwk.Key{KeyType: jwk.KeyTypeEC, Key: &ecKey.PublicKey, KeyOps: []string{"verify"}}, true},
+		{&jwk.Key{KeyType: jwk.KeyTypeEC, Key: &ecKey.PublicKey, KeyOps: []string{"encrypt"}}, false},
+	} {
+		set := &jwk.KeySet{Keys: []*jwk.Key{tt.key}}
+		token := jwt.New(jwt.SigningMethodECDSA256)
+		_, err := set.KeyFunc(token)
+		if tt.usable && err != nil {
+			t.Fatal(i, err)
+		}
+		if !tt.usable && !errors.Is(err, jwk.ErrNoCompatibleKey) {
+			t.Fatal(i, err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package jws
+
+import (
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+)
+
+var (
+	// ErrNoSignature 沒有任何簽名
+	ErrNoSignature = fmt.Errorf("%w %w",
+		errors.New("jws: no signature"),
+		jwt.ErrTokenMalformed,
+	)
+
+	// ErrHeaderConflict protected與header(unprotected)有相同的參數
+	// https://datatracker.ietf.org/doc/html/rfc7515#section-7.2.1
+	ErrHeaderConflict = fmt.Errorf("%w %w",
+		errors.New("jws: header parameter names must be disjoint"),
+		jwt.ErrTokenMalformed,
+	)
+)
@@ -0,0 +1,314 @@
+package jws_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/jwk"
+	"github.com/CarsonSlovoka/jwt/jws"
+	"strings"
+	"testing"
+)
+
+func getSigningMethod(method string) (jwt.ISigningMethod, error) {
+	switch method {
+	case jwt.SigningMethodRSA256.Name:
+		return jwt.SigningMethodRSA256, nil
+	case jwt.SigningMethodECDSA256.Name:
+		return jwt.SigningMethodECDSA256, nil
+	case "EdDSA":
+		return &jwt.SigningMethodED25519{}, nil
+	}
+	return nil, fmt.Errorf("unsupport method: %q", method)
+}
+
+// https://datatracker.ietf.org/doc/html/rfc7515#appendix-A.6
+const generalJSON = `{
+	"payload": "eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ",
+	"signatures": [
+		{
+			"protected": "eyJhbGciOiJSUzI1NiJ9",
+			"header": {"kid": "2010-12-29"},
+			"signature": "cC4hiUPoj9Eetdgtv3hF80EGrhuB__dzERat0XF9g2VtQgr9PJbu3XOiZj5RZmh7AAuHIm4Bh-0Qc_lF5YKt_O8W2Fp5jujGbds9uJdbF9CUAr7t1dnZcAcQjbKBYNX4BAynRFdiuB--f_nZLgrnbyTyWzO75vRK5h6xBArLIARNPvkSjtQBMHlb1L07Qe7K0GarZRmB_eSN9383LcOLn6_dO--xi12jzDwusC-eOkHWEsqtFZESc6BfI7noOPqvhJ1phCnvWh6IeYI2w9QOYEUipUTI8np6LbgGY9Fs98rqVt5AXLIhWkWywlVmtVrBp0igcN_IoypGlUPQGe77Rw"
+		},
+		{
+			"protected": "eyJhbGciOiJFUzI1NiJ9",
+			"header": {"kid": "e9bc097a-ce51-4036-9562-d2ade882db0d"},
+			"signature": "DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q"
+		}
+	]
+}`
+
+// https://datatracker.ietf.org/doc/html/rfc7515#appendix-A.7
+const flattenedJSON = `{
+	"payload": "eyJpc3MiOiJqb2UiLA0KICJleHAiOjEzMDA4MTkzODAsDQogImh0dHA6Ly9leGFtcGxlLmNvbS9pc19yb290Ijp0cnVlfQ",
+	"protected": "eyJhbGciOiJFUzI1NiJ9",
+	"header": {"kid": "e9bc097a-ce51-4036-9562-d2ade882db0d"},
+	"signature": "DtEhU3ljbEg8L38VWAfUAqOyKAM6-Xx-F4GawxaepmXFCgfTjDxw5djxLa8ISlSApmWQxfKTUJqPP3-Kg6NU1Q"
+}`
+
+// RFC 7515 A.2, A.3 的公鑰
+const rfc7515KeySet = `{"keys": [
+	{"kty":"RSA","kid":"2010-12-29",
+	 "n":"ofgWCuLjybRlzo0tZWJjNiuSfb4p4fAkd_wWJcyQoTbji9k0l8W26mPddxHmfHQp-Vaw-4qPCJrcS2mJPMEzP1Pt0Bm4d4QlL-yRT-SFd2lZS-pCgNMsD1W_YpRPEwOWvG6b32690r2jZ47soMZo9wGzjb_7OMg0LOL-bSf63kpaSHSXndS5z5rexMdbBYUsLA9e-KXBdQOS-UTo7WTBEMa2R2CapHg665xsmtdVMTBQY4uDZlxvb3qCo5ZwKh9kG4LT6_I5IhlJH7aGhyxXFvUK-DWNmoudF8NAco9_h9iaGNj8q2ethFkMLs91kzk2PAcDTW9gb54h4FRWyuXpoQ",
+	 "e":"AQAB"},
+	{"kty":"EC","kid":"e9bc097a-ce51-4036-9562-d2ade882db0d","crv":"P-256",
+	 "x":"f83OJ3D2xF1Bg8vub9tLe1gHMzV76e8Tus9uPHvRVEU",
+	 "y":"x_FEzRu9m36HLN_tue659LNpXW6pCyStikYjKIWI5a0"}
+]}`
+
+func TestParse_rfc7515(t *testing.T) {
+	set, err := jwk.ParseSet([]byte(rfc7515KeySet))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []string{generalJSON, flattenedJSON} {
+		msg, err := jws.Parse([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		results := msg.Verify(getSigningMethod, set.KeyFunc)
+		if err = results.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != len(msg.Signatures) {
+			t.Fatal("each signature should have a result")
+		}
+		// 結果會告訴我們是由哪一把鑰匙驗證成功
+		for _, r := range results {
+			kid := r.Header["kid"].(string)
+			if r.Key != set.LookupKeyID(kid)[0].Key {
+				t.Fatalf("signatures[%d] should be verified by %s", r.Index, kid)
+			}
+		}
+
+		claims := &jwt.MapClaims{}
+		if err = msg.UnmarshalClaims(claims); err != nil {
+			t.Fatal(err)
+		}
+		if iss, _ := claims.GetIssuer(); iss != "joe" {
+			t.Fatal(iss)
+		}
+	}
+}
+
+func TestToken_GeneralJSON(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublicKey, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	// 三個簽名者
+	token := jws.New([]byte(`{"contract":"42","amount":1000}`))
+	token.AddSignature(jwt.SigningMethodRSA256, map[string]any{"kid": "alice"})
+	token.AddSignature(jwt.SigningMethodECDSA256, nil).Protected["kid"] = "bob"
+	token.AddSignature(&jwt.SigningMethodED25519{}, map[string]any{"kid": "carol"})
+
+	bs, err := token.GeneralJSON(rsaKey, ecKey, edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys := map[string]any{
+		"alice": &rsaKey.PublicKey,
+		"bob":   &ecKey.PublicKey,
+		"carol": edPublicKey,
+	}
+	keyFunc := func(token *jwt.Token) (any, error) {
+		return keys[token.Header["kid"].(string)], nil
+	}
+
+	msg, err := jws.Parse(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(msg.Payload) != `{"contract":"42","amount":1000}` {
+		t.Fatal(string(msg.Payload))
+	}
+	if err = msg.Verify(getSigningMethod, keyFunc).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// bob的鑰匙被換掉，只有bob的簽名失敗
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	keys["bob"] = &otherKey.PublicKey
+	results := msg.Verify(getSigningMethod, keyFunc)
+	for i, r := range results {
+		if (i == 1) != errors.Is(r.Err, jwt.ErrTokenSignatureInvalid) {
+			t.Fatal(i, r.Err)
+		}
+	}
+	if !errors.Is(results.Err(), jwt.ErrTokenSignatureInvalid) {
+		t.Fatal(results.Err())
+	}
+	keys["bob"] = &ecKey.PublicKey
+
+	// unprotected header不受簽名保護，但protected header受到保護
+	var doc map[string]any
+	_ = json.Unmarshal(bs, &doc)
+	doc["signatures"].([]any)[0].(map[string]any)["header"] = map[string]any{"kid": "alice", "note": "added"}
+	doc["signatures"].([]any)[1].(map[string]any)["protected"] = "eyJhbGciOiJFUzI1NiIsImtpZCI6ImNhcm9sIn0"
+	bs, _ = json.Marshal(doc)
+	if msg, err = jws.Parse(bs); err != nil {
+		t.Fatal(err)
+	}
+	results = msg.Verify(getSigningMethod, keyFunc)
+	if results[0].Err != nil || results[1].Err == nil || results[2].Err != nil {
+		t.Fatal(results.Err())
+	}
+}
+
+func TestToken_FlattenedJSON(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	token := jws.NewWithClaims(&jwt.RegisteredClaims{Issuer: "carson"})
+	token.AddSignature(jwt.SigningMethodECDSA256, map[string]any{"kid": "k1"})
+	bs, err := token.FlattenedJSON(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(bs), "signatures") {
+		t.Fatal(string(bs))
+	}
+
+	msg, err := jws.Parse(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := msg.Verify(getSigningMethod, func(*jwt.Token) (any, error) {
+		return &ecKey.PublicKey, nil
+	})
+	if err = results.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// flattened只能有一個簽名
+	token.AddSignature(jwt.SigningMethodECDSA256, nil)
+	if _, err = token.FlattenedJSON(ecKey); err == nil {
+		t.Fatal("must fatal")
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	for i, src := range []string{
+		`{}`,
+		`{"payload":"e30"}`,
+		`{"payload":"e30","signatures":[]}`,
+		`{"payload":"e30","signatures":[{"protected":"eyJhbGciOiJFUzI1NiJ9"}]}`,
+		// alg同時出現在protected與header
+		`{"payload":"e30","protected":"eyJhbGciOiJFUzI1NiJ9","header":{"alg":"ES256"},"signature":"AA"}`,
+		// 沒有alg
+		`{"payload":"e30","header":{"kid":"1"},"signature":"AA"}`,
+		// alg只出現在未受保護的header
+		`{"payload":"e30","header":{"alg":"ES256"},"signature":"AA"}`,
+		// general與flattened混用
+		`{"payload":"e30","signature":"AA","signatures":[{"protected":"eyJhbGciOiJFUzI1NiJ9","signature":"AA"}]}`,
+	} {
+		if _, err := jws.Parse([]byte(src)); !errors.Is(err, jwt.ErrTokenMalformed) {
+			t.Fatal(i, err)
+		}
+	}
+
+	token := jws.New([]byte("hello"))
+	token.AddSignature(jwt.SigningMethodHMAC256, map[string]any{"alg": "HS256"})
+	if _, err := token.GeneralJSON([]byte("key")); !errors.Is(err, jws.ErrHeaderConflict) {
+		t.Fatal(err)
+	}
+}
+
+// crit必須受到保護，且只能列出應用程式所理解的擴充header
+func TestParse_critical(t *testing.T) {
+	key := []byte("my private key: 0123456789abcdef")
+	token := jws.New([]byte("hello"))
+	s := token.AddSignature(jwt.SigningMethodHMAC256, nil)
+	s.Protected["crit"] = []string{"exp-policy"}
+	s.Protected["exp-policy"] = "strict"
+	bs, err := token.FlattenedJSON(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = jws.Parse(bs); !errors.Is(err, jwt.ErrTokenInvalidCritical) {
+		t.Fatal(err)
+	}
+	msg, err := jws.Parse(bs, "exp-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = msg.Verify(func(string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodHMAC256, nil
+	}, func(*jwt.Token) (any, error) {
+		return key, nil
+	}).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// crit放在未受保護的header
+	token = jws.New([]byte("hello"))
+	token.AddSignature(jwt.SigningMethodHMAC256, map[string]any{"crit": []string{"exp-policy"}, "exp-policy": "strict"})
+	if bs, err = token.FlattenedJSON(key); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = jws.Parse(bs, "exp-policy"); !errors.Is(err, jwt.ErrTokenInvalidCritical) {
+		t.Fatal(err)
+	}
+}
+
+type ctxKey struct{}
+
+// contextMethod 記錄 VerifyContext 收到的ctx，用來確認ctx有傳遞下去
+type contextMethod struct {
+	*jwt.SigningMethodHMAC
+	got context.Context
+}
+
+func (m *contextMethod) SignContext(_ context.Context, signingBytes []byte, key any) ([]byte, error) {
+	return m.Sign(signingBytes, key)
+}
+
+func (m *contextMethod) VerifyContext(ctx context.Context, signingBytes []byte, signature []byte, key any) error {
+	m.got = ctx
+	return m.Verify(signingBytes, signature, key)
+}
+
+func TestMessage_VerifyContext(t *testing.T) {
+	key := []byte("my private key: 0123456789abcdef")
+	token := jws.New([]byte("hello"))
+	token.AddSignature(jwt.SigningMethodHMAC256, nil)
+	bs, err := token.FlattenedJSON(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := jws.Parse(bs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFunc := func(*jwt.Token) (any, error) {
+		return key, nil
+	}
+
+	method := &contextMethod{SigningMethodHMAC: jwt.SigningMethodHMAC256}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "caller")
+	if err = msg.VerifyContext(ctx, func(string) (jwt.ISigningMethod, error) {
+		return method, nil
+	}, keyFunc).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if method.got == nil || method.got.Value(ctxKey{}) != "caller" {
+		t.Fatal("the caller's ctx should be passed to VerifyContext")
+	}
+
+	// 沒有實作 jwt.ISigningMethodContext 的方法，在ctx結束之後也不會驗證
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err = msg.VerifyContext(canceled, func(string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodHMAC256, nil
+	}, keyFunc).Err(); !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+}
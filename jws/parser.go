@@ -0,0 +1,237 @@
+package jws
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"maps"
+)
+
+// Message 解析之後的JWS JSON Serialization(general或flattened)
+type Message struct {
+	Payload    []byte
+	Signatures []*ParsedSignature
+
+	rawPayload string // 加簽時所用的是原本的編碼
+}
+
+// ParsedSignature 解析之後的簽名
+type ParsedSignature struct {
+	Protected map[string]any
+	Header    map[string]any
+	Signature []byte
+
+	rawProtected string
+}
+
+// JOSEHeader protected與header的聯集
+func (s *ParsedSignature) JOSEHeader() map[string]any {
+	header := maps.Clone(s.Header)
+	if header == nil {
+		header = make(map[string]any, len(s.Protected))
+	}
+	maps.Copy(header, s.Protected)
+	return header
+}
+
+// SigningBytes 要被驗證的內容: ASCII(BASE64URL(protected) || '.' || BASE64URL(payload))
+func (m *Message) SigningBytes(s *ParsedSignature) []byte {
+	return []byte(s.rawProtected + "." + m.rawPayload)
+}
+
+type rawSignature struct {
+	Protected string         `json:"protected"`
+	Header    map[string]any `json:"header"`
+	Signature *string        `json:"signature"`
+}
+
+// Parse 解析JSON Serialization，有signatures時視為general，否則為flattened
+// https://datatracker.ietf.org/doc/html/rfc7515#section-7.2
+//
+// criticalHeaders 為應用程式所理解的crit擴充header名稱，crit列出了其他的名稱時會回傳 jwt.ErrTokenInvalidCritical
+func Parse(data []byte, criticalHeaders ...string) (*Message, error) {
+	var raw struct {
+		Payload    *string         `json:"payload"`
+		Signatures []*rawSignature `json:"signatures"`
+		rawSignature
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w %w", err, jwt.ErrTokenMalformed)
+	}
+	if raw.Payload == nil {
+		return nil, fmt.Errorf("payload is missing. %w", jwt.ErrTokenMalformed)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(*raw.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not base64 decode payload. %w %w", err, jwt.ErrTokenMalformed)
+	}
+
+	rawSignatures := raw.Signatures
+	if rawSignatures == nil { // flattened
+		rawSignatures = []*rawSignature{&raw.rawSignature}
+	} else if raw.rawSignature.Signature != nil || raw.rawSignature.Protected != "" || raw.rawSignature.Header != nil {
+		return nil, fmt.Errorf("general serialization must not contain signature members at the top level. %w", jwt.ErrTokenMalformed)
+	}
+	if len(rawSignatures) == 0 {
+		return nil, ErrNoSignature
+	}
+
+	m := &Message{Payload: payload, rawPayload: *raw.Payload}
+	for i, rs := range rawSignatures {
+		s, err := parseSignature(rs, criticalHeaders)
+		if err != nil {
+			return nil, fmt.Errorf("signatures[%d]: %w", i, err)
+		}
+		m.Signatures = append(m.Signatures, s)
+	}
+	return m, nil
+}
+
+func parseSignature(rs *rawSignature, criticalHeaders []string) (*ParsedSignature, error) {
+	if rs == nil || rs.Signature == nil {
+		return nil, fmt.Errorf("signature is missing. %w", jwt.ErrTokenMalformed)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(*rs.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("could not base64 decode signature. %w %w", err, jwt.ErrTokenMalformed)
+	}
+	s := &ParsedSignature{Header: rs.Header, Signature: signature, rawProtected: rs.Protected}
+	if rs.Protected != "" {
+		bs, err := base64.RawURLEncoding.DecodeString(rs.Protected)
+		if err != nil {
+			return nil, fmt.Errorf("could not base64 decode protected header. %w %w", err, jwt.ErrTokenMalformed)
+		}
+		if err = json.Unmarshal(bs, &s.Protected); err != nil {
+			return nil, fmt.Errorf("failed to parse protected header: %w %w", err, jwt.ErrTokenMalformed)
+		}
+	}
+	if err = checkDisjoint(s.Protected, s.Header); err != nil {
+		return nil, err
+	}
+	// alg與crit必須受到保護，否則可以在不影響簽名的情況下被竄改
+	// https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.11
+	if _, ok := s.Protected["alg"].(string); !ok {
+		return nil, fmt.Errorf("protected header algorithm not found or not string %w", jwt.ErrTokenMalformed)
+	}
+	if _, exists := s.Header["crit"]; exists {
+		return nil, fmt.Errorf("crit must be in the protected header %w %w", jwt.ErrTokenInvalidCritical, jwt.ErrTokenMalformed)
+	}
+	if err = jwt.CheckCritical(s.JOSEHeader(), criticalHeaders); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Result 單一個簽名的驗證結果
+type Result struct {
+	Index  int            // 對應 Message.Signatures 的位置
+	Header map[string]any // JOSE Header
+	Key    any            // 驗證成功時所使用的鑰匙，若keyFunc回傳多把鑰匙，則為其中吻合的那一把
+	Err    error          // nil表示驗證成功
+}
+
+// Results 所有簽名的驗證結果
+type Results []*Result
+
+// Err 所有失敗的原因，若全部的簽名都通過則為nil
+func (rs Results) Err() error {
+	var errs []error
+	for _, r := range rs {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("signatures[%d]: %w", r.Index, r.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Verify 驗證每一個簽名，回傳的結果順序與 Message.Signatures 相同
+// 至於需要全部的簽名都通過，或者只要特定簽名者通過，由使用者依據結果自行決定
+//
+// keyFunc 與 parser 所使用的相同，收到的token只有Header(JOSE Header)、SigningMethod以及由payload解析出來的Claims(若payload為JSON)
+// 因此可以直接使用 jwk.KeySet.KeyFunc
+func (m *Message) Verify(
+	getSigningMethod func(method string) (jwt.ISigningMethod, error),
+	keyFunc jwt.KeyFunc,
+) Results {
+	return m.VerifyContext(context.Background(), getSigningMethod, keyFunc)
+}
+
+// VerifyContext 與 Verify 相同，若SigningMethod有實作 jwt.ISigningMethodContext 則會使用ctx(例如遠端KMS的期限)
+func (m *Message) VerifyContext(
+	ctx context.Context,
+	getSigningMethod func(method string) (jwt.ISigningMethod, error),
+	keyFunc jwt.KeyFunc,
+) Results {
+	claims := &jwt.MapClaims{}
+	_ = json.Unmarshal(m.Payload, claims)
+
+	results := make(Results, len(m.Signatures))
+	for i, s := range m.Signatures {
+		r := &Result{Index: i, Header: s.JOSEHeader()}
+		r.Key, r.Err = m.verify(ctx, s, r.Header, claims, getSigningMethod, keyFunc)
+		results[i] = r
+	}
+	return results
+}
+
+func (m *Message) verify(
+	ctx context.Context,
+	s *ParsedSignature,
+	header map[string]any,
+	claims jwt.IClaims,
+	getSigningMethod func(method string) (jwt.ISigningMethod, error),
+	keyFunc jwt.KeyFunc,
+) (any, error) {
+	if keyFunc == nil {
+		return nil, fmt.Errorf("error keyFunc is nil. %w", jwt.ErrInvalidKeyType)
+	}
+	method, err := getSigningMethod(header["alg"].(string))
+	if err != nil {
+		return nil, err
+	}
+	keys, err := keyFunc(&jwt.Token{Header: header, Claims: claims, SigningMethod: method})
+	if err != nil {
+		return nil, fmt.Errorf("error while executing keyfunc. %w %w", err, jwt.ErrTokenKeyFuncUnknown)
+	}
+
+	signingBytes := m.SigningBytes(s)
+	candidates := []any{keys}
+	if publicKeys, ok := keys.([]crypto.PublicKey); ok {
+		if len(publicKeys) == 0 {
+			return nil, fmt.Errorf("keyfunc returned no keys. %w", jwt.ErrTokenKeyFuncUnknown)
+		}
+		candidates = candidates[:0]
+		for _, k := range publicKeys {
+			candidates = append(candidates, k)
+		}
+	}
+	// 與parser相同，key與演算法不相符時不會進行驗證，全部都不相符時才報錯
+	var bound []any
+	for _, key := range candidates {
+		var k any
+		if k, err = jwt.BindKey(method, key); err == nil {
+			bound = append(bound, k)
+		}
+	}
+	if len(bound) == 0 {
+		return nil, err
+	}
+	for _, key := range bound {
+		if err = jwt.VerifyContext(ctx, method, signingBytes, s.Signature, key); err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %w", err, jwt.ErrTokenSignatureInvalid)
+}
+
+// UnmarshalClaims 將payload解析成claims，請在驗證簽名之後再使用
+func (m *Message) UnmarshalClaims(claims jwt.IClaims) error {
+	if err := json.Unmarshal(m.Payload, claims); err != nil {
+		return fmt.Errorf("could not parse claims %w. %w", err, jwt.ErrTokenMalformed)
+	}
+	return nil
+}
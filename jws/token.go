@@ -0,0 +1,140 @@
+package jws
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+)
+
+// Token JWS JSON Serialization https://datatracker.ietf.org/doc/html/rfc7515#section-7.2
+//
+// 與 jwt.Token 不同的地方在於同一份內容可以由多個簽名者各自加簽
+type Token struct {
+	Claims jwt.IClaims
+
+	// Payload 若不為nil則加簽此內容，而不使用Claims
+	Payload []byte
+
+	Signatures []*Signature
+}
+
+// Signature 一個簽名者的設定
+type Signature struct {
+	Protected map[string]any // 會被加簽的header，alg會放在此處
+	Header    map[string]any // 不會被加簽的header(unprotected)，可以為nil
+
+	SigningMethod jwt.ISigningMethod
+}
+
+func New(payload []byte) *Token {
+	return &Token{Payload: payload}
+}
+
+func NewWithClaims(claims jwt.IClaims) *Token {
+	return &Token{Claims: claims}
+}
+
+// AddSignature 新增一個簽名者，回傳的Signature可以再補上其他的header(例如: kid)
+func (t *Token) AddSignature(signingMethod jwt.ISigningMethod, header map[string]any) *Signature {
+	s := &Signature{
+		Protected:     map[string]any{"alg": signingMethod.AlgName()},
+		Header:        header,
+		SigningMethod: signingMethod,
+	}
+	t.Signatures = append(t.Signatures, s)
+	return s
+}
+
+// jsonSignature 輸出時每一個簽名的格式
+type jsonSignature struct {
+	Protected string         `json:"protected,omitempty"`
+	Header    map[string]any `json:"header,omitempty"`
+	Signature string         `json:"signature"`
+}
+
+// GeneralJSON General JWS JSON Serialization
+//
+//	{"payload": "...", "signatures": [{"protected": "...", "header": {...}, "signature": "..."}, ...]}
+//
+// keys 依序為每一個Signature所用的鑰匙
+func (t *Token) GeneralJSON(keys ...any) ([]byte, error) {
+	payload, signatures, err := t.sign(keys)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Payload    string          `json:"payload"`
+		Signatures []jsonSignature `json:"signatures"`
+	}{payload, signatures})
+}
+
+// FlattenedJSON Flattened JWS JSON Serialization，只能有一個簽名
+//
+//	{"payload": "...", "protected": "...", "header": {...}, "signature": "..."}
+func (t *Token) FlattenedJSON(key any) ([]byte, error) {
+	if len(t.Signatures) != 1 {
+		return nil, fmt.Errorf("flattened serialization requires exactly one signature, got %d", len(t.Signatures))
+	}
+	payload, signatures, err := t.sign([]any{key})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		Payload string `json:"payload"`
+		jsonSignature
+	}{payload, signatures[0]})
+}
+
+func (t *Token) sign(keys []any) (string, []jsonSignature, error) {
+	if len(t.Signatures) == 0 {
+		return "", nil, ErrNoSignature
+	}
+	if len(keys) != len(t.Signatures) {
+		return "", nil, fmt.Errorf("expected %d keys, got %d. %w", len(t.Signatures), len(keys), jwt.ErrInvalidKey)
+	}
+
+	payload := t.Payload
+	if payload == nil {
+		var err error
+		if payload, err = json.Marshal(t.Claims); err != nil {
+			return "", nil, err
+		}
+	}
+	encodedPayload := base64.RawURLEncoding.EncodeToString(payload)
+
+	signatures := make([]jsonSignature, len(t.Signatures))
+	for i, s := range t.Signatures {
+		if err := checkDisjoint(s.Protected, s.Header); err != nil {
+			return "", nil, err
+		}
+		var protected string
+		if len(s.Protected) > 0 {
+			bs, err := json.Marshal(s.Protected)
+			if err != nil {
+				return "", nil, err
+			}
+			protected = base64.RawURLEncoding.EncodeToString(bs)
+		}
+
+		signature, err := s.SigningMethod.Sign([]byte(protected+"."+encodedPayload), keys[i])
+		if err != nil {
+			return "", nil, fmt.Errorf("signatures[%d]: %w", i, err)
+		}
+		signatures[i] = jsonSignature{
+			Protected: protected,
+			Header:    s.Header,
+			Signature: base64.RawURLEncoding.EncodeToString(signature),
+		}
+	}
+	return encodedPayload, signatures, nil
+}
+
+func checkDisjoint(protected, header map[string]any) error {
+	for name := range header {
+		if _, exists := protected[name]; exists {
+			return fmt.Errorf("%q %w", name, ErrHeaderConflict)
+		}
+	}
+	return nil
+}
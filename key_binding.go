@@ -0,0 +1,75 @@
+package jwt
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt/ed448"
+	"github.com/CarsonSlovoka/jwt/secp256k1"
+)
+
+// IBoundKey 本身就有限制可用演算法的key，例如 *jwk.Key (其alg欄位)
+// KeyFunc若回傳此類型的key，會先透過 KeyFor 取得實際驗證所用的key
+type IBoundKey interface {
+	// KeyFor 若此key不能用在alg上，必須回傳 ErrKeyAlgorithmMismatch
+	KeyFor(alg string) (any, error)
+}
+
+// keyMatcher 只在特定Go版本才會編譯的演算法(例如ML-DSA)透過此介面讓 BindKey 檢查key
+type keyMatcher interface {
+	matchesKey(key any) bool
+}
+
+// BindKey 在驗證簽名之前，確認key的類型(以及曲線、參數集)與演算法相符，避免algorithm confusion
+// 例如: 把RSA的公鑰當成HMAC的secret，或者用P-384的key驗證ES256的token
+//
+// 回傳實際要給 ISigningMethod.Verify 的key；不相符時回傳 ErrKeyAlgorithmMismatch
+// 非本套件所提供的 ISigningMethod 不做類型的檢查
+func BindKey(method ISigningMethod, key any) (any, error) {
+	if k, ok := key.(IBoundKey); ok {
+		var err error
+		if key, err = k.KeyFor(method.AlgName()); err != nil {
+			return nil, err
+		}
+	}
+
+	alg := method.AlgName()
+	mismatch := func() (any, error) {
+		return nil, fmt.Errorf("alg: %q key: %T %w", alg, key, ErrKeyAlgorithmMismatch)
+	}
+
+	switch m := method.(type) {
+	case *SigningMethodHMAC:
+		secret, ok := key.([]byte)
+		// PEM格式的公鑰是公開的資料，絕對不可以當成HMAC的secret
+		if !ok || bytes.HasPrefix(bytes.TrimSpace(secret), []byte("-----BEGIN")) {
+			return mismatch()
+		}
+	case *SigningMethodRSA, *SigningMethodRSAPSS:
+		if _, ok := key.(*rsa.PublicKey); !ok {
+			return mismatch()
+		}
+	case *SigningMethodECDSA:
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok || !m.matchesCurve(publicKey.Curve) {
+			return mismatch()
+		}
+	case *SigningMethodSecp256k1:
+		if _, ok := key.(*secp256k1.PublicKey); !ok {
+			return mismatch()
+		}
+	case *SigningMethodED25519:
+		switch key.(type) {
+		case ed25519.PublicKey, ed448.PublicKey:
+		default:
+			return mismatch()
+		}
+	case keyMatcher:
+		if !m.matchesKey(key) {
+			return mismatch()
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,90 @@
+package jwt
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrKeyTooWeak key不符合 KeyPolicy 的要求，使其可以被兩種錯誤類型判別
+var ErrKeyTooWeak = fmt.Errorf("%w %w",
+	errors.New("key does not satisfy the key policy"),
+	ErrInvalidKey,
+)
+
+// KeyPolicy 鑰匙強度的最低要求，所有的 SigningMethod* 在Sign與Verify時都會依據其KeyPolicy欄位檢查
+// 沒有設定時使用 StrictKeyPolicy
+type KeyPolicy struct {
+	// HMACKeyAtLeastHashSize HMAC的key長度不可以小於hash的輸出長度，例如HS256至少要32 bytes
+	// https://datatracker.ietf.org/doc/html/rfc7518#section-3.2
+	HMACKeyAtLeastHashSize bool
+
+	// MinRSAKeySize RSA modulus的最小位元數，0表示不限制
+	// https://datatracker.ietf.org/doc/html/rfc7518#section-3.3
+	MinRSAKeySize int
+
+	// AllowedCurves 允許的橢圓曲線(crv名稱: P-256, P-384, P-521, secp256k1)，nil表示不限制
+	AllowedCurves []string
+}
+
+// strictKeyPolicy 不公開，避免預設的要求在執行期間被異動
+var strictKeyPolicy = KeyPolicy{
+	HMACKeyAtLeastHashSize: true,
+	MinRSAKeySize:          2048,
+	AllowedCurves:          []string{"P-256", "P-384", "P-521", "secp256k1"},
+}
+
+// StrictKeyPolicy 預設的要求，每次都回傳新的副本，可以在其上修改後再透過 WithKeyPolicy 設定
+func StrictKeyPolicy() *KeyPolicy {
+	p := strictKeyPolicy
+	p.AllowedCurves = slices.Clone(p.AllowedCurves)
+	return &p
+}
+
+// InsecureLegacyKeyPolicy 不做任何限制，只應該用在需要與舊系統互通的情況
+//
+//	hs256 := jwt.SigningMethodHMAC256.WithKeyPolicy(jwt.InsecureLegacyKeyPolicy())
+func InsecureLegacyKeyPolicy() *KeyPolicy {
+	return &KeyPolicy{}
+}
+
+// orStrict p為nil時使用 strictKeyPolicy
+func (p *KeyPolicy) orStrict() *KeyPolicy {
+	if p == nil {
+		return &strictKeyPolicy
+	}
+	return p
+}
+
+func (p *KeyPolicy) checkHMAC(m *SigningMethodHMAC, key []byte) error {
+	p = p.orStrict()
+	if p.HMACKeyAtLeastHashSize && len(key) < m.Hash.Size() {
+		return fmt.Errorf("%s key must be at least %d bytes, got %d. %w", m.Name, m.Hash.Size(), len(key), ErrKeyTooWeak)
+	}
+	return nil
+}
+
+func (p *KeyPolicy) checkRSA(name string, bitLen int) error {
+	p = p.orStrict()
+	if bitLen < p.MinRSAKeySize {
+		return fmt.Errorf("%s key must be at least %d bits, got %d. %w", name, p.MinRSAKeySize, bitLen, ErrKeyTooWeak)
+	}
+	return nil
+}
+
+func (p *KeyPolicy) checkCurve(name string, crv string) error {
+	p = p.orStrict()
+	if p.AllowedCurves != nil && !slices.Contains(p.AllowedCurves, crv) {
+		return fmt.Errorf("%s curve %q is not allowed. %w", name, crv, ErrKeyTooWeak)
+	}
+	return nil
+}
+
+// curveName 取得NIST曲線的crv名稱
+func curveName(curve elliptic.Curve) string {
+	if curve == nil {
+		return ""
+	}
+	return curve.Params().Name
+}
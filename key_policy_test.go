@@ -0,0 +1,72 @@
+package jwt_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/secp256k1"
+	"testing"
+)
+
+func TestKeyPolicy(t *testing.T) {
+	msg := []byte("hello")
+	shortHMACKey := []byte("short")
+	rsa1024, _ := rsa.GenerateKey(rand.Reader, 1024)
+	p224Key, _ := ecdsa.GenerateKey(elliptic.P224(), rand.Reader)
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	k1Key, _ := secp256k1.GenerateKey(rand.Reader)
+	es224 := &jwt.SigningMethodECDSA{Name: "ES224", Hash: jwt.SigningMethodECDSA256.Hash, KeyBitSize: 224}
+	legacy := jwt.InsecureLegacyKeyPolicy()
+
+	for i, tt := range []struct {
+		m       jwt.ISigningMethod // 預設(嚴格)的要求
+		legacy  jwt.ISigningMethod // 舊系統的互通
+		signKey any
+		pubKey  any
+	}{
+		{jwt.SigningMethodHMAC256, jwt.SigningMethodHMAC256.WithKeyPolicy(legacy), shortHMACKey, shortHMACKey},
+		{jwt.SigningMethodHMAC512, jwt.SigningMethodHMAC512.WithKeyPolicy(legacy), make([]byte, 32), make([]byte, 32)}, // HS512至少要64 bytes
+		{jwt.SigningMethodRSA256, jwt.SigningMethodRSA256.WithKeyPolicy(legacy), rsa1024, &rsa1024.PublicKey},
+		{jwt.SigningMethodRSAPSS256, jwt.SigningMethodRSAPSS256.WithKeyPolicy(legacy), rsa1024, &rsa1024.PublicKey},
+		{es224, es224.WithKeyPolicy(legacy), p224Key, &p224Key.PublicKey},
+	} {
+		if _, err := tt.m.Sign(msg, tt.signKey); !errors.Is(err, jwt.ErrKeyTooWeak) || !errors.Is(err, jwt.ErrInvalidKey) {
+			t.Fatal(i, err)
+		}
+		if err := tt.m.Verify(msg, nil, tt.pubKey); !errors.Is(err, jwt.ErrKeyTooWeak) {
+			t.Fatal(i, err)
+		}
+
+		signature, err := tt.legacy.Sign(msg, tt.signKey)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if err = tt.legacy.Verify(msg, signature, tt.pubKey); err != nil {
+			t.Fatal(i, err)
+		}
+	}
+
+	// WithKeyPolicy 不會影響原本的SigningMethod
+	if jwt.SigningMethodHMAC256.KeyPolicy != nil || jwt.SigningMethodRSAPSS256.KeyPolicy != nil {
+		t.Fatal("the shared signing methods should not be modified")
+	}
+
+	// 自定義: 只允許P-256
+	onlyP256 := jwt.StrictKeyPolicy()
+	onlyP256.AllowedCurves = []string{"P-256"}
+	if _, err := jwt.SigningMethodECDSA384.WithKeyPolicy(onlyP256).Sign(msg, p384Key); !errors.Is(err, jwt.ErrKeyTooWeak) {
+		t.Fatal(err)
+	}
+	if _, err := jwt.SigningMethodES256K.WithKeyPolicy(onlyP256).Sign(msg, k1Key); !errors.Is(err, jwt.ErrKeyTooWeak) {
+		t.Fatal(err)
+	}
+	if _, err := jwt.SigningMethodECDSA384.Sign(msg, p384Key); err != nil {
+		t.Fatal(err)
+	}
+	if len(jwt.StrictKeyPolicy().AllowedCurves) != 4 {
+		t.Fatal("StrictKeyPolicy should not be modified")
+	}
+}
@@ -0,0 +1,33 @@
+package kms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+)
+
+// 遠端KMS的錯誤分類，Client 的實作應該將錯誤包裝成以下其中一種，讓呼叫端可以決定是否重試
+var (
+	// ErrUnavailable 暫時性的錯誤(網路、逾時、限流、伺服器錯誤)，可以稍後重試
+	ErrUnavailable = errors.New("kms: service unavailable")
+
+	// ErrKeyNotFound 遠端沒有此key
+	ErrKeyNotFound = fmt.Errorf("%w %w",
+		errors.New("kms: key not found"),
+		jwt.ErrInvalidKey,
+	)
+
+	// ErrPermissionDenied 沒有使用此key的權限
+	ErrPermissionDenied = errors.New("kms: permission denied")
+
+	// ErrInvalidRequest 請求的內容不合法，例如key不支援此演算法
+	ErrInvalidRequest = errors.New("kms: invalid request")
+)
+
+// IsRetryable 此錯誤是否為暫時性的錯誤
+// ctx被取消或者已經超過期限的情況不算，因為重試也沒有意義
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrUnavailable) &&
+		!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,114 @@
+package kms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// maxResponseSize 避免遠端回傳過大的內容
+const maxResponseSize = 1 << 20
+
+// HTTPClient 以HTTP+JSON實作的 Client
+//
+//	POST {baseURL}/sign   {"keyId", "alg", "message"}              => {"signature"}
+//	POST {baseURL}/verify {"keyId", "alg", "message", "signature"} => {"valid"}
+//
+// 二進位的資料以base64(標準, 含padding)編碼，也就是encoding/json對[]byte的預設行為
+// 錯誤時回應 {"error": "..."}，並依據HTTP的狀態碼分類
+type HTTPClient struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPClient httpClient為nil時使用 http.DefaultClient
+func NewHTTPClient(baseURL string, httpClient *http.Client) *HTTPClient {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &HTTPClient{strings.TrimRight(baseURL, "/"), httpClient}
+}
+
+type signRequest struct {
+	KeyID     KeyID  `json:"keyId"`
+	Alg       string `json:"alg"`
+	Message   []byte `json:"message"`
+	Signature []byte `json:"signature,omitempty"`
+}
+
+type signResponse struct {
+	Signature []byte `json:"signature,omitempty"`
+	Valid     bool   `json:"valid,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Sign implements the Client interface
+func (c *HTTPClient) Sign(ctx context.Context, keyID KeyID, alg string, message []byte) ([]byte, error) {
+	resp, err := c.call(ctx, "/sign", &signRequest{KeyID: keyID, Alg: alg, Message: message})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Signature) == 0 {
+		return nil, fmt.Errorf("empty signature. %w", ErrUnavailable)
+	}
+	return resp.Signature, nil
+}
+
+// Verify implements the Client interface
+func (c *HTTPClient) Verify(ctx context.Context, keyID KeyID, alg string, message, signature []byte) (bool, error) {
+	resp, err := c.call(ctx, "/verify", &signRequest{KeyID: keyID, Alg: alg, Message: message, Signature: signature})
+	if err != nil {
+		return false, err
+	}
+	return resp.Valid, nil
+}
+
+func (c *HTTPClient) call(ctx context.Context, path string, req *signRequest) (*signResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w %w", err, ErrInvalidRequest)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		// ctx被取消或逾時的錯誤會被保留，可以用errors.Is(err, context.DeadlineExceeded)判斷
+		return nil, fmt.Errorf("%w %w", err, ErrUnavailable)
+	}
+	defer func() {
+		_ = httpResp.Body.Close()
+	}()
+
+	var resp signResponse
+	bs, err := io.ReadAll(io.LimitReader(httpResp.Body, maxResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("%w %w", err, ErrUnavailable)
+	}
+	if err = json.Unmarshal(bs, &resp); err != nil && httpResp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("invalid response: %w %w", err, ErrUnavailable)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %w", httpResp.Status, resp.Error, classifyStatus(httpResp.StatusCode))
+	}
+	return &resp, nil
+}
+
+// classifyStatus 將HTTP的狀態碼轉換成錯誤的分類
+func classifyStatus(code int) error {
+	switch {
+	case code == http.StatusNotFound:
+		return ErrKeyNotFound
+	case code == http.StatusUnauthorized, code == http.StatusForbidden:
+		return ErrPermissionDenied
+	case code == http.StatusTooManyRequests, code == http.StatusRequestTimeout, code >= 500:
+		return ErrUnavailable
+	}
+	return ErrInvalidRequest
+}
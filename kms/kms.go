@@ -0,0 +1,111 @@
+// Package kms 透過遠端的金鑰管理服務(KMS)加簽與驗證，私鑰不會離開KMS
+//
+// 傳輸的方式由 Client 決定，本套件提供以HTTP+JSON實作的 HTTPClient，也可以自行實作gRPC等其他的版本
+//
+//	method := kms.New("ES256", kms.NewHTTPClient("https://kms.example.com"))
+//	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+//	defer cancel()
+//	bsToken, err := jwt.New(method).SignedBytesContext(ctx, kms.KeyID("signing-key-1"))
+package kms
+
+import (
+	"context"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+)
+
+// KeyID 遠端key的識別碼，Sign, Verify的key必須是此型別
+type KeyID string
+
+// Client 遠端KMS的RPC介面
+// 加簽的對象是原始的signingBytes，雜湊由遠端依據alg處理
+//
+// 簽名必須是JWS的格式 https://datatracker.ietf.org/doc/html/rfc7518#section-3.4
+// ECDSA的簽名為固定長度的 r || s；若KMS使用的是ASN.1 DER(例如AWS KMS)，請設定 SigningMethod.ASN1
+type Client interface {
+	Sign(ctx context.Context, keyID KeyID, alg string, message []byte) (signature []byte, err error)
+	Verify(ctx context.Context, keyID KeyID, alg string, message, signature []byte) (valid bool, err error)
+}
+
+// SigningMethod 將簽名交給遠端的KMS處理
+// 它實作了 jwt.ISigningMethodContext，Token 與 parser.Parser 會把ctx傳下來
+type SigningMethod struct {
+	Name   string // alg
+	Client Client
+
+	// ASN1 Client的ECDSA簽名為ASN.1 DER的格式
+	// 加簽後會轉換成JWS的 r || s，驗證前會轉回DER再交給Client；非ECDSA的演算法不受影響
+	ASN1 bool
+}
+
+// ecdsaKeyBytes ECDSA的alg與 r, s 各自的位元組數
+var ecdsaKeyBytes = map[string]int{
+	"ES256":  32,
+	"ES384":  48,
+	"ES512":  66,
+	"ES256K": 32,
+}
+
+// New 建立使用遠端KMS的方法，alg必須是遠端所支援的演算法，例如: RS256, ES256
+func New(alg string, client Client) *SigningMethod {
+	return &SigningMethod{Name: alg, Client: client}
+}
+
+// AlgName implements the jwt.ISigningMethod interface
+func (m *SigningMethod) AlgName() string {
+	return m.Name
+}
+
+// Sign implements the jwt.ISigningMethod interface
+func (m *SigningMethod) Sign(signingBytes []byte, key any) ([]byte, error) {
+	return m.SignContext(context.Background(), signingBytes, key)
+}
+
+// Verify implements the jwt.ISigningMethod interface
+func (m *SigningMethod) Verify(signingBytes []byte, signature []byte, key any) error {
+	return m.VerifyContext(context.Background(), signingBytes, signature, key)
+}
+
+// SignContext implements the jwt.ISigningMethodContext interface
+func (m *SigningMethod) SignContext(ctx context.Context, signingBytes []byte, key any) ([]byte, error) {
+	keyID, ok := key.(KeyID)
+	if !ok {
+		return nil, fmt.Errorf("kms sign expects kms.KeyID. %w", jwt.ErrInvalidKeyType)
+	}
+	signature, err := m.Client.Sign(ctx, keyID, m.Name, signingBytes)
+	if err != nil {
+		return nil, fmt.Errorf("kms: sign with %q: %w", keyID, err)
+	}
+	if keyBytes, ok := ecdsaKeyBytes[m.Name]; ok && m.ASN1 {
+		if signature, err = jwt.ECDSASignatureFromASN1(signature, keyBytes); err != nil {
+			return nil, fmt.Errorf("kms: sign with %q: %w", keyID, err)
+		}
+	}
+	return signature, nil
+}
+
+// VerifyContext implements the jwt.ISigningMethodContext interface
+// 若已經有公鑰，也可以直接使用本地的 jwt.SigningMethod* 驗證，不需要經過KMS
+func (m *SigningMethod) VerifyContext(ctx context.Context, signingBytes []byte, signature []byte, key any) error {
+	keyID, ok := key.(KeyID)
+	if !ok {
+		return fmt.Errorf("kms verify expects kms.KeyID. %w", jwt.ErrInvalidKeyType)
+	}
+	if keyBytes, ok := ecdsaKeyBytes[m.Name]; ok && m.ASN1 {
+		if len(signature) != keyBytes<<1 {
+			return jwt.ErrSignatureInvalid
+		}
+		var err error
+		if signature, err = jwt.ECDSASignatureToASN1(signature); err != nil {
+			return err
+		}
+	}
+	valid, err := m.Client.Verify(ctx, keyID, m.Name, signingBytes, signature)
+	if err != nil {
+		return fmt.Errorf("kms: verify with %q: %w", keyID, err)
+	}
+	if !valid {
+		return jwt.ErrSignatureInvalid
+	}
+	return nil
+}
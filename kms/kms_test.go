@@ -0,0 +1,262 @@
+package kms_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/kms"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeKMS 在同一個process之中模擬遠端的KMS，私鑰只存在於server
+type fakeKMS struct {
+	keys     map[kms.KeyID]crypto.Signer
+	registry *jwt.Registry
+
+	mu         sync.Mutex
+	delay      time.Duration
+	failStatus int // 不為0時，下一次的請求會回傳此狀態碼
+}
+
+func newFakeKMS() *fakeKMS {
+	return &fakeKMS{keys: make(map[kms.KeyID]crypto.Signer), registry: jwt.NewDefaultRegistry()}
+}
+
+func (f *fakeKMS) set(delay time.Duration, failStatus int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delay, f.failStatus = delay, failStatus
+}
+
+func (f *fakeKMS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	delay, failStatus := f.delay, f.failStatus
+	f.failStatus = 0
+	f.mu.Unlock()
+
+	writeJSON := func(status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+	if failStatus != 0 {
+		writeJSON(failStatus, map[string]string{"error": http.StatusText(failStatus)})
+		return
+	}
+
+	var req struct {
+		KeyID     kms.KeyID `json:"keyId"`
+		Alg       string    `json:"alg"`
+		Message   []byte    `json:"message"`
+		Signature []byte    `json:"signature"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	key, ok := f.keys[req.KeyID]
+	if !ok {
+		writeJSON(http.StatusNotFound, map[string]string{"error": "key not found"})
+		return
+	}
+	method, err := f.registry.GetSigningMethod(req.Alg)
+	if err != nil {
+		writeJSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	switch r.URL.Path {
+	case "/sign":
+		signature, err := method.Sign(req.Message, key)
+		if err != nil {
+			writeJSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(http.StatusOK, map[string]any{"signature": signature})
+	case "/verify":
+		err = method.Verify(req.Message, req.Signature, key.Public())
+		writeJSON(http.StatusOK, map[string]any{"valid": err == nil})
+	default:
+		writeJSON(http.StatusNotFound, map[string]string{"error": "unknown method"})
+	}
+}
+
+func TestSigningMethod(t *testing.T) {
+	fake := newFakeKMS()
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	fake.keys["ec-1"] = ecKey
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	method := kms.New("ES256", kms.NewHTTPClient(server.URL, server.Client()))
+	ctx := context.Background()
+	token := jwt.New(method)
+	token.Claims = jwt.MapClaims{"sub": "user123"}
+	bsToken, err := token.SignedBytesContext(ctx, kms.KeyID("ec-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 透過KMS驗證
+	vdFunc, err := parser.New().ParseContext(ctx, string(bsToken), func(alg string) (jwt.ISigningMethod, error) {
+		return method, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+		return kms.KeyID("ec-1"), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// KMS產生的簽名與本地的ES256相同，可以直接用公鑰驗證
+	vdFunc, _ = parser.New().Parse(string(bsToken), func(alg string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodECDSA256, nil
+	})
+	if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+		return &ecKey.PublicKey, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 簽名不正確
+	if err = method.VerifyContext(ctx, []byte("another"), make([]byte, 64), kms.KeyID("ec-1")); !errors.Is(err, jwt.ErrSignatureInvalid) {
+		t.Fatal(err)
+	}
+	if _, err = method.Sign([]byte("hello"), "ec-1"); !errors.Is(err, jwt.ErrInvalidKeyType) {
+		t.Fatal(err)
+	}
+}
+
+// derClient 模擬回傳ASN.1 DER格式ECDSA簽名的KMS(例如AWS KMS)
+type derClient struct {
+	key *ecdsa.PrivateKey
+}
+
+// Sign implements the kms.Client interface
+func (c *derClient) Sign(_ context.Context, _ kms.KeyID, _ string, message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	return ecdsa.SignASN1(rand.Reader, c.key, digest[:])
+}
+
+// Verify implements the kms.Client interface
+func (c *derClient) Verify(_ context.Context, _ kms.KeyID, _ string, message, signature []byte) (bool, error) {
+	digest := sha256.Sum256(message)
+	return ecdsa.VerifyASN1(&c.key.PublicKey, digest[:], signature), nil
+}
+
+func TestSigningMethod_asn1(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	client := &derClient{ecKey}
+	method := kms.New("ES256", client)
+	method.ASN1 = true
+
+	bsToken, err := jwt.New(method).SignedBytes(kms.KeyID("ec-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range []jwt.ISigningMethod{method, jwt.SigningMethodECDSA256} {
+		vdFunc, err := parser.New().Parse(string(bsToken), func(alg string) (jwt.ISigningMethod, error) {
+			return m, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+			if m == jwt.SigningMethodECDSA256 {
+				return &ecKey.PublicKey, nil
+			}
+			return kms.KeyID("ec-1"), nil
+		}); err != nil {
+			t.Fatal(m.AlgName(), err)
+		}
+	}
+
+	signature, _ := method.Sign([]byte("hello"), kms.KeyID("ec-1"))
+	if len(signature) != 64 {
+		t.Fatal(len(signature))
+	}
+	if err = method.Verify([]byte("hello"), signature, kms.KeyID("ec-1")); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		msg       string
+		signature []byte
+	}{
+		{"another", signature},
+		{"hello", signature[1:]},
+		{"hello", make([]byte, 64)},
+	} {
+		if err = method.Verify([]byte(tt.msg), tt.signature, kms.KeyID("ec-1")); !errors.Is(err, jwt.ErrSignatureInvalid) {
+			t.Fatal(err)
+		}
+	}
+
+	// 沒有設定ASN1時，DER的簽名不是JWS的格式，無法用本地的ES256驗證
+	der, _ := kms.New("ES256", client).Sign([]byte("hello"), kms.KeyID("ec-1"))
+	if err = jwt.SigningMethodECDSA256.Verify([]byte("hello"), der, &ecKey.PublicKey); !errors.Is(err, jwt.ErrSignatureInvalid) {
+		t.Fatal(err)
+	}
+}
+
+func TestSigningMethod_errors(t *testing.T) {
+	fake := newFakeKMS()
+	fake.keys["ec-1"], _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	method := kms.New("ES256", kms.NewHTTPClient(server.URL, server.Client()))
+
+	for _, tt := range []struct {
+		failStatus int
+		keyID      kms.KeyID
+		expect     error
+		retryable  bool
+	}{
+		{http.StatusServiceUnavailable, "ec-1", kms.ErrUnavailable, true},
+		{http.StatusTooManyRequests, "ec-1", kms.ErrUnavailable, true},
+		{http.StatusForbidden, "ec-1", kms.ErrPermissionDenied, false},
+		{0, "unknown", kms.ErrKeyNotFound, false},
+	} {
+		fake.set(0, tt.failStatus)
+		_, err := jwt.New(method).SignedBytesContext(context.Background(), tt.keyID)
+		if !errors.Is(err, tt.expect) || kms.IsRetryable(err) != tt.retryable {
+			t.Fatal(tt.failStatus, err)
+		}
+	}
+	if _, err := kms.New("HS999", method.Client).Sign([]byte("hello"), kms.KeyID("ec-1")); !errors.Is(err, kms.ErrInvalidRequest) {
+		t.Fatal(err)
+	}
+
+	// 超過期限
+	fake.set(200*time.Millisecond, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := jwt.New(method).SignedBytesContext(ctx, kms.KeyID("ec-1"))
+	if !errors.Is(err, context.DeadlineExceeded) || kms.IsRetryable(err) {
+		t.Fatal(err)
+	}
+
+	// 沒有實作 jwt.ISigningMethodContext 的方法，ctx結束後也不會再加簽
+	canceled, cancel2 := context.WithCancel(context.Background())
+	cancel2()
+	if _, err = jwt.New(jwt.SigningMethodHMAC256).SignedBytesContext(canceled, make([]byte, 32)); !errors.Is(err, context.Canceled) {
+		t.Fatal(err)
+	}
+}
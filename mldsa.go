@@ -0,0 +1,97 @@
+//go:build go1.27
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/mldsa"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// ErrMLDSAVerification 使其可以被兩種錯誤類型判別
+var ErrMLDSAVerification = fmt.Errorf("%w %w",
+	errors.New("mldsa: verification error"),
+	ErrSignatureInvalid,
+)
+
+// SigningMethodMLDSA 後量子的簽名演算法ML-DSA (FIPS 204)
+// https://datatracker.ietf.org/doc/draft-ietf-cose-dilithium/
+//
+// JOSE所使用的是pure ML-DSA，context為空，不會先對signingBytes做雜湊
+// crypto/mldsa 在Go 1.27才加入標準庫，為了不讓整個module都要求Go 1.27，此檔案只在Go 1.27以上才會被編譯
+type SigningMethodMLDSA struct {
+	Name   string
+	Params mldsa.Parameters
+}
+
+var (
+	SigningMethodMLDSA44 *SigningMethodMLDSA
+	SigningMethodMLDSA65 *SigningMethodMLDSA
+	SigningMethodMLDSA87 *SigningMethodMLDSA
+)
+
+func init() {
+	SigningMethodMLDSA44 = &SigningMethodMLDSA{"ML-DSA-44", mldsa.MLDSA44()}
+	SigningMethodMLDSA65 = &SigningMethodMLDSA{"ML-DSA-65", mldsa.MLDSA65()}
+	SigningMethodMLDSA87 = &SigningMethodMLDSA{"ML-DSA-87", mldsa.MLDSA87()}
+	extraDefaultMethods = append(extraDefaultMethods, SigningMethodMLDSA44, SigningMethodMLDSA65, SigningMethodMLDSA87)
+}
+
+// AlgName implements the ISigningMethod interface
+func (m *SigningMethodMLDSA) AlgName() string {
+	return m.Name
+}
+
+// GenerateKey 產生此參數集的私鑰
+func (m *SigningMethodMLDSA) GenerateKey() (*mldsa.PrivateKey, error) {
+	return mldsa.GenerateKey(m.Params)
+}
+
+// matchesKey implements the keyMatcher interface
+func (m *SigningMethodMLDSA) matchesKey(key any) bool {
+	publicKey, ok := key.(*mldsa.PublicKey)
+	return ok && publicKey.Parameters() == m.Params
+}
+
+// Sign implements the ISigningMethod interface
+// key可以是 *mldsa.PrivateKey 或者任何公鑰為 *mldsa.PublicKey 的 crypto.Signer
+func (m *SigningMethodMLDSA) Sign(signingBytes []byte, key any) ([]byte, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s sign expects crypto.Signer with *mldsa.PublicKey. %w", m.Name, ErrInvalidKeyType)
+	}
+	publicKey, ok := signer.Public().(*mldsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s sign expects crypto.Signer with *mldsa.PublicKey. %w", m.Name, ErrInvalidKeyType)
+	}
+	// 參數集不同的key不能混用，例如ML-DSA-44的key不能產生ML-DSA-65的簽名
+	if publicKey.Parameters() != m.Params {
+		return nil, fmt.Errorf("%s sign expects a %s key, got %s. %w",
+			m.Name, m.Params, publicKey.Parameters(), ErrInvalidKey,
+		)
+	}
+	// pure ML-DSA，直接對signingBytes加簽
+	return signer.Sign(rand.Reader, signingBytes, crypto.Hash(0))
+}
+
+// Verify implements the ISigningMethod interface
+func (m *SigningMethodMLDSA) Verify(signingBytes []byte, signature []byte, key any) error {
+	publicKey, ok := key.(*mldsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%s verify expects *mldsa.PublicKey. %w", m.Name, ErrInvalidKeyType)
+	}
+	if publicKey.Parameters() != m.Params {
+		return fmt.Errorf("%s verify expects a %s key, got %s. %w",
+			m.Name, m.Params, publicKey.Parameters(), ErrInvalidKey,
+		)
+	}
+	if len(signature) != m.Params.SignatureSize() {
+		return ErrMLDSAVerification
+	}
+	if err := mldsa.Verify(publicKey, signingBytes, signature, nil); err != nil {
+		return ErrMLDSAVerification
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+//go:build go1.27
+
+package jwt_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/mldsa"
+	"crypto/sha256"
+	"crypto/sha3"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"strings"
+	"testing"
+)
+
+func init() {
+	defaultAlgorithms = append(defaultAlgorithms, "ML-DSA-44", "ML-DSA-65", "ML-DSA-87")
+	hsmMechanism = func(pub crypto.PublicKey) (string, bool) {
+		_, ok := pub.(*mldsa.PublicKey)
+		return "CKM_ML_DSA", ok
+	}
+}
+
+// NIST ACVP ML-DSA-keyGen-FIPS204 的測試向量，由seed產生的公鑰以sha256比對
+func TestSigningMethodMLDSA_keyGen(t *testing.T) {
+	for _, tt := range []struct {
+		m          *jwt.SigningMethodMLDSA
+		seed       string
+		pubSHA256  string
+		publicSize int
+	}{
+		{jwt.SigningMethodMLDSA44, "93EF2E6EF1FB08999D142ABE0295482370D3F43BDB254A78E2B0D5168ECA065F",
+			"6995b20ecd5cde41719035028a712ccf35b1adf53b913030423d9d6fa188d673", 1312},
+		{jwt.SigningMethodMLDSA65, "70CEFB9AED5B68E018B079DA8284B9D5CAD5499ED9C265FF73588005D85C225C",
+			"646b26b8d09dbc9e865b6a006c693a3127b065e62fab5fbe8b159c416462feb6", 1952},
+		{jwt.SigningMethodMLDSA87, "38359FBCD79582CFFE609E137EE2EFE8A8DBCBAD18BA92BB433AB4F09B49299D",
+			"ea374a09356e5f89be784f28f4ef938e8976cb5c4db00fbacb257663491748d4", 2592},
+	} {
+		seed, _ := hex.DecodeString(tt.seed)
+		privateKey, err := mldsa.NewPrivateKey(tt.m.Params, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub := privateKey.PublicKey().Bytes()
+		if len(pub) != tt.publicSize {
+			t.Fatal(tt.m.Name, len(pub))
+		}
+		if sum := sha256.Sum256(pub); hex.EncodeToString(sum[:]) != tt.pubSHA256 {
+			t.Fatal(tt.m.Name, hex.EncodeToString(sum[:]))
+		}
+	}
+}
+
+func TestSigningMethodMLDSA(t *testing.T) {
+	for _, m := range []*jwt.SigningMethodMLDSA{
+		jwt.SigningMethodMLDSA44,
+		jwt.SigningMethodMLDSA65,
+		jwt.SigningMethodMLDSA87,
+	} {
+		privateKey, err := m.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		token := jwt.New(m)
+		token.Claims = jwt.MapClaims{"sub": "1234567890"}
+		bsToken, err := token.SignedBytes(privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vdFunc, err := parser.New().Parse(string(bsToken), func(method string) (jwt.ISigningMethod, error) {
+			if method != m.AlgName() {
+				t.Fatal(method)
+			}
+			return m, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+			return privateKey.PublicKey(), nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		msg := []byte("hello")
+		signature, _ := m.Sign(msg, privateKey)
+		if len(signature) != m.Params.SignatureSize() {
+			t.Fatal(len(signature))
+		}
+		if err = m.Verify([]byte("hello!"), signature, privateKey.PublicKey()); !errors.Is(err, jwt.ErrMLDSAVerification) {
+			t.Fatal(err)
+		}
+		if err = m.Verify(msg, signature[1:], privateKey.PublicKey()); !errors.Is(err, jwt.ErrSignatureInvalid) {
+			t.Fatal(err)
+		}
+		if err = m.Verify(msg, signature, privateKey); !errors.Is(err, jwt.ErrInvalidKeyType) {
+			t.Fatal(err)
+		}
+	}
+
+	// 參數集不同的key不能混用
+	key44, _ := jwt.SigningMethodMLDSA44.GenerateKey()
+	if _, err := jwt.SigningMethodMLDSA65.Sign([]byte("hello"), key44); !errors.Is(err, jwt.ErrInvalidKey) {
+		t.Fatal(err)
+	}
+	signature, _ := jwt.SigningMethodMLDSA44.Sign([]byte("hello"), key44)
+	if err := jwt.SigningMethodMLDSA65.Verify([]byte("hello"), signature, key44.PublicKey()); !errors.Is(err, jwt.ErrInvalidKey) {
+		t.Fatal(err)
+	}
+}
+
+// NIST ACVP ML-DSA-sigGen-FIPS204 (Sign_internal, deterministic) 的測試向量，簽名以sha256比對
+// https://pages.nist.gov/ACVP/draft-celi-acvp-ml-dsa.html#table-1
+//
+// 向量所提供的是μ，只能透過external μ加簽，因此以此確認key與簽名的計算符合規範
+func TestSigningMethodMLDSA_sigGen(t *testing.T) {
+	for _, tt := range []struct {
+		m         *jwt.SigningMethodMLDSA
+		seed      string
+		mu        string
+		sigSHA256 string
+	}{
+		{jwt.SigningMethodMLDSA44, "5C624FCC1862452452D0C665840D8237F43108E5499EDCDC108FBC49D596E4B7",
+			"2ad1c72bb0fcbe28099ce8bd2ed836dfebe520aad38fbac66ef785a3cfb10fb419327fa57818ee4e3718da4be48d24b59a208f8807271fdb7eda6e60141bd263",
+			"dcc71a421bc6ffafb7df0c7f6d018a19ada154d1e2ee360ed533cecd5dc980ad"},
+		{jwt.SigningMethodMLDSA65, "F215BA2280D86F142012FC05FFC04F2C7D22FF5DD7D69AA0EFB081E3A53E9318",
+			"35cdb7dddbed44af4641bac659f46598ed769ea9693fd4ed2152b84c45811d2e66eded1eb20cde1c1f4b82642a330d8e86ac432a2aefaa56cd9b2b5f4affd450",
+			"c027d21b21fa75abe7f35cd84a54e2e83bd352140bc8c49eab2c45004e7268a7"},
+	} {
+		seed, _ := hex.DecodeString(tt.seed)
+		mu, _ := hex.DecodeString(tt.mu)
+		privateKey, err := mldsa.NewPrivateKey(tt.m.Params, seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature, err := privateKey.SignDeterministic(mu, crypto.MLDSAMu)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(signature) != tt.m.Params.SignatureSize() {
+			t.Fatal(tt.m.Name, len(signature))
+		}
+		if sum := sha256.Sum256(signature); hex.EncodeToString(sum[:]) != tt.sigSHA256 {
+			t.Fatal(tt.m.Name, hex.EncodeToString(sum[:]))
+		}
+	}
+}
+
+// mldsaMu FIPS 204 Algorithm 2 與 Algorithm 7 的μ
+//
+//	tr = H(pk, 64)
+//	M' = 0x00 || len(ctx) || ctx || M
+//	μ  = H(tr || M', 64)
+func mldsaMu(publicKey *mldsa.PublicKey, domain byte, ctx string, msg []byte) []byte {
+	tr := sha3.SumSHAKE256(publicKey.Bytes(), 64)
+	h := sha3.NewSHAKE256()
+	_, _ = h.Write(tr)
+	_, _ = h.Write([]byte{domain, byte(len(ctx))})
+	_, _ = h.Write([]byte(ctx))
+	_, _ = h.Write(msg)
+	mu := make([]byte, 64)
+	_, _ = h.Read(mu)
+	return mu
+}
+
+// 依照規範自行計算μ並加簽，確認 Verify 使用的是pure ML-DSA且context為空
+func TestSigningMethodMLDSA_pure(t *testing.T) {
+	for _, m := range []*jwt.SigningMethodMLDSA{
+		jwt.SigningMethodMLDSA44,
+		jwt.SigningMethodMLDSA65,
+		jwt.SigningMethodMLDSA87,
+	} {
+		privateKey, _ := m.GenerateKey()
+		publicKey := privateKey.PublicKey()
+		msg := []byte("eyJhbGciOiJNTC1EU0EtNDQifQ.eyJzdWIiOiIxMjM0NTY3ODkwIn0")
+
+		signature, err := privateKey.SignDeterministic(mldsaMu(publicKey, 0, "", msg), crypto.MLDSAMu)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = m.Verify(msg, signature, publicKey); err != nil {
+			t.Fatal(m.Name, err)
+		}
+		// 與直接對訊息做deterministic的加簽結果相同
+		if expected, _ := privateKey.SignDeterministic(msg, nil); !bytes.Equal(signature, expected) {
+			t.Fatal(m.Name, "pure ML-DSA signature mismatch")
+		}
+
+		// context不為空、HashML-DSA(domain為1)的簽名都不能通過
+		for _, mu := range [][]byte{
+			mldsaMu(publicKey, 0, "jwt", msg),
+			mldsaMu(publicKey, 1, "", msg),
+		} {
+			signature, _ = privateKey.SignDeterministic(mu, crypto.MLDSAMu)
+			if err = m.Verify(msg, signature, publicKey); !errors.Is(err, jwt.ErrMLDSAVerification) {
+				t.Fatal(m.Name, err)
+			}
+		}
+	}
+}
+
+func TestSigningMethodMLDSA_roundTrip(t *testing.T) {
+	registry := jwt.NewDefaultRegistry()
+	for _, m := range []*jwt.SigningMethodMLDSA{
+		jwt.SigningMethodMLDSA44,
+		jwt.SigningMethodMLDSA65,
+		jwt.SigningMethodMLDSA87,
+	} {
+		privateKey, _ := m.GenerateKey()
+		otherKey, _ := m.GenerateKey()
+		keyFunc := func(token *jwt.Token) (any, error) {
+			return privateKey.PublicKey(), nil
+		}
+
+		token := jwt.New(m)
+		token.Claims = jwt.MapClaims{"sub": "1234567890"}
+		bsToken, err := token.SignedBytes(privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokenStr := string(bsToken)
+		verified, err := parser.New().ParseVerified(context.Background(), tokenStr, registry.GetSigningMethod, nil, keyFunc)
+		if err != nil {
+			t.Fatal(m.Name, err)
+		}
+		if !verified.Valid || verified.SigningMethod != m {
+			t.Fatal(m.Name, verified)
+		}
+
+		// 每次加簽都是隨機的，但都必須能通過驗證
+		if bsToken2, _ := token.SignedBytes(privateKey); string(bsToken2) == tokenStr {
+			t.Fatal(m.Name, "expected a hedged signature")
+		} else if _, err = parser.New().ParseVerified(context.Background(), string(bsToken2), registry.GetSigningMethod, nil, keyFunc); err != nil {
+			t.Fatal(m.Name, err)
+		}
+
+		segments := strings.Split(tokenStr, ".")
+		signature, _ := base64.RawURLEncoding.DecodeString(segments[2])
+		signature[0] ^= 1
+		otherToken, _ := token.SignedBytes(otherKey)
+		for i, tampered := range []string{
+			segments[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"0987654321"}`)) + "." + segments[2],
+			segments[0] + "." + segments[1] + "." + base64.RawURLEncoding.EncodeToString(signature),
+			string(otherToken),
+		} {
+			if _, err = parser.New().ParseVerified(context.Background(), tampered, registry.GetSigningMethod, nil, keyFunc); !errors.Is(err, jwt.ErrSignatureInvalid) {
+				t.Fatal(m.Name, i, err)
+			}
+		}
+	}
+}
+
+func TestSigningMethodMLDSA_cryptoSigner(t *testing.T) {
+	hsm := newMockHSM()
+	hsm.objects["ml-dsa-44"], _ = mldsa.GenerateKey(mldsa.MLDSA44())
+	signer := hsm.signer("ml-dsa-44")
+	bsToken, err := jwt.New(jwt.SigningMethodMLDSA44).SignedBytes(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hsm.mechanisms) != 1 || hsm.mechanisms[0] != "CKM_ML_DSA" {
+		t.Fatal(hsm.mechanisms)
+	}
+	vdFunc, err := parser.New().Parse(string(bsToken), func(method string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodMLDSA44, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+		return signer.Public(), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,44 @@
+package jwt
+
+// SigningMethodNone alg為none，代表token沒有簽名 https://datatracker.ietf.org/doc/html/rfc7518#section-3.6
+//
+// 沒有簽名的token任何人都可以偽造，因此
+//   - Sign, Verify的key必須是 UnsafeAllowNone
+//   - Registry.Register 不接受此方法，必須透過 Registry.RegisterUnsafe 註冊
+type SigningMethodNone struct{}
+
+type unsafeNoneMarker struct{}
+
+// UnsafeAllowNone 明確表示您了解none的風險，並且仍然要使用它
+var UnsafeAllowNone = unsafeNoneMarker{}
+
+var SigningMethodUnsafeNone *SigningMethodNone
+
+func init() {
+	SigningMethodUnsafeNone = &SigningMethodNone{}
+}
+
+// AlgName implements the ISigningMethod interface
+func (m *SigningMethodNone) AlgName() string {
+	return "none"
+}
+
+// Sign implements the ISigningMethod interface
+// 簽名為空，token的格式為 header.payload.
+func (m *SigningMethodNone) Sign(_ []byte, key any) ([]byte, error) {
+	if _, ok := key.(unsafeNoneMarker); !ok {
+		return nil, ErrAlgorithmUnsafe
+	}
+	return []byte{}, nil
+}
+
+// Verify implements the ISigningMethod interface
+func (m *SigningMethodNone) Verify(_ []byte, signature []byte, key any) error {
+	if _, ok := key.(unsafeNoneMarker); !ok {
+		return ErrAlgorithmUnsafe
+	}
+	if len(signature) != 0 {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
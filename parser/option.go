@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"github.com/CarsonSlovoka/jwt"
+	"slices"
+)
+
+// Option 設定Parser本身的選項(claims的驗證請使用 validator.Option)，請透過 Parser.With 套用
+type Option func(*Parser)
+
+// builtinCriticalHeaders parser本身已經支援的擴充header
+var builtinCriticalHeaders = []string{
+	"b64", // https://datatracker.ietf.org/doc/html/rfc7797#section-3
+}
+
+// WithCriticalHeaders 設定應用程式所理解的crit擴充header名稱
+// 當token的crit列出了不在此名單內的名稱時，會回傳 jwt.ErrTokenInvalidCritical
+// 注意: parser只確認這些header存在，其內容的處理需要由您在vdHeader之中實作
+func WithCriticalHeaders(names ...string) Option {
+	return func(p *Parser) {
+		p.criticalHeaders = append(p.criticalHeaders, names...)
+	}
+}
+
+// WithHeaderValidator 設定header的驗證，會在 ParseVerified 以及vdFunc之中執行(早於vdHeader)
+func WithHeaderValidator(validateHeader func(header map[string]any) error) Option {
+	return func(p *Parser) {
+		p.validateHeader = validateHeader
+	}
+}
+
+// WithClaimsValidator 設定自定義claims的驗證，會在簽名驗證通過之後執行(早於vdCustomClaims)
+func WithClaimsValidator(validateClaims func(jwt.IClaims) error) Option {
+	return func(p *Parser) {
+		p.validateClaims = validateClaims
+	}
+}
+
+// WithAllowedTypes 設定可接受的typ(取代預設的JWT)，例如: WithAllowedTypes("at+jwt")
+// 比對時不分大小寫，且"application/at+jwt"與"at+jwt"視為相同
+// 不在名單內的typ會回傳 jwt.ErrTokenInvalidType (同時也是 jwt.ErrTokenMalformed)
+//
+// 不同用途的token使用不同的typ，可以避免token被拿去其他的地方使用 https://datatracker.ietf.org/doc/html/rfc8725#section-3.11
+func WithAllowedTypes(types ...string) Option {
+	return func(p *Parser) {
+		p.allowedTypes = slices.Clone(types)
+	}
+}
+
+// WithRequiredType header一定要有typ，否則回傳 jwt.ErrTokenInvalidType (同時也是 jwt.ErrTokenMalformed)
+// 預設沒有typ的token也會被接受 https://datatracker.ietf.org/doc/html/rfc7519#section-5.1
+func WithRequiredType() Option {
+	return func(p *Parser) {
+		p.requireType = true
+	}
+}
+
+// WithStrictDecoding header與claims改用嚴格的解碼，以下的情況都會被視為格式錯誤
+//   - JSON有重複的名稱: ErrDuplicateMember (預設會採用最後一個值)
+//   - JSON不是合法的UTF-8: ErrInvalidUTF8
+//   - JSON的值之後還有其他的資料: ErrTrailingData
+//   - base64url有padding、換行或者未使用的位元不為0: ErrNonCanonicalBase64
+//
+// https://datatracker.ietf.org/doc/html/rfc7515#section-5.2
+// https://datatracker.ietf.org/doc/html/rfc8725#section-3.7
+func WithStrictDecoding() Option {
+	return func(p *Parser) {
+		p.strict = true
+	}
+}
@@ -1,18 +1,34 @@
 package parser
 
 import (
+	"context"
 	"crypto"
 	"encoding/base64"
-	"encoding/json"
 	"fmt"
 	"github.com/CarsonSlovoka/jwt"
 	"github.com/CarsonSlovoka/jwt/validator"
+	"slices"
 	"strings"
 )
 
 type Parser struct {
 	// validator 由於Validator的欄位都公開，不希望Parser生成完畢還可以被異動，所以改用小寫字段
 	validator *validator.Validator
+
+	// criticalHeaders 應用程式所理解的crit擴充header名稱，請透過 WithCriticalHeaders 設定
+	criticalHeaders []string
+
+	// allowedTypes 可接受的typ，nil表示只接受JWT，請透過 WithAllowedTypes 設定
+	allowedTypes []string
+	// requireType header是否一定要有typ，請透過 WithRequiredType 設定
+	requireType bool
+
+	// strict 嚴格的JSON與base64url解碼，請透過 WithStrictDecoding 設定
+	strict bool
+
+	// validateHeader, validateClaims 請透過 WithHeaderValidator, WithClaimsValidator 設定
+	validateHeader func(header map[string]any) error
+	validateClaims func(jwt.IClaims) error
 }
 
 // New 建立一個對象，只對驗證的內容做設定
@@ -32,6 +48,17 @@ func New(options ...validator.Option) *Parser {
 	return p
 }
 
+// With 回傳套用options之後的新Parser，原本的Parser不會被異動
+func (p *Parser) With(options ...Option) *Parser {
+	clone := *p
+	clone.criticalHeaders = slices.Clone(p.criticalHeaders)
+	clone.allowedTypes = slices.Clone(p.allowedTypes)
+	for _, option := range options {
+		option(&clone)
+	}
+	return &clone
+}
+
 // Parse 細節請參考 ParseWithClaims
 func (p *Parser) Parse(
 	tokenStr string,
@@ -47,8 +74,25 @@ func (p *Parser) Parse(
 	return p.ParseWithClaims(tokenStr, getSigningMethod, nil)
 }
 
+// ParseContext 與 Parse 相同，ctx會傳給 jwt.ISigningMethodContext (例如遠端KMS的驗證)
+func (p *Parser) ParseContext(
+	ctx context.Context,
+	tokenStr string,
+	getSigningMethod func(method string) (jwt.ISigningMethod, error),
+) (
+	vdFunc func(
+		vdHeader func(header map[string]any) error,
+		vdCustomClaims func(jwt.IClaims) error,
+		kf jwt.KeyFunc,
+	) error,
+	err error,
+) {
+	return p.ParseWithClaimsContext(ctx, tokenStr, getSigningMethod, nil)
+}
+
 // ParseWithClaims 其完成時，只是將傳入的jwt字串轉換成為jwt.Token對象
 // 至於後面的驗證，需要自定義，請參考 Parser.validate
+// 若不需要分成兩個階段，請直接使用 ParseVerified
 func (p *Parser) ParseWithClaims(
 	tokenStr string,
 	getSigningMethod func(method string) (jwt.ISigningMethod, error), // 自定義您server所提供的方法
@@ -61,135 +105,432 @@ func (p *Parser) ParseWithClaims(
 	) error,
 	err error,
 ) {
-	parts := strings.Split(tokenStr, ".")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("token contains an invalid number of segments: %+v, %w", parts, jwt.ErrTokenMalformed)
+	return p.ParseWithClaimsContext(context.Background(), tokenStr, getSigningMethod, iClaims)
+}
+
+// ParseWithClaimsContext 與 ParseWithClaims 相同，驗證簽名時若SigningMethod有實作 jwt.ISigningMethodContext 則會使用ctx
+func (p *Parser) ParseWithClaimsContext(
+	ctx context.Context,
+	tokenStr string,
+	getSigningMethod func(method string) (jwt.ISigningMethod, error),
+	iClaims jwt.IClaims,
+) (
+	vdFunc func(
+		vdHeader func(header map[string]any) error,
+		vdCustomClaims func(jwt.IClaims) error,
+		kf jwt.KeyFunc,
+	) error,
+	err error,
+) {
+	if iClaims == nil {
+		iClaims = &jwt.MapClaims{}
 	}
-	// header
-	var header map[string]any
-	header, err = p.parseHeader(parts[0])
+	token, signingBytes, err := p.parse(tokenStr, false, nil, getSigningMethod, iClaims)
 	if err != nil {
 		return nil, err
 	}
-	token := &jwt.Token{Header: header}
-	token.SigningMethod, err = getSigningMethod(token.Header["alg"].(string))
+	return func(
+		validateHeader func(map[string]any) error,
+		validateCustomClaims func(jwt.IClaims) error,
+		keyFunc jwt.KeyFunc,
+	) error {
+		return p.validate(ctx, token, validateHeader, validateCustomClaims, signingBytes, keyFunc)
+	}, nil
+}
+
+// ParseVerified 一次完成解碼、header的檢查、取得鑰匙、驗證簽名以及claims的驗證
+// 成功時回傳的token其 Raw, RegisteredHeader, Signature 都已填入，且 Valid 為true；任何一個步驟失敗都只會回傳錯誤
+//
+// header與自定義claims的驗證請透過 WithHeaderValidator, WithClaimsValidator 設定
+// iClaims為nil時，預設使用 jwt.MapClaims
+func (p *Parser) ParseVerified(
+	ctx context.Context,
+	tokenStr string,
+	getSigningMethod func(method string) (jwt.ISigningMethod, error),
+	iClaims jwt.IClaims,
+	keyFunc jwt.KeyFunc,
+) (*jwt.Token, error) {
+	if iClaims == nil {
+		iClaims = &jwt.MapClaims{}
+	}
+	token, signingBytes, err := p.parse(tokenStr, false, nil, getSigningMethod, iClaims)
 	if err != nil {
 		return nil, err
 	}
+	if err = p.validate(ctx, token, nil, nil, signingBytes, keyFunc); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
 
-	// claims
+// ParseUnverified 只解碼header與claims，不會驗證簽名與claims，也不需要知道alg所對應的 jwt.ISigningMethod
+// 適用於在驗證之前先讀取iss, kid等資訊來決定後續要如何驗證，之後仍必須再透過 ParseVerified 等方法驗證
+//
+// header的格式(typ, alg, crit)仍然會被檢查
+// iClaims為nil時，預設使用 jwt.MapClaims
+func (p *Parser) ParseUnverified(tokenStr string, iClaims jwt.IClaims) (*jwt.UnverifiedToken, error) {
 	if iClaims == nil {
 		iClaims = &jwt.MapClaims{}
 	}
-	if err = p.parseClaims(parts[1], iClaims); err != nil {
+	token, _, err := p.parse(tokenStr, false, nil, nil, iClaims)
+	if err != nil {
 		return nil, err
 	}
-	token.Claims = iClaims
+	return &jwt.UnverifiedToken{
+		Raw:              token.Raw,
+		Header:           token.Header,
+		RegisteredHeader: token.RegisteredHeader,
+		Claims:           token.Claims,
+		Signature:        token.Signature,
+	}, nil
+}
 
-	// 這邊統一將signature解碼，不要在該演算法的Verify做這件事:
-	// 1. 演算法只是提供驗證，所以不應該假設signature有被URLDecode
-	// 2. 就算放在演算法裡寫，也要每一個演算法的Verify都要寫URLDecode相當麻煩
-	var signature []byte // 通常特徵也會用URLEncoding，所以也要還原回去，才是之前算出來的特徵(之前加簽出來的內容)
-	signature, err = base64.RawURLEncoding.DecodeString(parts[2])
+// ParseDetached 解析payload不在token之中的jwt(header..signature)，payload由其他的管道取得(例如webhook的body)
+// https://datatracker.ietf.org/doc/html/rfc7515#appendix-F
+//
+// payload不一定是claims，所以只會驗證header與簽名，不會進行claims的驗證
+// 若payload是claims，請使用 ParseDetachedWithClaims
+func (p *Parser) ParseDetached(
+	tokenStr string,
+	payload []byte,
+	getSigningMethod func(method string) (jwt.ISigningMethod, error),
+) (
+	vdFunc func(
+		vdHeader func(header map[string]any) error,
+		kf jwt.KeyFunc,
+	) error,
+	err error,
+) {
+	token, signingBytes, err := p.parse(tokenStr, true, payload, getSigningMethod, nil)
 	if err != nil {
-		return nil, fmt.Errorf("could not base64 decode signature %w", err)
+		return nil, err
 	}
+	return func(
+		validateHeader func(map[string]any) error,
+		keyFunc jwt.KeyFunc,
+	) error {
+		return p.validate(context.Background(), token, validateHeader, nil, signingBytes, keyFunc)
+	}, nil
+}
 
+// ParseDetachedWithClaims 與 ParseDetached 相同，但payload會被視為claims，並且和 ParseWithClaims 一樣進行驗證
+func (p *Parser) ParseDetachedWithClaims(
+	tokenStr string,
+	payload []byte,
+	getSigningMethod func(method string) (jwt.ISigningMethod, error),
+	iClaims jwt.IClaims,
+) (
+	vdFunc func(
+		vdHeader func(header map[string]any) error,
+		vdCustomClaims func(jwt.IClaims) error,
+		kf jwt.KeyFunc,
+	) error,
+	err error,
+) {
+	if iClaims == nil {
+		iClaims = &jwt.MapClaims{}
+	}
+	token, signingBytes, err := p.parse(tokenStr, true, payload, getSigningMethod, iClaims)
+	if err != nil {
+		return nil, err
+	}
 	return func(
 		validateHeader func(map[string]any) error,
 		validateCustomClaims func(jwt.IClaims) error,
 		keyFunc jwt.KeyFunc,
 	) error {
-		return p.validate(token, validateHeader, validateCustomClaims,
-			[]byte(strings.Join(parts[0:2], ".")), signature, keyFunc)
+		return p.validate(context.Background(), token, validateHeader, validateCustomClaims, signingBytes, keyFunc)
 	}, nil
 }
 
+// parse 將jwt字串拆解，並取得要被驗證的內容
+// detached為true時，token的payload區段必須為空，改用傳入的payload
+// iClaims為nil時，不解析claims；getSigningMethod為nil時，不取得SigningMethod(僅限 ParseUnverified 使用)
+func (p *Parser) parse(
+	tokenStr string,
+	detached bool, payload []byte,
+	getSigningMethod func(method string) (jwt.ISigningMethod, error),
+	iClaims jwt.IClaims,
+) (token *jwt.Token, signingBytes []byte, err error) {
+	parts := strings.Split(tokenStr, ".")
+	if len(parts) != 3 {
+		return nil, nil, fmt.Errorf("token contains an invalid number of segments: %+v, %w", parts, jwt.ErrTokenMalformed)
+	}
+	// header
+	var (
+		header     map[string]any
+		registered jwt.RegisteredHeader
+	)
+	header, registered, err = p.parseHeader(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	var b64 bool
+	if b64, err = isBase64Payload(header); err != nil {
+		return nil, nil, err
+	}
+	token = &jwt.Token{Header: header, Raw: tokenStr, RegisteredHeader: registered}
+	if getSigningMethod != nil {
+		token.SigningMethod, err = getSigningMethod(token.Header["alg"].(string))
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 要被加簽的內容: ASCII(BASE64URL(header)) || '.' || payload
+	// b64為false時payload不經過編碼 https://datatracker.ietf.org/doc/html/rfc7797#section-3
+	if detached {
+		if parts[1] != "" {
+			return nil, nil, fmt.Errorf("detached token must have an empty payload segment %w", jwt.ErrTokenMalformed)
+		}
+		signingBytes = append([]byte(parts[0]), '.')
+		if b64 {
+			signingBytes = base64.RawURLEncoding.AppendEncode(signingBytes, payload)
+		} else {
+			signingBytes = append(signingBytes, payload...)
+		}
+	} else {
+		signingBytes = []byte(strings.Join(parts[0:2], "."))
+		if b64 {
+			if payload, err = p.decodeSegment(parts[1]); err != nil {
+				return nil, nil, fmt.Errorf("could not base64 decode claim %w. %w", err, jwt.ErrTokenMalformed)
+			}
+		} else {
+			payload = []byte(parts[1])
+		}
+	}
+
+	// claims
+	if iClaims != nil {
+		if err = p.parseClaims(payload, iClaims); err != nil {
+			return nil, nil, err
+		}
+		token.Claims = iClaims
+	}
+
+	// 這邊統一將signature解碼，不要在該演算法的Verify做這件事:
+	// 1. 演算法只是提供驗證，所以不應該假設signature有被URLDecode
+	// 2. 就算放在演算法裡寫，也要每一個演算法的Verify都要寫URLDecode相當麻煩
+	// 通常特徵也會用URLEncoding，所以也要還原回去，才是之前算出來的特徵(之前加簽出來的內容)
+	token.Signature, err = p.decodeSegment(parts[2])
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not base64 decode signature %w", err)
+	}
+	return token, signingBytes, nil
+}
+
 func (p *Parser) validate(
+	ctx context.Context,
 	token *jwt.Token,
 	validateHeader func(map[string]any) error,
 	customValidate func(jwt.IClaims) error,
-	signingBytes []byte, signature []byte, keyFunc jwt.KeyFunc,
+	signingBytes []byte, keyFunc jwt.KeyFunc,
 ) error {
 
 	if keyFunc == nil {
 		return fmt.Errorf("error keyFunc is nil. %w", jwt.ErrInvalidKeyType)
 	}
 
-	if validateHeader != nil {
-		err := validateHeader(token.Header)
-		if err != nil {
+	// 先執行Parser所設定的，再執行呼叫時所傳入的
+	for _, vd := range []func(map[string]any) error{p.validateHeader, validateHeader} {
+		if vd == nil {
+			continue
+		}
+		if err := vd(token.Header); err != nil {
 			return err
 		}
 	}
 
-	if err := p.validator.Validate(token.Claims); err != nil {
-		return err
+	if token.Claims != nil { // detached的payload不一定是claims
+		if err := p.validator.Validate(token.Claims); err != nil {
+			return err
+		}
 	}
 
 	keys, err := keyFunc(token)
 	if err != nil {
+		// 保留keyFunc的錯誤，讓使用者可以區分是找不到鑰匙(例如: jwk.ErrKeyNotFound)還是簽名錯誤
 		return fmt.Errorf(
-			"error while executing keyfunc. %w",
-			jwt.ErrTokenKeyFuncUnknown,
+			"error while executing keyfunc. %w %w",
+			err, jwt.ErrTokenKeyFuncUnknown,
 		)
 	}
 
+	// 在驗證簽名之前，先確認key與演算法相符
+	var candidates []any
 	switch key := keys.(type) {
 	case []crypto.PublicKey:
-		// 如果有多把keys就一把一把驗證，如果有找到匹配的就離開
+		if len(key) == 0 { // 沒有任何一把鑰匙，不可以當作驗證通過
+			return fmt.Errorf("keyfunc returned no keys. %w", jwt.ErrTokenKeyFuncUnknown)
+		}
+		// 不相符的key直接略過，全部都不相符時才報錯
 		for _, k := range key {
-			if err = token.SigningMethod.Verify(signingBytes, signature, k); err == nil {
-				break
+			var bound any
+			if bound, err = jwt.BindKey(token.SigningMethod, k); err == nil {
+				candidates = append(candidates, bound)
 			}
 		}
+		if len(candidates) == 0 {
+			return err
+		}
 	default:
-		err = token.SigningMethod.Verify(signingBytes, signature, key)
+		bound, err := jwt.BindKey(token.SigningMethod, key)
+		if err != nil {
+			return err
+		}
+		candidates = append(candidates, bound)
+	}
+
+	// 如果有多把keys就一把一把驗證，如果有找到匹配的就離開
+	for _, k := range candidates {
+		if err = jwt.VerifyContext(ctx, token.SigningMethod, signingBytes, token.Signature, k); err == nil {
+			break
+		}
 	}
 
 	if err != nil {
-		return fmt.Errorf("%w %w", err, jwt.ErrTokenMalformed)
+		// 與過去的版本相容，簽名錯誤仍然可以用 jwt.ErrTokenMalformed 判斷
+		return fmt.Errorf("%w %w %w", err, jwt.ErrTokenSignatureInvalid, jwt.ErrTokenMalformed)
 	}
 
 	// 自定義內容，可能會有複雜的驗證，因此放在最後驗證
-	if customValidate != nil {
-		if err = customValidate(token.Claims); err != nil {
+	for _, vd := range []func(jwt.IClaims) error{p.validateClaims, customValidate} {
+		if vd == nil || token.Claims == nil {
+			continue
+		}
+		if err = vd(token.Claims); err != nil {
 			return err
 		}
 	}
 
+	token.Valid = true
 	return nil
 }
 
-func (p *Parser) parseHeader(headerStr string) (map[string]any, error) {
-	bs, err := base64.RawURLEncoding.DecodeString(headerStr)
+func (p *Parser) parseHeader(headerStr string) (header map[string]any, registered jwt.RegisteredHeader, err error) {
+	bs, err := p.decodeSegment(headerStr)
 	if err != nil {
-		return nil, err
+		return nil, registered, err
 	}
-	var header map[string]any
-	if err = json.Unmarshal(bs, &header); err != nil {
-		return nil, fmt.Errorf("failed to parse header: %w %w", err, jwt.ErrTokenMalformed)
+	if err = p.unmarshal(bs, &header); err != nil {
+		return nil, registered, fmt.Errorf("failed to parse header: %w %w", err, jwt.ErrTokenMalformed)
 	}
-	if header["typ"] != "JWT" {
-		return nil, fmt.Errorf("invalid token type: %v %w", header["typ"], jwt.ErrTokenMalformed)
+	if err = p.checkType(header); err != nil {
+		return nil, registered, err
 	}
 	algName, ok := header["alg"]
 	if !ok {
-		return nil, fmt.Errorf("token algorithm not found %w", jwt.ErrTokenMalformed)
+		return nil, registered, fmt.Errorf("token algorithm not found %w", jwt.ErrTokenMalformed)
 	}
 	if _, ok = algName.(string); !ok {
-		return nil, fmt.Errorf("token algorithm not string %w", jwt.ErrTokenMalformed)
+		return nil, registered, fmt.Errorf("token algorithm not string %w", jwt.ErrTokenMalformed)
 	}
-	return header, nil
+	if err = p.checkCritical(header); err != nil {
+		return nil, registered, err
+	}
+	if registered, err = registeredHeader(header); err != nil {
+		return nil, registered, err
+	}
+	return header, registered, nil
 }
 
-func (p *Parser) parseClaims(claimStr string, out jwt.IClaims) error {
-	bs, err := base64.RawURLEncoding.DecodeString(claimStr)
-	if err != nil {
-		return err
+// registeredHeader 由已經解析過的header取出標準欄位，型別不符(例如kid不是字串)視為格式錯誤
+//
+// 不可以再用 json.Unmarshal 解析到struct，因為它比對名稱時不分大小寫
+// 例如 {"kid":"a","KID":"b"} 會得到 header["kid"]為"a"，但KeyID卻是"b"
+func registeredHeader(header map[string]any) (registered jwt.RegisteredHeader, err error) {
+	for name, dst := range map[string]*string{
+		"alg": &registered.Algorithm,
+		"typ": &registered.Type,
+		"cty": &registered.ContentType,
+		"kid": &registered.KeyID,
+	} {
+		v, exists := header[name]
+		if !exists || v == nil {
+			continue
+		}
+		var ok bool
+		if *dst, ok = v.(string); !ok {
+			return registered, fmt.Errorf("header %s must be a string %w", name, jwt.ErrTokenMalformed)
+		}
 	}
-	if err = json.Unmarshal(bs, &out); err != nil {
-		return fmt.Errorf("could not base64 decode claim %w. %w", err, jwt.ErrTokenMalformed)
+	if v, exists := header["crit"]; exists && v != nil {
+		list, ok := v.([]any)
+		if !ok {
+			return registered, fmt.Errorf("header crit must be an array of strings %w", jwt.ErrTokenMalformed)
+		}
+		for _, name := range list {
+			s, ok := name.(string)
+			if !ok {
+				return registered, fmt.Errorf("header crit must be an array of strings %w", jwt.ErrTokenMalformed)
+			}
+			registered.Critical = append(registered.Critical, s)
+		}
+	}
+	return registered, nil
+}
+
+func (p *Parser) parseClaims(bs []byte, out jwt.IClaims) error {
+	if err := p.unmarshal(bs, &out); err != nil {
+		return fmt.Errorf("could not unmarshal claim %w. %w", err, jwt.ErrTokenMalformed)
 	}
 	return nil
 }
+
+// isBase64Payload 依據header的b64決定payload是否有經過base64url編碼
+// 使用b64時必須要將其列在crit之中 https://datatracker.ietf.org/doc/html/rfc7797#section-6
+func isBase64Payload(header map[string]any) (bool, error) {
+	v, exists := header["b64"]
+	if !exists {
+		return true, nil
+	}
+	b64, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("header b64 must be a boolean %w", jwt.ErrTokenMalformed)
+	}
+	crit, _ := header["crit"].([]any)
+	if !slices.Contains(crit, any("b64")) {
+		return false, fmt.Errorf("header b64 must be listed in crit %w", jwt.ErrTokenMalformed)
+	}
+	return b64, nil
+}
+
+// checkType https://datatracker.ietf.org/doc/html/rfc7519#section-5.1
+//
+// typ是選填的，有提供時必須在allowedTypes之中；比對時不分大小寫，且可以省略"application/"
+// https://datatracker.ietf.org/doc/html/rfc7515#section-4.1.9
+func (p *Parser) checkType(header map[string]any) error {
+	v, exists := header["typ"]
+	if !exists {
+		if p.requireType {
+			return fmt.Errorf("typ is required %w %w", jwt.ErrTokenInvalidType, jwt.ErrTokenMalformed)
+		}
+		return nil
+	}
+	typ, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("typ must be a string %w %w", jwt.ErrTokenInvalidType, jwt.ErrTokenMalformed)
+	}
+	allowed := p.allowedTypes
+	if allowed == nil {
+		allowed = []string{"JWT"}
+	}
+	typ = normalizeType(typ)
+	for _, t := range allowed {
+		if normalizeType(t) == typ {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid token type: %q %w %w", v, jwt.ErrTokenInvalidType, jwt.ErrTokenMalformed)
+}
+
+// normalizeType 轉為小寫並去除"application/"，例如: application/at+JWT => at+jwt
+func normalizeType(typ string) string {
+	typ = strings.ToLower(typ)
+	return strings.TrimPrefix(typ, "application/")
+}
+
+// checkCritical 細節請參考 jwt.CheckCritical，可以理解的擴充header為 builtinCriticalHeaders 以及 WithCriticalHeaders 所設定的名稱
+func (p *Parser) checkCritical(header map[string]any) error {
+	return jwt.CheckCritical(header, slices.Concat(builtinCriticalHeaders, p.criticalHeaders))
+}
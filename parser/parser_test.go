@@ -1,13 +1,24 @@
 package parser_test
 
 import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/jwk"
 	"github.com/CarsonSlovoka/jwt/parser"
 	"github.com/CarsonSlovoka/jwt/validator"
 	"slices"
+	"strings"
 	"testing"
 )
 
@@ -79,3 +90,465 @@ func TestParser_Parse(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestParser_ParseDetached(t *testing.T) {
+	key := []byte("webhook-secret-0123456789abcdef!")
+	getSigningMethod := func(method string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodHMAC256, nil
+	}
+	keyFunc := func(token *jwt.Token) (any, error) {
+		return key, nil
+	}
+	body := []byte(`{"event":"payment.succeeded","amount":10.25}`)
+
+	for _, b64 := range []bool{true, false} {
+		token := jwt.New(jwt.SigningMethodHMAC256)
+		token.SetBase64Payload(b64)
+		bsToken, err := token.SignedDetachedBytes(body, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parts := strings.Split(string(bsToken), "."); len(parts) != 3 || parts[1] != "" {
+			t.Fatalf("payload segment must be empty: %s", bsToken)
+		}
+
+		p := parser.New()
+		vdFunc, err := p.ParseDetached(string(bsToken), body, getSigningMethod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = vdFunc(nil, keyFunc); err != nil {
+			t.Fatal(b64, err)
+		}
+
+		// payload被竄改
+		vdFunc, err = p.ParseDetached(string(bsToken), []byte(`{"event":"payment.succeeded","amount":99.25}`), getSigningMethod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = vdFunc(nil, keyFunc); !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			t.Fatal(b64, err)
+		}
+		// 與過去的版本相容
+		if !errors.Is(err, jwt.ErrTokenMalformed) {
+			t.Fatal(b64, err)
+		}
+
+		// 一般的Parse不能接受空的payload
+		if _, err = p.Parse(string(bsToken), getSigningMethod); !errors.Is(err, jwt.ErrTokenMalformed) {
+			t.Fatal(b64, err)
+		}
+	}
+
+	// payload是claims的時候，仍然會進行claims的驗證
+	token := jwt.New(jwt.SigningMethodHMAC256)
+	token.SetBase64Payload(false)
+	claims := []byte(`{"iss":"auth.example.com","sub":"user123","aud":"app.example.com"}`)
+	bsToken, _ := token.SignedDetachedBytes(claims, key)
+	for _, tt := range []struct {
+		issuer string
+		err    error
+	}{
+		{"auth.example.com", nil},
+		{"other.example.com", jwt.ErrTokenInvalidIssuer},
+	} {
+		out := &jwt.RegisteredClaims{}
+		vdFunc, err := parser.New(func(v *validator.Validator) {
+			v.ExpectedIssuer = tt.issuer
+		}).ParseDetachedWithClaims(string(bsToken), claims, getSigningMethod, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = vdFunc(nil, nil, keyFunc); !errors.Is(err, tt.err) {
+			t.Fatal(tt.issuer, err)
+		}
+		if out.Subject != "user123" {
+			t.Fatal("claims not parsed")
+		}
+	}
+}
+
+// b64為false且payload直接放在compact的格式之中
+func TestParser_Parse_unencodedPayload(t *testing.T) {
+	key := []byte("webhook-secret-0123456789abcdef!")
+	getSigningMethod := func(method string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodHMAC256, nil
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHMAC256, jwt.MapClaims{
+		"iss": "auth", "sub": "user123", "aud": "app", // compact的格式之中，未編碼的payload不能有'.'
+	})
+	token.SetBase64Payload(false)
+	bsToken, err := token.SignedBytes(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vdFunc, err := parser.New().Parse(string(bsToken), getSigningMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+		return key, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// b64沒有列在crit之中，必須拒絕
+	delete(token.Header, "crit")
+	bsToken, _ = token.SignedBytes(key)
+	if _, err = parser.New().Parse(string(bsToken), getSigningMethod); !errors.Is(err, jwt.ErrTokenMalformed) {
+		t.Fatal(err)
+	}
+}
+
+func TestParser_Parse_critical(t *testing.T) {
+	key := []byte("crit-secret-0123456789abcdefghij")
+	getSigningMethod := func(method string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodHMAC256, nil
+	}
+	p := parser.New()
+	pExt := p.With(parser.WithCriticalHeaders("exp-policy"))
+
+	for i, tt := range []struct {
+		p      *parser.Parser
+		header map[string]any
+		err    error
+	}{
+		{p, map[string]any{}, nil},
+		{pExt, map[string]any{"crit": []string{"exp-policy"}, "exp-policy": "strict"}, nil},
+		{pExt, map[string]any{"crit": []string{"b64"}, "b64": true}, nil},                                        // b64 是內建支援的
+		{p, map[string]any{"crit": []string{"exp-policy"}, "exp-policy": "strict"}, jwt.ErrTokenInvalidCritical}, // 不認識
+		{pExt, map[string]any{"crit": []string{"exp-policy", "other"}, "exp-policy": "strict", "other": 1}, jwt.ErrTokenInvalidCritical},
+		{pExt, map[string]any{"crit": []string{}}, jwt.ErrTokenInvalidCritical},                           // 空的
+		{pExt, map[string]any{"crit": "exp-policy", "exp-policy": "strict"}, jwt.ErrTokenInvalidCritical}, // 不是陣列
+		{pExt, map[string]any{"crit": []string{"kid"}, "kid": "k1"}, jwt.ErrTokenInvalidCritical},         // 已註冊的header
+		{pExt, map[string]any{"crit": []string{"exp-policy"}}, jwt.ErrTokenInvalidCritical},               // header不存在
+	} {
+		token := jwt.NewWithClaims(jwt.SigningMethodHMAC256, jwt.MapClaims{
+			"iss": "auth.example.com", "sub": "user123", "aud": "app.example.com",
+		})
+		for k, v := range tt.header {
+			token.Header[k] = v
+		}
+		bsToken, err := token.SignedBytes(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vdFunc, err := tt.p.Parse(string(bsToken), getSigningMethod)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Fatal(i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+			return key, nil
+		}); err != nil {
+			t.Fatal(i, err)
+		}
+	}
+
+	// With不會影響原本的Parser
+	_ = p.With(parser.WithCriticalHeaders("other"))
+	token := jwt.New(jwt.SigningMethodHMAC256)
+	token.Header["crit"] = []string{"other"}
+	token.Header["other"] = 1
+	bsToken, _ := token.SignedBytes(key)
+	if _, err := p.Parse(string(bsToken), getSigningMethod); !errors.Is(err, jwt.ErrTokenInvalidCritical) {
+		t.Fatal(err)
+	}
+}
+
+func TestParser_Parse_keyBinding(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	p256Key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384Key, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	derBytes, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	pemBytes := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: derBytes})
+
+	// 以RSA公鑰(PEM)當作secret所產生的HS256 token
+	hsToken, _ := jwt.New(jwt.SigningMethodHMAC256).SignedBytes(pemBytes)
+	esToken, _ := jwt.New(jwt.SigningMethodECDSA256).SignedBytes(p256Key)
+	rsJWK, _ := jwk.New(&rsaKey.PublicKey)
+	rsJWK.Algorithm = "RS256"
+	ecJWK, _ := jwk.New(&p256Key.PublicKey)
+
+	for i, tt := range []struct {
+		token  []byte
+		m      jwt.ISigningMethod
+		key    any
+		expect error
+	}{
+		{hsToken, jwt.SigningMethodHMAC256, &rsaKey.PublicKey, jwt.ErrKeyAlgorithmMismatch},
+		{hsToken, jwt.SigningMethodHMAC256, pemBytes, jwt.ErrKeyAlgorithmMismatch},
+		{hsToken, jwt.SigningMethodHMAC256, rsJWK, jwt.ErrKeyAlgorithmMismatch},
+		{esToken, jwt.SigningMethodECDSA256, &p384Key.PublicKey, jwt.ErrKeyAlgorithmMismatch},
+		{esToken, jwt.SigningMethodECDSA256, []crypto.PublicKey{&p384Key.PublicKey, &rsaKey.PublicKey}, jwt.ErrKeyAlgorithmMismatch},
+		// 不相符的key會被略過
+		{esToken, jwt.SigningMethodECDSA256, []crypto.PublicKey{&p384Key.PublicKey, &p256Key.PublicKey}, nil},
+		{esToken, jwt.SigningMethodECDSA256, ecJWK, nil},
+	} {
+		vdFunc, err := parser.New().Parse(string(tt.token), func(method string) (jwt.ISigningMethod, error) {
+			return tt.m, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+			return tt.key, nil
+		})
+		if !errors.Is(err, tt.expect) {
+			t.Fatal(i, err)
+		}
+		// key不相符時，不會進行簽名的驗證
+		if tt.expect != nil && errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			t.Fatal(i, err)
+		}
+	}
+}
+
+func TestParser_ParseVerified(t *testing.T) {
+	key := []byte("my private key: 0123456789abcdef")
+	token := jwt.NewWithClaims(jwt.SigningMethodHMAC256, &jwt.RegisteredClaims{
+		Issuer:   "auth.example.com",
+		Subject:  "user123",
+		Audience: jwt.ClaimStrings{"app.example.com"},
+	})
+	token.Header["kid"] = "k1"
+	bsToken, err := token.SignedBytes(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	getSigningMethod := func(method string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodHMAC256, nil
+	}
+	keyFunc := func(token *jwt.Token) (any, error) {
+		return key, nil
+	}
+	errRejected := errors.New("rejected")
+
+	p := parser.New(func(v *validator.Validator) {
+		v.ExpectedIssuer = "auth.example.com"
+	})
+	claims := &jwt.RegisteredClaims{}
+	verified, err := p.ParseVerified(context.Background(), string(bsToken), getSigningMethod, claims, keyFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verified.Valid || verified.Raw != string(bsToken) || verified.Claims != claims || claims.Subject != "user123" {
+		t.Fatal(verified)
+	}
+	if verified.RegisteredHeader.Algorithm != "HS256" || verified.RegisteredHeader.Type != "JWT" || verified.RegisteredHeader.KeyID != "k1" {
+		t.Fatal(verified.RegisteredHeader)
+	}
+	signature, _ := jwt.SigningMethodHMAC256.Sign(bsToken[:bytes.LastIndexByte(bsToken, '.')], key)
+	if !bytes.Equal(verified.Signature, signature) {
+		t.Fatal("signature not decoded")
+	}
+
+	for i, tt := range []struct {
+		p       *parser.Parser
+		keyFunc jwt.KeyFunc
+		expect  error
+	}{
+		{p, func(token *jwt.Token) (any, error) {
+			return []byte("another private key: 0123456789ab"), nil
+		}, jwt.ErrTokenSignatureInvalid},
+		{p, nil, jwt.ErrInvalidKeyType},
+		{parser.New(func(v *validator.Validator) {
+			v.ExpectedIssuer = "other.example.com"
+		}), keyFunc, jwt.ErrTokenInvalidIssuer},
+		{p.With(parser.WithHeaderValidator(func(header map[string]any) error {
+			return errRejected
+		})), keyFunc, errRejected},
+		{p.With(parser.WithClaimsValidator(func(jwt.IClaims) error {
+			return errRejected
+		})), keyFunc, errRejected},
+	} {
+		verified, err = tt.p.ParseVerified(context.Background(), string(bsToken), getSigningMethod, nil, tt.keyFunc)
+		if !errors.Is(err, tt.expect) {
+			t.Fatal(i, err)
+		}
+		// 驗證失敗時不會回傳token
+		if verified != nil {
+			t.Fatal(i, verified)
+		}
+	}
+
+	// 舊的vdFunc同樣會執行Parser所設定的驗證
+	vdFunc, err := p.With(parser.WithClaimsValidator(func(jwt.IClaims) error {
+		return errRejected
+	})).Parse(string(bsToken), getSigningMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, keyFunc); !errors.Is(err, errRejected) {
+		t.Fatal(err)
+	}
+}
+
+func TestParser_ParseUnverified(t *testing.T) {
+	key := []byte("my private key: 0123456789abcdef")
+	token := jwt.NewWithClaims(jwt.SigningMethodHMAC256, &jwt.RegisteredClaims{
+		Issuer:  "https://idp-a.example.com",
+		Subject: "user123",
+	})
+	token.Header["kid"] = "k1"
+	bsToken, _ := token.SignedBytes(key)
+
+	claims := &jwt.RegisteredClaims{}
+	unverified, err := parser.New().ParseUnverified(string(bsToken), claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unverified.RegisteredHeader.KeyID != "k1" || claims.Issuer != "https://idp-a.example.com" || unverified.Claims != claims {
+		t.Fatal(unverified)
+	}
+	if unverified.Raw != string(bsToken) || len(unverified.Signature) != 32 {
+		t.Fatal(unverified)
+	}
+
+	// 即使簽名錯誤或alg未知，仍然可以讀取內容
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"XX999","typ":"JWT","kid":"k2"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"https://idp-b.example.com"}`))
+	unverified, err = parser.New().ParseUnverified(header+"."+payload+".c2ln", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unverified.RegisteredHeader.Algorithm != "XX999" || (*unverified.Claims.(*jwt.MapClaims))["iss"] != "https://idp-b.example.com" {
+		t.Fatal(unverified)
+	}
+
+	// 格式錯誤仍然會被拒絕
+	if _, err = parser.New().ParseUnverified(header+"."+payload, nil); !errors.Is(err, jwt.ErrTokenMalformed) {
+		t.Fatal(err)
+	}
+
+	// RegisteredHeader與Header必須一致，不可以因為大小寫不同而取到不同的kid
+	header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","kid":"a","KID":"b"}`))
+	if unverified, err = parser.New().ParseUnverified(header+"."+payload+".c2ln", nil); err != nil {
+		t.Fatal(err)
+	}
+	if unverified.RegisteredHeader.KeyID != "a" || unverified.Header["kid"] != "a" {
+		t.Fatal(unverified.RegisteredHeader, unverified.Header)
+	}
+	header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","kid":1}`))
+	if _, err = parser.New().ParseUnverified(header+"."+payload+".c2ln", nil); !errors.Is(err, jwt.ErrTokenMalformed) {
+		t.Fatal(err)
+	}
+}
+
+func TestParser_Parse_type(t *testing.T) {
+	key := []byte("my private key: 0123456789abcdef")
+	getSigningMethod := func(method string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodHMAC256, nil
+	}
+	sign := func(typ string) string {
+		bsToken, err := jwt.NewWithType(jwt.SigningMethodHMAC256, typ, &jwt.MapClaims{}).SignedBytes(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(bsToken)
+	}
+	accessToken := parser.New().With(parser.WithAllowedTypes("at+jwt"), parser.WithRequiredType())
+
+	for i, tt := range []struct {
+		p        *parser.Parser
+		tokenStr string
+		expect   error
+	}{
+		{parser.New(), sign("JWT"), nil},
+		{parser.New(), sign("jwt"), nil},
+		{parser.New(), sign("application/JWT"), nil},
+		{parser.New(), sign(""), nil}, // typ是選填的
+		{parser.New(), sign("at+jwt"), jwt.ErrTokenInvalidType},
+		{accessToken, sign("at+jwt"), nil},
+		{accessToken, sign("application/AT+JWT"), nil},
+		{accessToken, sign(""), jwt.ErrTokenInvalidType},
+		// 避免把其他用途的token當作access token
+		{accessToken, sign("JWT"), jwt.ErrTokenInvalidType},
+		{accessToken, sign("logout+jwt"), jwt.ErrTokenInvalidType},
+		{parser.New().With(parser.WithAllowedTypes("application/logout+jwt")), sign("logout+jwt"), nil},
+	} {
+		_, err := tt.p.ParseUnverified(tt.tokenStr, nil)
+		if !errors.Is(err, tt.expect) {
+			t.Fatal(i, err)
+		}
+		// 與過去的版本相容，仍然可以用ErrTokenMalformed判斷
+		if tt.expect != nil && !errors.Is(err, jwt.ErrTokenMalformed) {
+			t.Fatal(i, err)
+		}
+	}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":1}`))
+	if _, err := parser.New().Parse(header+".e30.c2ln", getSigningMethod); !errors.Is(err, jwt.ErrTokenMalformed) {
+		t.Fatal(err)
+	}
+}
+
+func TestParser_Parse_strictDecoding(t *testing.T) {
+	key := []byte("my private key: 0123456789abcdef")
+	getSigningMethod := func(method string) (jwt.ISigningMethod, error) {
+		return jwt.SigningMethodHMAC256, nil
+	}
+	keyFunc := func(token *jwt.Token) (any, error) {
+		return key, nil
+	}
+	// 以原始的segment組成token並加簽，讓簽名本身是正確的
+	sign := func(header, payload string) string {
+		signingStr := header + "." + payload
+		signature, _ := jwt.SigningMethodHMAC256.Sign([]byte(signingStr), key)
+		return signingStr + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+	enc := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	header := enc(`{"alg":"HS256","typ":"JWT"}`)
+	strict := parser.New().With(parser.WithStrictDecoding())
+
+	for i, tt := range []struct {
+		tokenStr string
+		expect   error
+	}{
+		{sign(header, enc(`{"sub":"user123"}`)), nil},
+		{sign(header, enc(`{"sub":"user123","nested":{"a":1,"b":[{"c":1},{"c":2}]}}`)), nil},
+		{sign(header, enc(`{"sub":"user123","sub":"admin"}`)), parser.ErrDuplicateMember},
+		{sign(header, enc(`{"sub":"user123","\u0073ub":"admin"}`)), parser.ErrDuplicateMember},
+		{sign(header, enc(`{"nested":{"a":1,"a":2}}`)), parser.ErrDuplicateMember},
+		// 解析到struct時不分大小寫，不可以讓後面的ISS蓋掉iss
+		{sign(header, enc(`{"iss":"good","ISS":"evil"}`)), parser.ErrDuplicateMember},
+		{sign(header, enc(`{"sub":"user123","\u017fub":"admin"}`)), parser.ErrDuplicateMember}, // ſ與s也視為相同
+		{sign(enc(`{"alg":"HS256","typ":"JWT","kid":"a","KID":"b"}`), enc(`{}`)), parser.ErrDuplicateMember},
+		{sign(enc(`{"alg":"HS256","typ":"JWT","alg":"none"}`), enc(`{}`)), parser.ErrDuplicateMember},
+		{sign(header, enc("{\"sub\":\"\xff\"}")), parser.ErrInvalidUTF8},
+		{sign(header, enc(`{"sub":"user123"} `)), parser.ErrTrailingData},
+		{sign(header, enc(`{"sub":"user123"}{}`)), jwt.ErrTokenMalformed},
+		{sign(header, enc(`{"a":1}`)+"\n"), parser.ErrNonCanonicalBase64},
+		// `{"a":1}` => eyJhIjoxfQ，最後一個字元的未使用位元不為0
+		{sign(header, "eyJhIjoxfR"), parser.ErrNonCanonicalBase64},
+	} {
+		vdFunc, err := strict.Parse(tt.tokenStr, getSigningMethod)
+		if err == nil {
+			err = vdFunc(nil, nil, keyFunc)
+		}
+		if !errors.Is(err, tt.expect) || (tt.expect != nil && !errors.Is(err, jwt.ErrTokenMalformed)) {
+			t.Fatal(i, err)
+		}
+
+		// 預設的模式會接受這些內容
+		if tt.expect != nil && tt.expect != jwt.ErrTokenMalformed {
+			if _, err = parser.New().Parse(tt.tokenStr, getSigningMethod); err != nil {
+				t.Fatal(i, err)
+			}
+		}
+	}
+
+	// 有padding的signature
+	tokenStr := sign(header, enc(`{"sub":"user123"}`))
+	tokenStr = tokenStr[:strings.LastIndexByte(tokenStr, '.')+1] + base64.URLEncoding.EncodeToString([]byte("signatur"))
+	if _, err := strict.Parse(tokenStr, getSigningMethod); !errors.Is(err, parser.ErrNonCanonicalBase64) {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,133 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 嚴格模式(WithStrictDecoding)的錯誤，都可以被 jwt.ErrTokenMalformed 判別
+var (
+	// ErrDuplicateMember JSON物件之中有重複的名稱 https://datatracker.ietf.org/doc/html/rfc7515#section-5.2
+	ErrDuplicateMember = fmt.Errorf("%w %w",
+		errors.New("json object has duplicate member names"),
+		jwt.ErrTokenMalformed,
+	)
+
+	// ErrInvalidUTF8 JSON不是合法的UTF-8 https://datatracker.ietf.org/doc/html/rfc8259#section-8.1
+	ErrInvalidUTF8 = fmt.Errorf("%w %w",
+		errors.New("json is not valid UTF-8"),
+		jwt.ErrTokenMalformed,
+	)
+
+	// ErrTrailingData JSON的值之後還有其他的資料(包含空白)
+	ErrTrailingData = fmt.Errorf("%w %w",
+		errors.New("json has trailing data"),
+		jwt.ErrTokenMalformed,
+	)
+
+	// ErrNonCanonicalBase64 base64url不是唯一的編碼形式，例如有padding、換行，或者最後一個字元的未使用位元不為0
+	// https://datatracker.ietf.org/doc/html/rfc7515#section-2
+	ErrNonCanonicalBase64 = fmt.Errorf("%w %w",
+		errors.New("base64url is not canonical"),
+		jwt.ErrTokenMalformed,
+	)
+)
+
+// decodeSegment base64url解碼，嚴格模式時只接受唯一的編碼形式
+func (p *Parser) decodeSegment(seg string) ([]byte, error) {
+	if p.strict && strings.HasSuffix(seg, "=") {
+		return nil, fmt.Errorf("padding is not allowed %w", ErrNonCanonicalBase64)
+	}
+	bs, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return nil, err
+	}
+	// RawURLEncoding會略過\r\n，也不檢查未使用的位元，所以直接比對重新編碼的結果
+	if p.strict && base64.RawURLEncoding.EncodeToString(bs) != seg {
+		return nil, ErrNonCanonicalBase64
+	}
+	return bs, nil
+}
+
+// unmarshal 嚴格模式時，先確認bs是合法的UTF-8、沒有重複的名稱、沒有多餘的資料
+func (p *Parser) unmarshal(bs []byte, v any) error {
+	if p.strict {
+		if err := checkStrictJSON(bs); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(bs, v)
+}
+
+func checkStrictJSON(bs []byte) error {
+	if !utf8.Valid(bs) {
+		return ErrInvalidUTF8
+	}
+	dec := json.NewDecoder(bytes.NewReader(bs))
+	if err := checkDuplicateMembers(dec); err != nil {
+		return err
+	}
+	if dec.InputOffset() != int64(len(bs)) {
+		return ErrTrailingData
+	}
+	return nil
+}
+
+// checkDuplicateMembers 讀取一個JSON的值，並確認其中(包含巢狀)的物件都沒有重複的名稱
+// 名稱會先經過unescape才比對，所以"a"與"\u0061"視為相同
+// 解析到struct的時候 encoding/json 不分大小寫，所以"iss"與"ISS"也視為重複，否則後者會蓋掉前者
+func checkDuplicateMembers(dec *json.Decoder) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t {
+	case json.Delim('{'):
+		names := make(map[string]struct{})
+		for dec.More() {
+			if t, err = dec.Token(); err != nil {
+				return err
+			}
+			name := t.(string)
+			folded := foldName(name)
+			if _, exists := names[folded]; exists {
+				return fmt.Errorf("%q %w", name, ErrDuplicateMember)
+			}
+			names[folded] = struct{}{}
+			if err = checkDuplicateMembers(dec); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for dec.More() {
+			if err = checkDuplicateMembers(dec); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+	_, err = dec.Token() // '}' or ']'
+	return err
+}
+
+// foldName 將每個字元換成其case folding之中最小的字元
+// foldName(a) == foldName(b) 與 strings.EqualFold(a, b) 等價，也就是 encoding/json 比對struct欄位的方式
+func foldName(name string) string {
+	var sb strings.Builder
+	for _, r := range name {
+		folded := r
+		for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+			folded = min(folded, f)
+		}
+		sb.WriteRune(folded)
+	}
+	return sb.String()
+}
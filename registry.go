@@ -0,0 +1,106 @@
+package jwt
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Registry alg名稱與 ISigningMethod 的對應，可以取代自行撰寫的getSigningMethod
+//
+//	registry := jwt.NewDefaultRegistry()
+//	vdFunc, err := parser.New().Parse(tokenStr, registry.AllowList("RS256", "ES256").GetSigningMethod)
+//
+// none不會被 Register 接受，必須透過 RegisterUnsafe 明確地註冊
+type Registry struct {
+	mu      sync.RWMutex
+	methods map[string]ISigningMethod
+}
+
+// extraDefaultMethods 只在特定Go版本才會編譯的內建演算法，由各自的init加入，例如ML-DSA(Go 1.27以上)
+var extraDefaultMethods []ISigningMethod
+
+// NewRegistry 建立空的Registry
+func NewRegistry() *Registry {
+	return &Registry{methods: make(map[string]ISigningMethod)}
+}
+
+// NewDefaultRegistry 建立包含本套件所有內建演算法(none除外)的Registry
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry()
+	_ = r.Register(
+		SigningMethodHMAC256, SigningMethodHMAC384, SigningMethodHMAC512,
+		SigningMethodRSA256, SigningMethodRSA384, SigningMethodRSA512,
+		SigningMethodRSAPSS256, SigningMethodRSAPSS384, SigningMethodRSAPSS512,
+		SigningMethodECDSA256, SigningMethodECDSA384, SigningMethodECDSA512,
+		SigningMethodES256K,
+		&SigningMethodEdDSA{},
+	)
+	r.register(extraDefaultMethods...)
+	return r
+}
+
+// Register 註冊(或覆蓋)演算法，可用來加入自定義的 ISigningMethod
+// alg為none時會回傳 ErrAlgorithmUnsafe，且此次所有的methods都不會被註冊
+func (r *Registry) Register(methods ...ISigningMethod) error {
+	for _, m := range methods {
+		if m.AlgName() == "none" {
+			return fmt.Errorf("use RegisterUnsafe to register %q. %w", m.AlgName(), ErrAlgorithmUnsafe)
+		}
+	}
+	r.register(methods...)
+	return nil
+}
+
+// RegisterUnsafe 註冊none等不安全的演算法，marker必須為 UnsafeAllowNone
+//
+//	registry.RegisterUnsafe(jwt.UnsafeAllowNone, jwt.SigningMethodUnsafeNone)
+func (r *Registry) RegisterUnsafe(_ unsafeNoneMarker, methods ...ISigningMethod) {
+	r.register(methods...)
+}
+
+func (r *Registry) register(methods ...ISigningMethod) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, m := range methods {
+		r.methods[m.AlgName()] = m
+	}
+}
+
+// GetSigningMethod 依據alg取得已註冊的方法，未註冊時回傳 ErrAlgorithmNotRegistered
+// 其簽名與parser所需要的getSigningMethod相同，可以直接傳入
+func (r *Registry) GetSigningMethod(alg string) (ISigningMethod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m, ok := r.methods[alg]
+	if !ok {
+		return nil, fmt.Errorf("alg: %q %w", alg, ErrAlgorithmNotRegistered)
+	}
+	return m, nil
+}
+
+// Algorithms 已註冊的alg名稱(已排序)
+func (r *Registry) Algorithms() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	algs := make([]string, 0, len(r.methods))
+	for alg := range r.methods {
+		algs = append(algs, alg)
+	}
+	slices.Sort(algs)
+	return algs
+}
+
+// AllowList 回傳只包含algs的新Registry，不在r之中的alg會被略過
+// 之後對r的異動不會影響到回傳的Registry
+func (r *Registry) AllowList(algs ...string) *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	allowed := NewRegistry()
+	for _, alg := range algs {
+		if m, ok := r.methods[alg]; ok {
+			allowed.methods[alg] = m
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,125 @@
+package jwt_test
+
+import (
+	"errors"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// defaultAlgorithms NewDefaultRegistry 應該要有的演算法，只在特定Go版本才有的演算法(例如ML-DSA)由其測試檔加入
+var defaultAlgorithms = []string{
+	"HS256", "HS384", "HS512",
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"ES256K",
+	"EdDSA",
+}
+
+func TestRegistry(t *testing.T) {
+	registry := jwt.NewDefaultRegistry()
+	if algs := registry.Algorithms(); len(algs) != len(defaultAlgorithms) || slices.Contains(algs, "none") {
+		t.Fatal(algs)
+	}
+	for _, alg := range defaultAlgorithms {
+		m, err := registry.GetSigningMethod(alg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.AlgName() != alg {
+			t.Fatal(m.AlgName())
+		}
+	}
+
+	// 只允許HS256
+	key := []byte(strings.Repeat("k", 64))
+	allowed := registry.AllowList("HS256", "XX999")
+	if algs := allowed.Algorithms(); !slices.Equal(algs, []string{"HS256"}) {
+		t.Fatal(algs)
+	}
+	for _, tt := range []struct {
+		m      jwt.ISigningMethod
+		expect error
+	}{
+		{jwt.SigningMethodHMAC256, nil},
+		{jwt.SigningMethodHMAC512, jwt.ErrAlgorithmNotRegistered},
+	} {
+		bsToken, _ := jwt.New(tt.m).SignedBytes(key)
+		vdFunc, err := parser.New().Parse(string(bsToken), allowed.GetSigningMethod)
+		if !errors.Is(err, tt.expect) {
+			t.Fatal(tt.m.AlgName(), err)
+		}
+		if err != nil {
+			continue
+		}
+		if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+			return key, nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 之後的異動不影響AllowList
+	if err := registry.Register(&customMethod{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.GetSigningMethod("custom"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := allowed.GetSigningMethod("custom"); !errors.Is(err, jwt.ErrAlgorithmNotRegistered) {
+		t.Fatal(err)
+	}
+}
+
+func TestRegistry_none(t *testing.T) {
+	registry := jwt.NewRegistry()
+	if err := registry.Register(jwt.SigningMethodHMAC256, jwt.SigningMethodUnsafeNone); !errors.Is(err, jwt.ErrAlgorithmUnsafe) {
+		t.Fatal(err)
+	}
+	if len(registry.Algorithms()) != 0 {
+		t.Fatal("none of the methods should be registered")
+	}
+
+	bsToken, err := jwt.New(jwt.SigningMethodUnsafeNone).SignedBytes(jwt.UnsafeAllowNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bsToken[len(bsToken)-1] != '.' {
+		t.Fatal(string(bsToken))
+	}
+	if _, err = parser.New().Parse(string(bsToken), registry.GetSigningMethod); !errors.Is(err, jwt.ErrAlgorithmNotRegistered) {
+		t.Fatal(err)
+	}
+
+	registry.RegisterUnsafe(jwt.UnsafeAllowNone, jwt.SigningMethodUnsafeNone)
+	vdFunc, err := parser.New().Parse(string(bsToken), registry.GetSigningMethod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 即使註冊了none，key仍然必須是UnsafeAllowNone
+	if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+		return []byte("key"), nil
+	}); !errors.Is(err, jwt.ErrAlgorithmUnsafe) {
+		t.Fatal(err)
+	}
+	if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+		return jwt.UnsafeAllowNone, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = jwt.SigningMethodUnsafeNone.Sign(nil, nil); !errors.Is(err, jwt.ErrAlgorithmUnsafe) {
+		t.Fatal(err)
+	}
+	if err = jwt.SigningMethodUnsafeNone.Verify(nil, []byte{1}, jwt.UnsafeAllowNone); !errors.Is(err, jwt.ErrSignatureInvalid) {
+		t.Fatal(err)
+	}
+}
+
+type customMethod struct{}
+
+func (m *customMethod) AlgName() string                  { return "custom" }
+func (m *customMethod) Sign([]byte, any) ([]byte, error) { return []byte("sig"), nil }
+func (m *customMethod) Verify([]byte, []byte, any) error { return nil }
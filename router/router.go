@@ -0,0 +1,173 @@
+// Package router 依據token的iss，將其交給該issuer所屬的設定(validator、鑰匙、可用的演算法)驗證
+//
+// 每一個issuer的鑰匙只會被用來驗證該issuer的token，避免A的鑰匙可以驗證自稱為B的token
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"github.com/CarsonSlovoka/jwt/validator"
+	"maps"
+	"slices"
+	"sync"
+)
+
+var (
+	// ErrUnknownIssuer token的iss沒有被註冊(或者沒有iss)，使其可以被兩種錯誤類型判別
+	ErrUnknownIssuer = fmt.Errorf("%w %w",
+		errors.New("router: unknown issuer"),
+		jwt.ErrTokenInvalidIssuer,
+	)
+
+	// ErrInvalidConfig Issuer 的設定不完整，或者該issuer已經被註冊過
+	ErrInvalidConfig = errors.New("router: invalid issuer config")
+)
+
+// Issuer 單一issuer的驗證設定
+type Issuer struct {
+	// Validator 此issuer的claims驗證(audience, 時間的容許範圍等)
+	// 以 parser.New 的預設值為基礎，只套用有設定的欄位；Require*只能開啟，不能藉由零值關閉
+	// ExpectedIssuer與RequireIssuer會被強制設定為註冊時的iss
+	//
+	// ExpectedAudience 不可以為空，除非明確地設定 SkipAudience
+	Validator validator.Validator
+
+	// SkipAudience 明確地不驗證aud，例如此issuer的token本來就沒有aud
+	// 多個issuer共用一個Router時，沒有驗證aud的token可能會被拿到其他的服務使用，請謹慎使用
+	SkipAudience bool
+
+	// KeyFunc 此issuer的鑰匙來源，例如 jwk.KeySet.KeyFunc
+	KeyFunc jwt.KeyFunc
+
+	// Algorithms 此issuer可以使用的alg，不可以為空
+	Algorithms []string
+
+	// Registry alg所對應的 jwt.ISigningMethod，nil表示使用 jwt.NewDefaultRegistry
+	Registry *jwt.Registry
+
+	// ParserOptions 只套用在此issuer的parser選項，例如 parser.WithClaimsValidator
+	ParserOptions []parser.Option
+}
+
+type route struct {
+	parser           *parser.Parser
+	keyFunc          jwt.KeyFunc
+	getSigningMethod func(alg string) (jwt.ISigningMethod, error)
+}
+
+// Router 依據iss分派給各個issuer的parser
+type Router struct {
+	mu sync.RWMutex
+	// options 所有issuer共用的parser選項
+	options []parser.Option
+	// parser 用來讀取未驗證的iss
+	parser *parser.Parser
+	routes map[string]*route
+}
+
+// New 建立Router，options會套用到所有issuer的parser
+// 若有issuer使用crit等會影響header檢查的選項，請在這裡設定，否則讀取iss時就會被拒絕
+func New(options ...parser.Option) *Router {
+	return &Router{
+		options: options,
+		parser:  parser.New().With(options...),
+		routes:  make(map[string]*route),
+	}
+}
+
+// Register 註冊issuer，iss必須與token之中的iss完全相同
+// 設定不完整或者iss已經被註冊時回傳 ErrInvalidConfig
+func (r *Router) Register(iss string, config Issuer) error {
+	if iss == "" {
+		return fmt.Errorf("iss is empty. %w", ErrInvalidConfig)
+	}
+	if config.KeyFunc == nil {
+		return fmt.Errorf("iss: %q KeyFunc is nil. %w", iss, ErrInvalidConfig)
+	}
+	if len(config.Algorithms) == 0 {
+		return fmt.Errorf("iss: %q Algorithms is empty. %w", iss, ErrInvalidConfig)
+	}
+	if config.Validator.ExpectedAudience == "" && !config.SkipAudience {
+		return fmt.Errorf("iss: %q Validator.ExpectedAudience is empty, set SkipAudience to skip the audience check. %w", iss, ErrInvalidConfig)
+	}
+	registry := config.Registry
+	if registry == nil {
+		registry = jwt.NewDefaultRegistry()
+	}
+	allowed := registry.AllowList(config.Algorithms...)
+	for _, alg := range config.Algorithms {
+		if _, err := allowed.GetSigningMethod(alg); err != nil {
+			return fmt.Errorf("iss: %q %w %w", iss, err, ErrInvalidConfig)
+		}
+	}
+
+	p := parser.New(func(v *validator.Validator) {
+		mergeValidator(v, config.Validator)
+		v.ExpectedIssuer = iss
+		v.RequireIssuer = true
+	}).With(r.options...).With(config.ParserOptions...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.routes[iss]; exists {
+		return fmt.Errorf("iss: %q is already registered. %w", iss, ErrInvalidConfig)
+	}
+	r.routes[iss] = &route{
+		parser:           p,
+		keyFunc:          config.KeyFunc,
+		getSigningMethod: allowed.GetSigningMethod,
+	}
+	return nil
+}
+
+// mergeValidator 將config之中有設定的欄位套用到v(parser.New 的預設值)
+// Require*與VerifyIat只能開啟，否則零值的Validator會把預設的RequireAudience, RequireSubject關掉
+func mergeValidator(v *validator.Validator, config validator.Validator) {
+	if config.TimeFunc != nil {
+		v.TimeFunc = config.TimeFunc
+	}
+	v.RequireIssuer = v.RequireIssuer || config.RequireIssuer
+	v.RequireSubject = v.RequireSubject || config.RequireSubject
+	v.RequireAudience = v.RequireAudience || config.RequireAudience
+	v.RequireExpirationTime = v.RequireExpirationTime || config.RequireExpirationTime
+	v.RequireNotBefore = v.RequireNotBefore || config.RequireNotBefore
+	v.RequireIssueAt = v.RequireIssueAt || config.RequireIssueAt
+	v.VerifyIat = v.VerifyIat || config.VerifyIat
+	if config.ExpectedSubject != "" {
+		v.ExpectedSubject = config.ExpectedSubject
+	}
+	if config.ExpectedAudience != "" {
+		v.ExpectedAudience = config.ExpectedAudience
+	}
+}
+
+// Issuers 已註冊的iss(已排序)
+func (r *Router) Issuers() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.routes))
+}
+
+// ParseVerified 先讀取未驗證的iss，再交給該issuer的parser完整地驗證，細節請參考 parser.Parser.ParseVerified
+// iss未註冊時回傳 ErrUnknownIssuer，此時不會呼叫任何issuer的KeyFunc
+func (r *Router) ParseVerified(ctx context.Context, tokenStr string, iClaims jwt.IClaims) (*jwt.Token, error) {
+	unverified, err := r.parser.ParseUnverified(tokenStr, &jwt.RegisteredClaims{})
+	if err != nil {
+		return nil, err
+	}
+	iss, err := unverified.Claims.GetIssuer()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	rt, ok := r.routes[iss]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("iss: %q %w", iss, ErrUnknownIssuer)
+	}
+	return rt.parser.ParseVerified(ctx, tokenStr, rt.getSigningMethod, iClaims, rt.keyFunc)
+}
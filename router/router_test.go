@@ -0,0 +1,122 @@
+package router_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/router"
+	"github.com/CarsonSlovoka/jwt/validator"
+	"testing"
+	"time"
+)
+
+func TestRouter(t *testing.T) {
+	rsaKeyA, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaKeyD, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKeyB, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	hmacKeyC := []byte("issuer c secret: 0123456789abcdef")
+
+	var keyFuncCalls int
+	keyFunc := func(key any) jwt.KeyFunc {
+		return func(token *jwt.Token) (any, error) {
+			keyFuncCalls++
+			return key, nil
+		}
+	}
+
+	r := router.New()
+	for iss, config := range map[string]router.Issuer{
+		"https://a.example.com": {KeyFunc: keyFunc(&rsaKeyA.PublicKey), Algorithms: []string{"RS256"}, SkipAudience: true},
+		"https://b.example.com": {KeyFunc: keyFunc(&ecKeyB.PublicKey), Algorithms: []string{"ES256"}, SkipAudience: true},
+		"https://c.example.com": {
+			KeyFunc:    keyFunc(hmacKeyC),
+			Algorithms: []string{"HS256"},
+			// 只設定了ExpectedAudience，RequireAudience仍然維持預設的true
+			Validator: validator.Validator{ExpectedAudience: "api.example.com"},
+		},
+		"https://d.example.com": {
+			KeyFunc:      keyFunc(&rsaKeyD.PublicKey),
+			Algorithms:   []string{"RS256"},
+			SkipAudience: true,
+			// 此issuer的時鐘比較快
+			Validator: validator.Validator{TimeFunc: func() time.Time {
+				return time.Now().Add(2 * time.Hour)
+			}},
+		},
+	} {
+		if err := r.Register(iss, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if issuers := r.Issuers(); len(issuers) != 4 || issuers[0] != "https://a.example.com" {
+		t.Fatal(issuers)
+	}
+
+	sign := func(m jwt.ISigningMethod, key any, claims *jwt.RegisteredClaims) string {
+		bsToken, err := jwt.NewWithClaims(m, claims).SignedBytes(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(bsToken)
+	}
+	exp := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	for i, tt := range []struct {
+		tokenStr string
+		expect   error
+	}{
+		{sign(jwt.SigningMethodRSA256, rsaKeyA, &jwt.RegisteredClaims{Issuer: "https://a.example.com"}), nil},
+		{sign(jwt.SigningMethodECDSA256, ecKeyB, &jwt.RegisteredClaims{Issuer: "https://b.example.com"}), nil},
+		{sign(jwt.SigningMethodHMAC256, hmacKeyC, &jwt.RegisteredClaims{Issuer: "https://c.example.com", Audience: jwt.ClaimStrings{"api.example.com"}}), nil},
+		{sign(jwt.SigningMethodHMAC256, hmacKeyC, &jwt.RegisteredClaims{Issuer: "https://c.example.com", Audience: jwt.ClaimStrings{"other.example.com"}}), jwt.ErrTokenInvalidAudience},
+		{sign(jwt.SigningMethodHMAC256, hmacKeyC, &jwt.RegisteredClaims{Issuer: "https://c.example.com"}), jwt.ErrClaimRequired},
+		// A的鑰匙不能驗證自稱為D的token
+		{sign(jwt.SigningMethodRSA256, rsaKeyA, &jwt.RegisteredClaims{Issuer: "https://d.example.com"}), jwt.ErrTokenSignatureInvalid},
+		// B不接受RS256
+		{sign(jwt.SigningMethodRSA256, rsaKeyA, &jwt.RegisteredClaims{Issuer: "https://b.example.com"}), jwt.ErrAlgorithmNotRegistered},
+		// 依據D的時鐘，此token已經過期
+		{sign(jwt.SigningMethodRSA256, rsaKeyD, &jwt.RegisteredClaims{Issuer: "https://d.example.com", ExpiresAt: exp}), jwt.ErrTokenExpired},
+	} {
+		token, err := r.ParseVerified(context.Background(), tt.tokenStr, nil)
+		if !errors.Is(err, tt.expect) {
+			t.Fatal(i, err)
+		}
+		if tt.expect == nil && !token.Valid {
+			t.Fatal(i, "token should be valid")
+		}
+	}
+
+	// 未註冊的iss不會呼叫任何的KeyFunc
+	keyFuncCalls = 0
+	for _, iss := range []string{"https://unknown.example.com", ""} {
+		tokenStr := sign(jwt.SigningMethodRSA256, rsaKeyA, &jwt.RegisteredClaims{Issuer: iss})
+		if _, err := r.ParseVerified(context.Background(), tokenStr, nil); !errors.Is(err, router.ErrUnknownIssuer) || !errors.Is(err, jwt.ErrTokenInvalidIssuer) {
+			t.Fatal(iss, err)
+		}
+	}
+	if keyFuncCalls != 0 {
+		t.Fatal(keyFuncCalls)
+	}
+
+	// 不完整的設定
+	for i, tt := range []struct {
+		iss    string
+		config router.Issuer
+	}{
+		{"https://a.example.com", router.Issuer{KeyFunc: keyFunc(nil), Algorithms: []string{"RS256"}, SkipAudience: true}},
+		{"https://e.example.com", router.Issuer{KeyFunc: keyFunc(nil), SkipAudience: true}},
+		{"https://e.example.com", router.Issuer{KeyFunc: keyFunc(nil), Algorithms: []string{"none"}, SkipAudience: true}},
+		{"https://e.example.com", router.Issuer{Algorithms: []string{"RS256"}, SkipAudience: true}},
+		{"", router.Issuer{KeyFunc: keyFunc(nil), Algorithms: []string{"RS256"}, SkipAudience: true}},
+		// 零值的Validator沒有設定audience，必須明確地使用SkipAudience
+		{"https://e.example.com", router.Issuer{KeyFunc: keyFunc(nil), Algorithms: []string{"RS256"}}},
+	} {
+		if err := r.Register(tt.iss, tt.config); !errors.Is(err, router.ErrInvalidConfig) {
+			t.Fatal(i, err)
+		}
+	}
+}
@@ -11,6 +11,9 @@ import (
 type SigningMethodRSA struct {
 	Name string
 	Hash crypto.Hash // 假設你用crypto.SHA512，那麼import必須要包含"crypto/sha512"，否則會報錯
+
+	// KeyPolicy 鑰匙強度的要求，nil表示使用 StrictKeyPolicy，請透過 WithKeyPolicy 設定
+	KeyPolicy *KeyPolicy
 }
 
 var (
@@ -20,19 +23,30 @@ var (
 )
 
 func init() {
-	SigningMethodRSA256 = &SigningMethodRSA{"RS256", crypto.SHA256}
-	SigningMethodRSA384 = &SigningMethodRSA{"RS384", crypto.SHA384}
-	SigningMethodRSA512 = &SigningMethodRSA{"RS512", crypto.SHA512}
+	SigningMethodRSA256 = &SigningMethodRSA{Name: "RS256", Hash: crypto.SHA256}
+	SigningMethodRSA384 = &SigningMethodRSA{Name: "RS384", Hash: crypto.SHA384}
+	SigningMethodRSA512 = &SigningMethodRSA{Name: "RS512", Hash: crypto.SHA512}
 }
 
 func (m *SigningMethodRSA) AlgName() string {
 	return m.Name
 }
 
+// WithKeyPolicy 回傳使用policy的副本，原本的SigningMethod不會被異動
+func (m *SigningMethodRSA) WithKeyPolicy(policy *KeyPolicy) *SigningMethodRSA {
+	clone := *m
+	clone.KeyPolicy = policy
+	return &clone
+}
+
+// Sign key可以是 *rsa.PrivateKey 或者任何公鑰為 *rsa.PublicKey 的 crypto.Signer (例如HSM, KMS)
 func (m *SigningMethodRSA) Sign(signingBytes []byte, key any) ([]byte, error) {
-	privateKey, ok := key.(*rsa.PrivateKey)
-	if !ok {
-		return nil, fmt.Errorf("RSA sign expects *rsa.PrivateKey. %w", ErrInvalidKeyType)
+	signer, publicKey, err := rsaSigner(key)
+	if err != nil {
+		return nil, fmt.Errorf("RSA sign expects crypto.Signer with *rsa.PublicKey. %w", err)
+	}
+	if err = m.KeyPolicy.checkRSA(m.Name, rsaKeySize(publicKey)); err != nil {
+		return nil, err
 	}
 
 	if !m.Hash.Available() {
@@ -41,7 +55,8 @@ func (m *SigningMethodRSA) Sign(signingBytes []byte, key any) ([]byte, error) {
 
 	hasher := m.Hash.New()
 	hasher.Write(signingBytes)
-	return rsa.SignPKCS1v15(rand.Reader, privateKey, m.Hash, hasher.Sum(nil))
+	// opts為crypto.Hash時，*rsa.PrivateKey 使用PKCS #1 v1.5
+	return signer.Sign(rand.Reader, hasher.Sum(nil), m.Hash)
 }
 
 func (m *SigningMethodRSA) Verify(
@@ -53,6 +68,9 @@ func (m *SigningMethodRSA) Verify(
 	if !ok {
 		return fmt.Errorf("RSA verify expects *rsa.PublicKey. %w", ErrInvalidKeyType)
 	}
+	if err = m.KeyPolicy.checkRSA(m.Name, rsaKeySize(publicKey)); err != nil {
+		return err
+	}
 
 	if !m.Hash.Available() {
 		return ErrHashUnavailable
@@ -71,3 +89,24 @@ func (m *SigningMethodRSA) Verify(
 	}
 	return nil
 }
+
+// rsaKeySize modulus的位元數
+func rsaKeySize(publicKey *rsa.PublicKey) int {
+	if publicKey.N == nil {
+		return 0
+	}
+	return publicKey.N.BitLen()
+}
+
+// rsaSigner 取得公鑰為RSA的 crypto.Signer
+func rsaSigner(key any) (crypto.Signer, *rsa.PublicKey, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, ErrInvalidKeyType
+	}
+	publicKey, ok := signer.Public().(*rsa.PublicKey)
+	if !ok {
+		return nil, nil, ErrInvalidKeyType
+	}
+	return signer, publicKey, nil
+}
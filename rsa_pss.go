@@ -0,0 +1,108 @@
+// RSASSA-PSS: https://datatracker.ietf.org/doc/html/rfc7518#section-3.5
+// 與 SigningMethodRSA 的差異只在於padding的方式，PKCS#1 v1.5 是固定的，而PSS會加入隨機的salt，所以每次加簽出來的結果都不同
+
+package jwt
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+)
+
+type SigningMethodRSAPSS struct {
+	*SigningMethodRSA // Name, Hash 與RSA共用
+
+	// Options 加簽時所使用的設定
+	// RFC 7518 規定salt的長度要與hash的輸出長度相同，所以預設使用 rsa.PSSSaltLengthEqualsHash
+	Options *rsa.PSSOptions
+
+	// VerifyOptions 驗證時所使用的設定
+	// 預設為 rsa.PSSSaltLengthAuto，可以接受任意長度的salt，以相容其他不遵守RFC的實作
+	// 若要嚴格要求salt長度必須等於hash長度，可以改成 rsa.PSSSaltLengthEqualsHash
+	VerifyOptions *rsa.PSSOptions
+}
+
+var (
+	SigningMethodRSAPSS256 *SigningMethodRSAPSS
+	SigningMethodRSAPSS384 *SigningMethodRSAPSS
+	SigningMethodRSAPSS512 *SigningMethodRSAPSS
+)
+
+func init() {
+	SigningMethodRSAPSS256 = newSigningMethodRSAPSS("PS256", crypto.SHA256)
+	SigningMethodRSAPSS384 = newSigningMethodRSAPSS("PS384", crypto.SHA384)
+	SigningMethodRSAPSS512 = newSigningMethodRSAPSS("PS512", crypto.SHA512)
+}
+
+func newSigningMethodRSAPSS(name string, hash crypto.Hash) *SigningMethodRSAPSS {
+	return &SigningMethodRSAPSS{
+		SigningMethodRSA: &SigningMethodRSA{Name: name, Hash: hash},
+		Options: &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthEqualsHash,
+		},
+		VerifyOptions: &rsa.PSSOptions{
+			SaltLength: rsa.PSSSaltLengthAuto,
+		},
+	}
+}
+
+// WithKeyPolicy 回傳使用policy的副本，原本的SigningMethod(包含其SigningMethodRSA)不會被異動
+func (m *SigningMethodRSAPSS) WithKeyPolicy(policy *KeyPolicy) *SigningMethodRSAPSS {
+	clone := *m
+	clone.SigningMethodRSA = m.SigningMethodRSA.WithKeyPolicy(policy)
+	return &clone
+}
+
+// Sign implements the ISigningMethod interface
+// key可以是 *rsa.PrivateKey 或者任何公鑰為 *rsa.PublicKey 的 crypto.Signer
+func (m *SigningMethodRSAPSS) Sign(signingBytes []byte, key any) ([]byte, error) {
+	signer, publicKey, err := rsaSigner(key)
+	if err != nil {
+		return nil, fmt.Errorf("RSA-PSS sign expects crypto.Signer with *rsa.PublicKey. %w", err)
+	}
+	if err = m.KeyPolicy.checkRSA(m.Name, rsaKeySize(publicKey)); err != nil {
+		return nil, err
+	}
+
+	if !m.Hash.Available() {
+		return nil, ErrHashUnavailable
+	}
+
+	hasher := m.Hash.New()
+	hasher.Write(signingBytes)
+	// crypto.Signer需要透過opts的Hash得知所使用的雜湊
+	// Options為nil時不可以留下0(rsa.PSSSaltLengthAuto，即最大的salt長度)，要符合RFC 7518的規定
+	opts := &rsa.PSSOptions{Hash: m.Hash, SaltLength: rsa.PSSSaltLengthEqualsHash}
+	if m.Options != nil {
+		opts.SaltLength = m.Options.SaltLength
+	}
+	return signer.Sign(rand.Reader, hasher.Sum(nil), opts)
+}
+
+// Verify implements the ISigningMethod interface
+func (m *SigningMethodRSAPSS) Verify(signingBytes []byte, signature []byte, key any) error {
+	publicKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("RSA-PSS verify expects *rsa.PublicKey. %w", ErrInvalidKeyType)
+	}
+	if err := m.KeyPolicy.checkRSA(m.Name, rsaKeySize(publicKey)); err != nil {
+		return err
+	}
+
+	if !m.Hash.Available() {
+		return ErrHashUnavailable
+	}
+
+	hasher := m.Hash.New()
+	hasher.Write(signingBytes)
+
+	opts := m.VerifyOptions
+	if opts == nil { // 沒有特別指定，就與加簽時的設定相同
+		opts = m.Options
+	}
+	if err := rsa.VerifyPSS(publicKey, m.Hash, hasher.Sum(nil), signature, opts); err != nil {
+		return fmt.Errorf("%w %w", err, ErrSignatureInvalid)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package secp256k1
+
+import (
+	"encoding/hex"
+	"github.com/CarsonSlovoka/jwt/internal/montgomery"
+)
+
+// secp256k1: y^2 = x^3 + 7 https://www.secg.org/sec2-v2.pdf (section 2.4.1)
+var (
+	fp = newModulus("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f")
+	fn = newModulus("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")
+
+	curveB3   element // 3*b，complete公式所需
+	generator point
+)
+
+func init() {
+	b3 := element{21}
+	fp.ToMont(&curveB3, &b3)
+
+	var gx, gy element
+	fp.setBytes(&gx, mustDecodeHex("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"))
+	fp.setBytes(&gy, mustDecodeHex("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"))
+	generator = point{gx, gy, fp.One()}
+}
+
+// point 以射影座標(X:Y:Z)表示的點，對應的仿射座標為(X/Z, Y/Z)，無窮遠點為(0:1:0)
+type point struct {
+	x, y, z element
+}
+
+func newIdentity() point {
+	return point{y: fp.One()}
+}
+
+// add 使用complete的加法公式，不需要對無窮遠點或者P=Q做特別的處理，因此沒有任何分支
+// Renes, Costello, Batina "Complete addition formulas for prime order elliptic curves" Algorithm 7 (a=0)
+// https://eprint.iacr.org/2015/1060
+func (p *point) add(p1, p2 *point) {
+	var t0, t1, t2, t3, t4, x3, y3, z3 element
+	fp.Mul(&t0, &p1.x, &p2.x)
+	fp.Mul(&t1, &p1.y, &p2.y)
+	fp.Mul(&t2, &p1.z, &p2.z)
+	fp.Add(&t3, &p1.x, &p1.y)
+	fp.Add(&t4, &p2.x, &p2.y)
+	fp.Mul(&t3, &t3, &t4)
+	fp.Add(&t4, &t0, &t1)
+	fp.Sub(&t3, &t3, &t4)
+	fp.Add(&t4, &p1.y, &p1.z)
+	fp.Add(&x3, &p2.y, &p2.z)
+	fp.Mul(&t4, &t4, &x3)
+	fp.Add(&x3, &t1, &t2)
+	fp.Sub(&t4, &t4, &x3)
+	fp.Add(&x3, &p1.x, &p1.z)
+	fp.Add(&y3, &p2.x, &p2.z)
+	fp.Mul(&x3, &x3, &y3)
+	fp.Add(&y3, &t0, &t2)
+	fp.Sub(&y3, &x3, &y3)
+	fp.Add(&x3, &t0, &t0)
+	fp.Add(&t0, &x3, &t0)
+	fp.Mul(&t2, &curveB3, &t2)
+	fp.Add(&z3, &t1, &t2)
+	fp.Sub(&t1, &t1, &t2)
+	fp.Mul(&y3, &curveB3, &y3)
+	fp.Mul(&x3, &t4, &y3)
+	fp.Mul(&t2, &t3, &t1)
+	fp.Sub(&x3, &t2, &x3)
+	fp.Mul(&y3, &y3, &t0)
+	fp.Mul(&t1, &t1, &z3)
+	fp.Add(&y3, &t1, &y3)
+	fp.Mul(&t0, &t0, &t3)
+	fp.Mul(&z3, &z3, &t4)
+	fp.Add(&z3, &z3, &t0)
+	p.x, p.y, p.z = x3, y3, z3
+}
+
+// double 同上，Algorithm 9 (a=0)
+func (p *point) double(p1 *point) {
+	var t0, t1, t2, x3, y3, z3 element
+	fp.Square(&t0, &p1.y)
+	fp.Add(&z3, &t0, &t0)
+	fp.Add(&z3, &z3, &z3)
+	fp.Add(&z3, &z3, &z3)
+	fp.Mul(&t1, &p1.y, &p1.z)
+	fp.Square(&t2, &p1.z)
+	fp.Mul(&t2, &curveB3, &t2)
+	fp.Mul(&x3, &t2, &z3)
+	fp.Add(&y3, &t0, &t2)
+	fp.Mul(&z3, &t1, &z3)
+	fp.Add(&t1, &t2, &t2)
+	fp.Add(&t2, &t1, &t2)
+	fp.Sub(&t0, &t0, &t2)
+	fp.Mul(&y3, &t0, &y3)
+	fp.Add(&y3, &x3, &y3)
+	fp.Mul(&t1, &p1.x, &p1.y)
+	fp.Mul(&x3, &t0, &t1)
+	fp.Add(&x3, &x3, &x3)
+	p.x, p.y, p.z = x3, y3, z3
+}
+
+// selectPoint cond為1時p=b，為0時p=a
+func (p *point) selectPoint(a, b *point, cond uint64) {
+	montgomery.Select(&p.x, &a.x, &b.x, cond)
+	montgomery.Select(&p.y, &a.y, &b.y, cond)
+	montgomery.Select(&p.z, &a.z, &b.z, cond)
+}
+
+// scalarMult p = k*q，k為32 bytes的big-endian純量
+//
+// 採用4位元的固定視窗，每個視窗都做相同次數的倍點與加法，並且查表時會走訪整張表，
+// 所以執行的時間與記憶體的存取都與k無關
+func (p *point) scalarMult(q *point, k []byte) {
+	var table [16]point
+	table[0] = newIdentity()
+	table[1] = *q
+	for i := 2; i < 16; i++ {
+		if i%2 == 0 {
+			table[i].double(&table[i/2])
+		} else {
+			table[i].add(&table[i-1], q)
+		}
+	}
+
+	r := newIdentity()
+	var t point
+	for _, b := range k {
+		for _, w := range [2]byte{b >> 4, b & 0x0f} {
+			r.double(&r)
+			r.double(&r)
+			r.double(&r)
+			r.double(&r)
+			t = newIdentity()
+			for i := range table {
+				t.selectPoint(&t, &table[i], uint64(subtleEq(uint8(i), w)))
+			}
+			r.add(&r, &t)
+		}
+	}
+	*p = r
+}
+
+// subtleEq 相等回傳1，否則回傳0
+func subtleEq(x, y uint8) uint8 {
+	z := uint32(x ^ y)
+	return uint8(((z - 1) >> 31) & 1)
+}
+
+// affine 轉換成仿射座標(非Montgomery形式的32 bytes)
+// 無窮遠點沒有仿射座標，此時infinity為1
+func (p *point) affine() (x, y []byte, infinity uint64) {
+	var zInv, ax, ay element
+	fp.Inv(&zInv, &p.z)
+	fp.Mul(&ax, &p.x, &zInv)
+	fp.Mul(&ay, &p.y, &zInv)
+	return fp.bytes(&ax), fp.bytes(&ay), p.z.IsZero()
+}
+
+// isOnCurve 確認仿射座標(Montgomery形式)的點在曲線上: y^2 = x^3 + 7
+func isOnCurve(x, y *element) bool {
+	var lhs, rhs, seven element
+	fp.Square(&lhs, y)
+	fp.Square(&rhs, x)
+	fp.Mul(&rhs, &rhs, x)
+	s := element{7}
+	fp.ToMont(&seven, &s)
+	fp.Add(&rhs, &rhs, &seven)
+	return lhs.Equal(&rhs) == 1
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,250 @@
+// Package secp256k1 實作ES256K(RFC 8812)所使用的secp256k1曲線與ECDSA
+//
+// 標準函式庫的crypto/ecdsa只支援NIST的曲線，因此這邊自行實作，並且
+//   - 有限體與純量的運算都是常數時間
+//   - 點的加法使用complete公式，純量乘法使用固定視窗，不會依據私鑰或nonce產生分支
+package secp256k1
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+)
+
+const (
+	// PrivateKeySize 私鑰的長度
+	PrivateKeySize = 32
+	// PublicKeySize 未壓縮的公鑰長度 0x04 || X || Y
+	PublicKeySize = 65
+	// SignatureSize r || s 各32 bytes
+	SignatureSize = 64
+)
+
+var (
+	ErrInvalidPrivateKey = errors.New("secp256k1: invalid private key")
+	ErrInvalidPublicKey  = errors.New("secp256k1: invalid public key")
+)
+
+// PublicKey secp256k1的公鑰
+type PublicKey struct {
+	q point // Z固定為1
+}
+
+// PrivateKey secp256k1的私鑰
+type PrivateKey struct {
+	d   [PrivateKeySize]byte // big-endian，範圍為[1, n-1]
+	pub *PublicKey
+}
+
+// NewPublicKey 從SEC 1未壓縮的格式(0x04 || X || Y)建立公鑰，並確認點在曲線上
+func NewPublicKey(key []byte) (*PublicKey, error) {
+	if len(key) != PublicKeySize || key[0] != 4 {
+		return nil, ErrInvalidPublicKey
+	}
+	var x, y element
+	if fp.setBytes(&x, key[1:33]) != 1 || fp.setBytes(&y, key[33:]) != 1 {
+		return nil, ErrInvalidPublicKey
+	}
+	if !isOnCurve(&x, &y) {
+		return nil, ErrInvalidPublicKey
+	}
+	return &PublicKey{q: point{x, y, fp.One()}}, nil
+}
+
+// Bytes SEC 1未壓縮的格式
+func (k *PublicKey) Bytes() []byte {
+	b := make([]byte, 1, PublicKeySize)
+	b[0] = 4
+	b = append(b, fp.bytes(&k.q.x)...)
+	return append(b, fp.bytes(&k.q.y)...)
+}
+
+// Equal 比較兩把公鑰是否相同
+func (k *PublicKey) Equal(x crypto.PublicKey) bool {
+	other, ok := x.(*PublicKey)
+	if !ok {
+		return false
+	}
+	return k.q.x.Equal(&other.q.x)&k.q.y.Equal(&other.q.y) == 1
+}
+
+// NewPrivateKey 從32 bytes的big-endian數值建立私鑰，其範圍必須在[1, n-1]之間
+func NewPrivateKey(key []byte) (*PrivateKey, error) {
+	if len(key) != PrivateKeySize {
+		return nil, ErrInvalidPrivateKey
+	}
+	var d element
+	if fn.setBytes(&d, key)&(1^d.IsZero()) != 1 {
+		return nil, ErrInvalidPrivateKey
+	}
+	priv := &PrivateKey{}
+	copy(priv.d[:], key)
+
+	var q point
+	q.scalarMult(&generator, priv.d[:])
+	x, y, _ := q.affine()
+	pub, err := NewPublicKey(append(append([]byte{4}, x...), y...))
+	if err != nil {
+		return nil, err
+	}
+	priv.pub = pub
+	return priv, nil
+}
+
+// GenerateKey 產生新的私鑰
+func GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	for {
+		k, err := randomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		if priv, err := NewPrivateKey(k); err == nil {
+			return priv, nil
+		}
+	}
+}
+
+// Bytes 32 bytes的big-endian數值
+func (k *PrivateKey) Bytes() []byte {
+	b := k.d
+	return b[:]
+}
+
+// PublicKey 取得對應的公鑰
+func (k *PrivateKey) PublicKey() *PublicKey {
+	return k.pub
+}
+
+// Public implements the crypto.Signer interface
+func (k *PrivateKey) Public() crypto.PublicKey {
+	return k.pub
+}
+
+// Sign implements the crypto.Signer interface
+// 與 *ecdsa.PrivateKey 相同，回傳的是ASN.1 DER格式的簽名 SEQUENCE { r INTEGER, s INTEGER }
+// 若需要 r || s 的格式，請直接使用 Sign 函數
+func (k *PrivateKey) Sign(rand io.Reader, digest []byte, _ crypto.SignerOpts) ([]byte, error) {
+	sig, err := Sign(rand, k, digest)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(struct {
+		R, S *big.Int
+	}{
+		new(big.Int).SetBytes(sig[:32]),
+		new(big.Int).SetBytes(sig[32:]),
+	})
+}
+
+// Equal 比較兩把私鑰是否相同
+func (k *PrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(*PrivateKey)
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare(k.d[:], other.d[:]) == 1
+}
+
+// randomScalar 取得[1, n-1]之間的隨機數(拒絕取樣)
+func randomScalar(rand io.Reader) ([]byte, error) {
+	b := make([]byte, 32)
+	for {
+		if _, err := io.ReadFull(rand, b); err != nil {
+			return nil, err
+		}
+		var k element
+		if fn.setBytes(&k, b)&(1^k.IsZero()) == 1 {
+			return b, nil
+		}
+	}
+}
+
+// Sign 對雜湊值hash加簽，回傳 r || s (各32 bytes)
+func Sign(rand io.Reader, priv *PrivateKey, hash []byte) ([]byte, error) {
+	for {
+		k, err := randomScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		// r或s為0的機率可以忽略，若發生則換一個nonce
+		if sig, err := signWithNonce(priv, hash, k); err == nil {
+			return sig, nil
+		}
+	}
+}
+
+var errRetry = errors.New("secp256k1: r or s is zero")
+
+// signWithNonce s = k^-1 * (e + r*d) mod n，其中r為k*G的x座標 mod n
+// https://www.secg.org/sec1-v2.pdf (section 4.1.3)
+func signWithNonce(priv *PrivateKey, hash []byte, nonce []byte) ([]byte, error) {
+	var k, kInv, r, s, e, d element
+	if fn.setBytes(&k, nonce)&(1^k.IsZero()) != 1 {
+		return nil, errRetry
+	}
+
+	var R point
+	R.scalarMult(&generator, nonce)
+	x, _, _ := R.affine()
+	fn.setBytesReduced(&r, x)
+	if r.IsZero() == 1 {
+		return nil, errRetry
+	}
+
+	hashToScalar(&e, hash)
+	fn.setBytes(&d, priv.d[:])
+	fn.Inv(&kInv, &k)
+	fn.Mul(&s, &r, &d)
+	fn.Add(&s, &s, &e)
+	fn.Mul(&s, &s, &kInv)
+	if s.IsZero() == 1 {
+		return nil, errRetry
+	}
+
+	sig := make([]byte, 0, SignatureSize)
+	sig = append(sig, fn.bytes(&r)...)
+	return append(sig, fn.bytes(&s)...), nil
+}
+
+// Verify 驗證 r || s 是否為pub對hash的簽名
+// https://www.secg.org/sec1-v2.pdf (section 4.1.4)
+func Verify(pub *PublicKey, hash, sig []byte) bool {
+	if len(sig) != SignatureSize {
+		return false
+	}
+	var r, s, e, w, u1, u2 element
+	if fn.setBytes(&r, sig[:32])&(1^r.IsZero()) != 1 ||
+		fn.setBytes(&s, sig[32:])&(1^s.IsZero()) != 1 {
+		return false
+	}
+
+	hashToScalar(&e, hash)
+	fn.Inv(&w, &s)
+	fn.Mul(&u1, &e, &w)
+	fn.Mul(&u2, &r, &w)
+
+	var p1, p2 point
+	p1.scalarMult(&generator, fn.bytes(&u1))
+	p2.scalarMult(&pub.q, fn.bytes(&u2))
+	p1.add(&p1, &p2)
+	x, _, infinity := p1.affine()
+	if infinity == 1 {
+		return false
+	}
+	var v element
+	fn.setBytesReduced(&v, x)
+	return v.Equal(&r) == 1
+}
+
+// hashToScalar 取hash最左邊的256位元當作整數，再對n取模數
+func hashToScalar(z *element, hash []byte) {
+	b := make([]byte, 32)
+	if len(hash) > 32 {
+		hash = hash[:32]
+	}
+	copy(b[32-len(hash):], hash)
+	fn.setBytesReduced(z, b)
+}
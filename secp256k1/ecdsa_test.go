@@ -0,0 +1,198 @@
+package secp256k1_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/CarsonSlovoka/jwt/secp256k1"
+	"math/big"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// 公鑰 = k*G，測試向量來自 github.com/decred/dcrd/dcrec/secp256k1 (curve_test.go)
+func TestNewPrivateKey(t *testing.T) {
+	for _, tt := range []struct {
+		k, x, y string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			"483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+		},
+		{
+			"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364140", // n-1
+			"79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			"b7c52588d95c3b9aa25b0403f1eef75702e84bb7597aabe663b82f6f04ef2777",
+		},
+		{
+			"aa5e28d6a97a2479a65527f7290311a3624d4cc0fa1578598ee3c2613bf99522",
+			"34f9460f0e4f08393d192b3c5133a6ba099aa0ad9fd54ebccfacdfa239ff49c6",
+			"0b71ea9bd730fd8923f6d25a7a91e7dd7728a960686cb5a901bb419e0f2ca232",
+		},
+		{
+			"7e2b897b8cebc6361663ad410835639826d590f393d90a9538881735256dfae3",
+			"d74bf844b0862475103d96a611cf2d898447e288d34b360bc885cb8ce7c00575",
+			"131c670d414c4546b88ac3ff664611b1c38ceb1c21d76369d7a7a0969d61d97d",
+		},
+		{
+			"6461e6df0fe7dfd05329f41bf771b86578143d4dd1f7866fb4ca7e97c5fa945d",
+			"e8aecc370aedd953483719a116711963ce201ac3eb21d3f3257bb48668c6a72f",
+			"c25caf2f0eba1ddb2f0f3f47866299ef907867b7d27e95b3873bf98397b24ee1",
+		},
+		{
+			"1b22644a7be026548810c378d0b2994eefa6d2b9881803cb02ceff865287d1b9",
+			"f73c65ead01c5126f28f442d087689bfa08e12763e0cec1d35b01751fd735ed3",
+			"f449a8376906482a84ed01479bd18882b919c140d638307f0c0934ba12590bde",
+		},
+	} {
+		priv, err := secp256k1.NewPrivateKey(mustHex(t, tt.k))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := append([]byte{4}, mustHex(t, tt.x+tt.y)...)
+		if !bytes.Equal(priv.PublicKey().Bytes(), expected) {
+			t.Fatalf("k: %s\n%x\n%x", tt.k, priv.PublicKey().Bytes(), expected)
+		}
+		if _, err = secp256k1.NewPublicKey(expected); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 私鑰必須在[1, n-1]之間
+	for _, k := range []string{
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		"fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141",
+		"ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+	} {
+		if _, err := secp256k1.NewPrivateKey(mustHex(t, k)); err == nil {
+			t.Fatal("must fatal", k)
+		}
+	}
+
+	// 不在曲線上的點
+	pub := append([]byte{4}, mustHex(t, "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"+
+		"483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b9")...)
+	if _, err := secp256k1.NewPublicKey(pub); err == nil {
+		t.Fatal("must fatal")
+	}
+}
+
+// 測試向量來自 github.com/decred/dcrd/dcrec/secp256k1 (ecdsa/signature_test.go)，已透過Sage獨立驗證
+// 注意: 該實作會將s正規化為較小的值(low-S)，因此s與n-s都視為相同
+func TestSignWithNonce(t *testing.T) {
+	n, _ := new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	for _, tt := range []struct {
+		key, hash, nonce, r, s string
+	}{
+		{
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"c301ba9de5d6053caad9f5eb46523f007702add2c62fa39de03146a36b8026b7",
+			"4154324ecd4158938f1df8b5b659aeb639c7fbc36005934096e514af7d64bcc2",
+			"c6c4137b0e5fbfc88ae3f293d7e80c8566c43ae20340075d44f75b009c943d09",
+			"00ba213513572e35943d5acdd17215561b03f11663192a7252196cc8b2a99560",
+		},
+		{
+			"0000000000000000000000000000000000000000000000000000000000000002",
+			"c301ba9de5d6053caad9f5eb46523f007702add2c62fa39de03146a36b8026b7",
+			"679a6d36e7fe6c02d7668af86d78186e8f9ccc04371ac1c8c37939d1f5cae07a",
+			"4a090d82f48ca12d9e7aa24b5dcc187ee0db2920496f671d63e86036aaa7997e",
+			"00261ffe8ba45007fc5fbbba6b4c6ed41beafb48b09fa8af1d6a3fbc6ccefbad",
+		},
+		{
+			"0000000000000000000000000000000000000000000000000000000000000001",
+			"dc063eba3c8d52a159e725c1a161506f6cb6b53478ad5ef3f08d534efa871d9f",
+			"65f880c892fdb6e7f74f76b18c7c942cfd037ef9cf97c39c36e08bbc36b41616",
+			"72e5666f4e9d1099447b825cf737ee32112f17a67e2ca7017ae098da31dfbb8b",
+			"1a7326da661a62f66358dcf53300afdc8e8407939dae1192b5b0899b0254311b",
+		},
+		{
+			"a1becef2069444a9dc6331c3247e113c3ee142edda683db8643f9cb0af7cbe33",
+			"4a6c419a1e25c85327115c4ace586decddfe2990ed8f3d4d801871158338501d",
+			"edb3a01063a0c6ccfc0d77295077cbd322cf364bfa64b7eeea3b20305135d444",
+			"ef392791d87afca8256c4c9c68d981248ee34a09069f50fa8dfc19ae34cd92ce",
+			"0a2b9cb69fd794f7f204c272293b8585a294916a21a11fd94ec04acae2dc6d21",
+		},
+		{
+			"65b46d4eb001c649a86309286aaf94b18386effe62c2e1586d9b1898ccf0099b",
+			"4c6eb9e38415034f4c93d3304d10bef38bf0ad420eefd0f72f940f11c5857786",
+			"7afd696a9e770961d2b2eaec77ab7c22c734886fa57bc4a50a9f1946168cd06f",
+			"81db1d6dca08819ad936d3284a359091e57c036648d477b96af9d8326965a7d1",
+			"1bdf719c4be69351ba7617a187ac246912101aea4b5a7d6dfc234478622b43c6",
+		},
+	} {
+		priv, err := secp256k1.NewPrivateKey(mustHex(t, tt.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash := mustHex(t, tt.hash)
+		sig, err := secp256k1.SignWithNonce(priv, hash, mustHex(t, tt.nonce))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(sig[:32]) != tt.r {
+			t.Fatalf("r\n%x\n%s", sig[:32], tt.r)
+		}
+		s := new(big.Int).SetBytes(sig[32:])
+		lowS := new(big.Int).Sub(n, s)
+		if hex.EncodeToString(sig[32:]) != tt.s && hex.EncodeToString(lowS.FillBytes(make([]byte, 32))) != tt.s {
+			t.Fatalf("s\n%x\n%s", sig[32:], tt.s)
+		}
+		if !secp256k1.Verify(priv.PublicKey(), hash, sig) {
+			t.Fatal("verify failed")
+		}
+		// s與n-s都是合法的簽名
+		if !secp256k1.Verify(priv.PublicKey(), hash, append(bytes.Clone(sig[:32]), lowS.FillBytes(make([]byte, 32))...)) {
+			t.Fatal("verify failed")
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	priv, err := secp256k1.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256([]byte("hello world"))
+	sig, err := secp256k1.Sign(rand.Reader, priv, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !secp256k1.Verify(priv.PublicKey(), hash[:], sig) {
+		t.Fatal("verify failed")
+	}
+
+	other := sha256.Sum256([]byte("hello world!"))
+	if secp256k1.Verify(priv.PublicKey(), other[:], sig) {
+		t.Fatal("the hash has been changed")
+	}
+	otherKey, _ := secp256k1.GenerateKey(rand.Reader)
+	if secp256k1.Verify(otherKey.PublicKey(), hash[:], sig) {
+		t.Fatal("wrong public key")
+	}
+	for _, bad := range [][]byte{
+		sig[:63],
+		make([]byte, 64), // r = s = 0
+		append(bytes.Clone(sig[:32]), mustHex(t, "fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141")...), // s = n
+	} {
+		if secp256k1.Verify(priv.PublicKey(), hash[:], bad) {
+			t.Fatalf("must fail: %x", bad)
+		}
+	}
+
+	restored, err := secp256k1.NewPrivateKey(priv.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Equal(priv) || !restored.PublicKey().Equal(priv.Public()) {
+		t.Fatal("not equal")
+	}
+}
@@ -0,0 +1,4 @@
+package secp256k1
+
+// SignWithNonce 讓測試可以指定nonce，以便比對已知的測試向量
+var SignWithNonce = signWithNonce
@@ -0,0 +1,64 @@
+package secp256k1
+
+import (
+	"encoding/binary"
+	"github.com/CarsonSlovoka/jwt/internal/montgomery"
+	"math/big"
+)
+
+// limbs p與n都是256位元，以4個uint64表示
+const limbs = 4
+
+// element 除非特別說明，否則都處於Montgomery的形式(x*R mod m, R = 2^256)
+type element = montgomery.Element
+
+// modulus secp256k1需要兩種模數: 座標所在的有限體p，以及純量所在的群的階n
+// 運算的部分由 montgomery.Modulus 提供，這裡只處理secp256k1的編碼(32 bytes的big-endian)
+type modulus struct {
+	*montgomery.Modulus
+}
+
+func newModulus(hexStr string) *modulus {
+	m, ok := new(big.Int).SetString(hexStr, 16)
+	if !ok || m.BitLen() != limbs*64 {
+		panic("secp256k1: invalid modulus")
+	}
+	return &modulus{montgomery.New(m, limbs)}
+}
+
+// elementFromBytes 將32 bytes的big-endian內容直接轉換(不做模數的檢查與Montgomery的轉換)
+func elementFromBytes(b []byte) element {
+	var e element
+	for i := range limbs {
+		e[i] = binary.BigEndian.Uint64(b[(limbs-1-i)*8:])
+	}
+	return e
+}
+
+// setBytes 將32 bytes的big-endian數值轉換成Montgomery形式，數值必須小於m
+// ok為1表示數值在範圍之內
+func (md *modulus) setBytes(z *element, b []byte) (ok uint64) {
+	x := elementFromBytes(b)
+	ok = md.Less(&x)
+	md.ToMont(z, &x)
+	return ok
+}
+
+// setBytesReduced 與 setBytes 相同，但數值大於等於m時會先取模數
+// 只適用於 2^256 < 2m 的模數，secp256k1的p與n都符合
+func (md *modulus) setBytesReduced(z *element, b []byte) {
+	x := elementFromBytes(b)
+	md.Reduce(&x, &x)
+	md.ToMont(z, &x)
+}
+
+// bytes 轉換回32 bytes的big-endian數值
+func (md *modulus) bytes(x *element) []byte {
+	var t element
+	md.FromMont(&t, x)
+	b := make([]byte, limbs*8)
+	for i := range limbs {
+		binary.BigEndian.PutUint64(b[(limbs-1-i)*8:], t[i])
+	}
+	return b
+}
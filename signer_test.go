@@ -0,0 +1,136 @@
+package jwt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/CarsonSlovoka/jwt"
+	"github.com/CarsonSlovoka/jwt/parser"
+	"github.com/CarsonSlovoka/jwt/secp256k1"
+	"io"
+	"testing"
+)
+
+// mockHSM 模擬PKCS #11的token: 私鑰只存在於HSM之中，外部只能透過label取得 crypto.Signer
+type mockHSM struct {
+	objects    map[string]crypto.Signer
+	mechanisms []string // 每一次加簽所使用的機制
+}
+
+func newMockHSM() *mockHSM {
+	return &mockHSM{objects: make(map[string]crypto.Signer)}
+}
+
+// signer C_FindObjects + C_SignInit
+func (h *mockHSM) signer(label string) crypto.Signer {
+	return &hsmSigner{h, label, h.objects[label].Public()}
+}
+
+// hsmMechanism 只在特定Go版本才有的key型別(例如ML-DSA)，由其測試檔提供對應的機制
+var hsmMechanism = func(pub crypto.PublicKey) (string, bool) {
+	return "", false
+}
+
+type hsmSigner struct {
+	hsm   *mockHSM
+	label string
+	pub   crypto.PublicKey
+}
+
+// Public implements the crypto.Signer interface
+func (s *hsmSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+// Sign implements the crypto.Signer interface
+func (s *hsmSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	key, ok := s.hsm.objects[s.label]
+	if !ok {
+		return nil, fmt.Errorf("CKR_KEY_HANDLE_INVALID: %s", s.label)
+	}
+	mechanism := "CKM_ECDSA"
+	switch s.pub.(type) {
+	case *rsa.PublicKey:
+		mechanism = "CKM_RSA_PKCS"
+		if _, ok = opts.(*rsa.PSSOptions); ok {
+			mechanism = "CKM_RSA_PKCS_PSS"
+		}
+	case ed25519.PublicKey:
+		mechanism = "CKM_EDDSA"
+	default:
+		if m, ok := hsmMechanism(s.pub); ok {
+			mechanism = m
+		}
+	}
+	s.hsm.mechanisms = append(s.hsm.mechanisms, mechanism)
+	return key.Sign(rand, digest, opts)
+}
+
+func TestSigningMethod_cryptoSigner(t *testing.T) {
+	hsm := newMockHSM()
+	hsm.objects["rsa"], _ = rsa.GenerateKey(rand.Reader, 2048)
+	hsm.objects["p256"], _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	hsm.objects["p384"], _ = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	hsm.objects["p521"], _ = ecdsa.GenerateKey(elliptic.P521(), rand.Reader)
+	hsm.objects["secp256k1"], _ = secp256k1.GenerateKey(rand.Reader)
+	_, hsm.objects["ed25519"], _ = ed25519.GenerateKey(rand.Reader)
+
+	for _, tt := range []struct {
+		m         jwt.ISigningMethod
+		label     string
+		mechanism string
+	}{
+		{jwt.SigningMethodRSA256, "rsa", "CKM_RSA_PKCS"},
+		{jwt.SigningMethodRSAPSS384, "rsa", "CKM_RSA_PKCS_PSS"},
+		{jwt.SigningMethodECDSA256, "p256", "CKM_ECDSA"},
+		{jwt.SigningMethodECDSA384, "p384", "CKM_ECDSA"},
+		{jwt.SigningMethodECDSA512, "p521", "CKM_ECDSA"},
+		{jwt.SigningMethodES256K, "secp256k1", "CKM_ECDSA"},
+		{&jwt.SigningMethodEdDSA{}, "ed25519", "CKM_EDDSA"},
+	} {
+		signer := hsm.signer(tt.label)
+		hsm.mechanisms = nil
+		bsToken, err := jwt.New(tt.m).SignedBytes(signer)
+		if err != nil {
+			t.Fatal(tt.m.AlgName(), err)
+		}
+		if len(hsm.mechanisms) != 1 || hsm.mechanisms[0] != tt.mechanism {
+			t.Fatal(tt.m.AlgName(), hsm.mechanisms)
+		}
+
+		vdFunc, err := parser.New().Parse(string(bsToken), func(method string) (jwt.ISigningMethod, error) {
+			return tt.m, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = vdFunc(nil, nil, func(token *jwt.Token) (any, error) {
+			return signer.Public(), nil
+		}); err != nil {
+			t.Fatal(tt.m.AlgName(), err)
+		}
+	}
+
+	// Signer的公鑰與演算法不符
+	if _, err := jwt.SigningMethodRSA256.Sign([]byte("hello"), hsm.signer("p256")); !errors.Is(err, jwt.ErrInvalidKeyType) {
+		t.Fatal(err)
+	}
+	if _, err := jwt.SigningMethodECDSA256.Sign([]byte("hello"), hsm.signer("rsa")); !errors.Is(err, jwt.ErrInvalidKeyType) {
+		t.Fatal(err)
+	}
+	if _, err := jwt.SigningMethodECDSA256.Sign([]byte("hello"), hsm.signer("p384")); !errors.Is(err, jwt.ErrInvalidKey) {
+		t.Fatal(err)
+	}
+
+	// HSM的錯誤要能傳遞出來
+	signer := hsm.signer("p256")
+	delete(hsm.objects, "p256")
+	if _, err := jwt.SigningMethodECDSA256.Sign([]byte("hello"), signer); err == nil {
+		t.Fatal("expected an error from the HSM")
+	}
+}
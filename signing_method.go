@@ -1,5 +1,7 @@
 package jwt
 
+import "context"
+
 type ISigningMethod interface {
 	// AlgName HS256, RS512, ...
 	AlgName() string
@@ -13,3 +15,33 @@ type ISigningMethod interface {
 		key any, // 若為非對稱式加密用的是公鑰
 	) error
 }
+
+// ISigningMethodContext 可選的擴充，需要透過網路的方法(例如遠端的KMS)可以藉由ctx取消或者設定期限
+// Token 與 parser.Parser 在方法有實作此介面時，會改用 SignContext, VerifyContext
+type ISigningMethodContext interface {
+	ISigningMethod
+	SignContext(ctx context.Context, signingBytes []byte, key any) ([]byte, error)
+	VerifyContext(ctx context.Context, signingBytes []byte, signature []byte, key any) error
+}
+
+// SignContext 若m有實作 ISigningMethodContext 就使用 SignContext，否則在ctx尚未結束時呼叫 Sign
+func SignContext(ctx context.Context, m ISigningMethod, signingBytes []byte, key any) ([]byte, error) {
+	if mc, ok := m.(ISigningMethodContext); ok {
+		return mc.SignContext(ctx, signingBytes, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return m.Sign(signingBytes, key)
+}
+
+// VerifyContext 若m有實作 ISigningMethodContext 就使用 VerifyContext，否則在ctx尚未結束時呼叫 Verify
+func VerifyContext(ctx context.Context, m ISigningMethod, signingBytes []byte, signature []byte, key any) error {
+	if mc, ok := m.(ISigningMethodContext); ok {
+		return mc.VerifyContext(ctx, signingBytes, signature, key)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return m.Verify(signingBytes, signature, key)
+}
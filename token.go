@@ -2,8 +2,11 @@ package jwt
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"slices"
 )
 
 type Token struct {
@@ -11,6 +14,17 @@ type Token struct {
 	Claims IClaims
 	// ISigningMethod // 不鑲嵌它，因為如果使用者直接調用這個方法，會需要處理前置header, claims要被URLEncode之後才能動作
 	SigningMethod ISigningMethod
+
+	// 以下的欄位只有經由parser解析的token才會填入
+
+	// Raw 原始的jwt字串
+	Raw string
+	// RegisteredHeader Header之中的標準欄位
+	RegisteredHeader RegisteredHeader
+	// Signature 已經過base64url解碼的簽名
+	Signature []byte
+	// Valid header, 鑰匙, 簽名以及claims全部都驗證通過時才為true
+	Valid bool
 }
 
 func New(signingMethod ISigningMethod) *Token {
@@ -18,39 +32,119 @@ func New(signingMethod ISigningMethod) *Token {
 }
 
 func NewWithClaims(signingMethod ISigningMethod, claims IClaims) *Token {
+	return NewWithType(signingMethod, "JWT", claims)
+}
+
+// NewWithType 與 NewWithClaims 相同，但header的typ改用指定的內容，例如RFC 9068的access token使用"at+jwt"
+// typ為空字串時，header不會有typ
+// https://datatracker.ietf.org/doc/html/rfc8725#section-3.11
+func NewWithType(signingMethod ISigningMethod, typ string, claims IClaims) *Token {
+	header := map[string]any{
+		"alg": signingMethod.AlgName(),
+	}
+	if typ != "" {
+		header["typ"] = typ
+	}
 	return &Token{
-		Header: map[string]any{
-			"typ": "JWT",
-			"alg": signingMethod.AlgName(),
-		},
+		Header:        header,
 		Claims:        claims,
 		SigningMethod: signingMethod,
 	}
 }
 
+// SetBase64Payload 設定payload是否要經過base64url編碼(預設為true)
+// 設定為false時，會加上 "b64": false 並將b64加入crit https://datatracker.ietf.org/doc/html/rfc7797#section-3
+// crit之中其他的名稱都會被保留，只有在crit變成空的時候才會移除crit
+func (t *Token) SetBase64Payload(encode bool) {
+	crit := headerCritical(t.Header)
+	if encode {
+		delete(t.Header, "b64")
+		crit = slices.DeleteFunc(crit, func(name string) bool { return name == "b64" })
+	} else {
+		t.Header["b64"] = false
+		if !slices.Contains(crit, "b64") {
+			crit = append(crit, "b64")
+		}
+	}
+	if len(crit) == 0 {
+		delete(t.Header, "crit")
+		return
+	}
+	t.Header["crit"] = crit
+}
+
+// headerCritical 取得crit所列出的名稱，crit可能是自行設定的[]string，也可能是解析JSON之後的[]any
+func headerCritical(header map[string]any) []string {
+	switch v := header["crit"].(type) {
+	case []string:
+		return slices.Clone(v)
+	case []any:
+		names := make([]string, 0, len(v))
+		for _, c := range v {
+			if name, ok := c.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// IsBase64Payload header沒有b64或者b64為true時，payload需要經過base64url編碼
+func (t *Token) IsBase64Payload() bool {
+	b64, ok := t.Header["b64"].(bool)
+	return !ok || b64
+}
+
 // SigningBytes 取得要被加簽的內容
 func (t *Token) SigningBytes() ([]byte, error) {
-	var h, c []byte
-	var err error
-	h, err = json.Marshal(t.Header)
+	c, err := json.Marshal(t.Claims)
 	if err != nil {
 		return nil, err
 	}
-	c, err = json.Marshal(t.Claims)
+	return t.signingBytes(c)
+}
+
+// DetachedSigningBytes 取得payload不放在token之中(detached content)時，要被加簽的內容
+// https://datatracker.ietf.org/doc/html/rfc7515#appendix-F
+func (t *Token) DetachedSigningBytes(payload []byte) ([]byte, error) {
+	return t.signingBytes(payload)
+}
+
+// signingBytes ASCII(BASE64URL(header)) || '.' || payload
+// 若b64為false則payload直接使用原始的內容 https://datatracker.ietf.org/doc/html/rfc7797#section-3
+func (t *Token) signingBytes(payload []byte) ([]byte, error) {
+	h, err := json.Marshal(t.Header)
 	if err != nil {
 		return nil, err
 	}
-	return bytes.Join([][]byte{encodeSegment(h), encodeSegment(c)}, []byte{'.'}), nil
+	buf := append(encodeSegment(h), '.')
+	if t.IsBase64Payload() {
+		return base64.RawURLEncoding.AppendEncode(buf, payload), nil
+	}
+	return append(buf, payload...), nil
 }
 
 // SignedBytes 取得到完整的jwt字串內容
 func (t *Token) SignedBytes(key any) ([]byte, error) {
+	return t.SignedBytesContext(context.Background(), key)
+}
+
+// SignedBytesContext 與 SignedBytes 相同，若SigningMethod有實作 ISigningMethodContext 則會使用ctx(例如遠端KMS的期限)
+func (t *Token) SignedBytesContext(ctx context.Context, key any) ([]byte, error) {
 	signBytes, err := t.SigningBytes()
 	if err != nil {
 		return nil, err
 	}
+	if !t.IsBase64Payload() {
+		// compact的格式是以'.'分隔，未編碼的payload若含有'.'會無法解析，此時應改用 SignedDetachedBytes
+		// https://datatracker.ietf.org/doc/html/rfc7797#section-5.2
+		if bytes.Count(signBytes, []byte{'.'}) != 1 {
+			return nil, fmt.Errorf("unencoded payload must not contain '.' %w", ErrTokenMalformed)
+		}
+	}
 
-	signature, err := t.SigningMethod.Sign(signBytes, key)
+	signature, err := SignContext(ctx, t.SigningMethod, signBytes, key)
 	if err != nil {
 		return nil, err
 	}
@@ -59,3 +153,24 @@ func (t *Token) SignedBytes(key any) ([]byte, error) {
 		signature,              // 我們將特徵的內容也套用到URLEncode
 	), nil
 }
+
+// SignedDetachedBytes 對payload加簽，但是產生出來的jwt不包含payload(中間的區段為空): header..signature
+// payload需由其他的管道(例如http body)傳給對方，驗證時請使用 parser.Parser.ParseDetached
+func (t *Token) SignedDetachedBytes(payload []byte, key any) ([]byte, error) {
+	return t.SignedDetachedBytesContext(context.Background(), payload, key)
+}
+
+// SignedDetachedBytesContext 與 SignedDetachedBytes 相同，但會將ctx傳給 ISigningMethodContext
+func (t *Token) SignedDetachedBytesContext(ctx context.Context, payload []byte, key any) ([]byte, error) {
+	signBytes, err := t.DetachedSigningBytes(payload)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := SignContext(ctx, t.SigningMethod, signBytes, key)
+	if err != nil {
+		return nil, err
+	}
+	h, _, _ := bytes.Cut(signBytes, []byte{'.'})
+	buf := append(bytes.Clone(h), '.', '.')
+	return base64.RawURLEncoding.AppendEncode(buf, signature), nil
+}
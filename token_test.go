@@ -11,6 +11,7 @@ import (
 	"github.com/CarsonSlovoka/jwt"
 	"github.com/CarsonSlovoka/jwt/parser"
 	"github.com/CarsonSlovoka/jwt/validator"
+	"slices"
 	"testing"
 )
 
@@ -26,7 +27,7 @@ func TestNew_hmac(t *testing.T) {
 		},
 	)
 	// token.Header["xx"] = "" // 如果有需要自定義Header可以後補上
-	privateKey := []byte("key")
+	privateKey := []byte("key: 0123456789abcdefghijklmnopq")
 	bsSignature, err := token.SignedBytes(privateKey)
 	if err != nil {
 		t.Fatal(err)
@@ -50,7 +51,7 @@ func TestNew_hmac(t *testing.T) {
 
 func TestToken_SignedBytes_hmac(t *testing.T) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHMAC256, jwt.MapClaims{})
-	privateKey := []byte("helloWorld")
+	privateKey := []byte("helloWorld: 0123456789abcdefghij")
 	signedBytes, err := token.SignedBytes(privateKey)
 	if err != nil {
 		t.Fatal(err)
@@ -164,3 +165,63 @@ func TestNew_ecdsa(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// https://datatracker.ietf.org/doc/html/rfc7797#section-4
+func TestToken_SignedDetachedBytes(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/rfc7515#appendix-A.1
+	key, _ := base64.RawURLEncoding.DecodeString("AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow")
+	payload := []byte("$.02")
+
+	for _, tt := range []struct {
+		b64      bool
+		expected string
+	}{
+		{true, "eyJhbGciOiJIUzI1NiJ9..5mvfOroL-g7HyqJoozehmsaqmvTYGEq5jTI1gVvoEoQ"},
+		{false, "eyJhbGciOiJIUzI1NiIsImI2NCI6ZmFsc2UsImNyaXQiOlsiYjY0Il19..A5dxf2s96_n5FLueVuW1Z_vh161FwXZC4YLPff6dmDY"},
+	} {
+		token := jwt.New(jwt.SigningMethodHMAC256)
+		delete(token.Header, "typ") // 範例的header只有alg
+		token.SetBase64Payload(tt.b64)
+		bsToken, err := token.SignedDetachedBytes(payload, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bsToken) != tt.expected {
+			t.Fatalf("b64: %v\n%s\n%s", tt.b64, bsToken, tt.expected)
+		}
+	}
+
+	// 未編碼的payload含有'.'，不能放在compact的格式之中
+	token := jwt.NewWithClaims(jwt.SigningMethodHMAC256, jwt.MapClaims{"amount": 0.02})
+	token.SetBase64Payload(false)
+	if _, err := token.SignedBytes(key); err == nil {
+		t.Fatal("must fatal")
+	}
+}
+
+// crit之中其他的擴充header不可以被b64的設定影響
+func TestToken_SetBase64Payload_crit(t *testing.T) {
+	token := jwt.New(jwt.SigningMethodHMAC256)
+	token.Header["crit"] = []string{"exp-policy"}
+	token.Header["exp-policy"] = "strict"
+
+	token.SetBase64Payload(false)
+	if crit := token.Header["crit"].([]string); !slices.Equal(crit, []string{"exp-policy", "b64"}) {
+		t.Fatal(crit)
+	}
+	token.SetBase64Payload(false) // 不會重複加入
+	if crit := token.Header["crit"].([]string); !slices.Equal(crit, []string{"exp-policy", "b64"}) {
+		t.Fatal(crit)
+	}
+	token.SetBase64Payload(true)
+	if crit := token.Header["crit"].([]string); !slices.Equal(crit, []string{"exp-policy"}) || token.Header["b64"] != nil {
+		t.Fatal(token.Header)
+	}
+
+	// 解析JSON之後的crit為[]any
+	token.Header["crit"] = []any{"b64"}
+	token.SetBase64Payload(true)
+	if _, exists := token.Header["crit"]; exists {
+		t.Fatal("empty crit should be removed")
+	}
+}
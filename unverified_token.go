@@ -0,0 +1,18 @@
+package jwt
+
+// UnverifiedToken 尚未驗證簽名的token，只能用來查看其內容，例如依據iss, kid決定要交給哪一個驗證者
+// 其內容可能已被竄改，所以不可以當作驗證的結果使用
+//
+// 它刻意與 Token 是不同的型別，因此不能被傳入需要已驗證token的地方(例如 KeyFunc)
+type UnverifiedToken struct {
+	// Raw 原始的jwt字串
+	Raw string
+	// Header 完整的header
+	Header map[string]any
+	// RegisteredHeader Header之中的標準欄位
+	RegisteredHeader RegisteredHeader
+	// Claims 未經驗證的claims
+	Claims IClaims
+	// Signature 已經過base64url解碼的簽名，尚未被驗證
+	Signature []byte
+}
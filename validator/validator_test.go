@@ -26,7 +26,7 @@ func (m MyCustomClaims) Validate() error { // 自定義你的驗證邏輯
 }
 
 func TestValidator_Validate(t *testing.T) {
-	key := []byte("my private key")
+	key := []byte("my private key: 0123456789abcdef")
 	// 模擬取得server簽名後的產物
 	token := jwt.NewWithClaims(jwt.SigningMethodHMAC256, &MyCustomClaims{
 		Foo: "bar", // 此驗證被定義為foo才是正確的，我們故意改成bar，使其觸發 ErrorMustBeFoo 來確保驗證真的有被執行到
@@ -62,7 +62,7 @@ type MyCustomClaims2 struct {
 // 此範例與 TestValidator_Validate 很像，只是這次自訂的struct，不實作Validate
 // 因此若也要達到相同的效果，就要在給 vdCustomClaimsFunc
 func TestValidator_Validate2(t *testing.T) {
-	key := []byte("my private key")
+	key := []byte("my private key: 0123456789abcdef")
 	token := jwt.NewWithClaims(jwt.SigningMethodHMAC256, &MyCustomClaims2{
 		Foo: "bar",
 	})
@@ -99,7 +99,7 @@ func TestValidator_Validate2(t *testing.T) {
 
 // 使用 MapClaims 來對自定義 Claims 內容做驗證
 func TestValidator_Validate3(t *testing.T) {
-	key := []byte("my private key")
+	key := []byte("my private key: 0123456789abcdef")
 	token := jwt.NewWithClaims(jwt.SigningMethodHMAC256, &jwt.MapClaims{
 		"foo": "bar",
 	})